@@ -0,0 +1,280 @@
+/*
+-------------------------------------------------
+   Author :       zlyuancn
+   date：         2026/10/19
+   Description :
+-------------------------------------------------
+*/
+
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http/pprof"
+	"reflect"
+	"strings"
+
+	"github.com/kataras/iris/v12"
+	"github.com/zly-app/zapp"
+	"github.com/zly-app/zapp/core"
+	"go.uber.org/zap"
+
+	"github.com/zly-app/service/api/config"
+	"github.com/zly-app/service/api/middleware"
+)
+
+// admin接口认证token的header
+const AdminTokenHeader = "X-Admin-Token"
+
+// 配置中包含这些关键字的字段在admin接口中会被隐藏
+var adminSecretFieldKeywords = []string{"token", "secret", "password", "key"}
+
+// 路由信息
+type RouteInfo struct {
+	Method   string   `json:"method"`
+	Path     string   `json:"path"`
+	Handler  string   `json:"handler"`  // 主处理程序名
+	Handlers []string `json:"handlers"` // 完整的调用链, 包含中间件
+}
+
+// 可在运行时切换的日志开关, 为nil的字段表示不修改
+type LogFlags struct {
+	ReqLogLevelIsInfo     *bool `json:"ReqLogLevelIsInfo,omitempty"`
+	RspLogLevelIsInfo     *bool `json:"RspLogLevelIsInfo,omitempty"`
+	BindLogLevelIsInfo    *bool `json:"BindLogLevelIsInfo,omitempty"`
+	LogApiResultInDevelop *bool `json:"LogApiResultInDevelop,omitempty"`
+	LogApiResultInProd    *bool `json:"LogApiResultInProd,omitempty"`
+	AlwaysLogHeaders      *bool `json:"AlwaysLogHeaders,omitempty"`
+	AlwaysLogBody         *bool `json:"AlwaysLogBody,omitempty"`
+}
+
+// 获取已注册的路由列表
+func (a *ApiService) Routes() []RouteInfo {
+	routes := a.GetRoutes()
+	infos := make([]RouteInfo, 0, len(routes))
+	for _, r := range routes {
+		info := RouteInfo{
+			Method:   r.Method,
+			Path:     r.Tmpl().Src,
+			Handlers: make([]string, len(r.Handlers)),
+		}
+		for i, h := range r.Handlers {
+			info.Handlers[i] = getHandlerName(h)
+		}
+		if r.MainHandlerIndex >= 0 && r.MainHandlerIndex < len(info.Handlers) {
+			info.Handler = info.Handlers[r.MainHandlerIndex]
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// 获取当前的日志开关
+func (a *ApiService) LogFlags() LogFlags {
+	conf := a.Config()
+	return LogFlags{
+		ReqLogLevelIsInfo:     &conf.ReqLogLevelIsInfo,
+		RspLogLevelIsInfo:     &conf.RspLogLevelIsInfo,
+		BindLogLevelIsInfo:    &conf.BindLogLevelIsInfo,
+		LogApiResultInDevelop: &conf.LogApiResultInDevelop,
+		LogApiResultInProd:    &conf.LogApiResultInProd,
+		AlwaysLogHeaders:      &conf.AlwaysLogHeaders,
+		AlwaysLogBody:         &conf.AlwaysLogBody,
+	}
+}
+
+// 在运行时切换日志开关, 对之后的请求生效
+func (a *ApiService) SetLogFlags(flags LogFlags) {
+	a.UpdateConfig(func(conf *config.Config) {
+		setBoolIfNotNil(&conf.ReqLogLevelIsInfo, flags.ReqLogLevelIsInfo)
+		setBoolIfNotNil(&conf.RspLogLevelIsInfo, flags.RspLogLevelIsInfo)
+		setBoolIfNotNil(&conf.BindLogLevelIsInfo, flags.BindLogLevelIsInfo)
+		setBoolIfNotNil(&conf.LogApiResultInDevelop, flags.LogApiResultInDevelop)
+		setBoolIfNotNil(&conf.LogApiResultInProd, flags.LogApiResultInProd)
+		setBoolIfNotNil(&conf.AlwaysLogHeaders, flags.AlwaysLogHeaders)
+		setBoolIfNotNil(&conf.AlwaysLogBody, flags.AlwaysLogBody)
+	})
+}
+
+func setBoolIfNotNil(dst *bool, v *bool) {
+	if v != nil {
+		*dst = *v
+	}
+}
+
+// 初始化admin接口
+func (a *ApiService) initAdmin() {
+	if !a.conf.AdminEnable {
+		return
+	}
+
+	if a.conf.AdminBind == "" {
+		if a.conf.AdminToken == "" {
+			a.app.Fatal("api服务的admin接口挂载在api服务下时必须设置 AdminToken")
+		}
+		a.registryAdminRouter(a.Party(a.conf.AdminPath, routeGroupMiddleware(AdminRouteGroup), WrapMiddleware(a.adminAuth)))
+		return
+	}
+	if err := checkAdminBind(a.conf.AdminBind, a.conf.AdminToken); err != nil {
+		a.app.Fatal("api服务的admin接口配置错误", zap.Error(err))
+	}
+
+	adminApp := iris.New()
+	adminApp.Logger().SetLevel("disable") // 关闭默认日志
+	adminApp.Use(
+		middleware.BaseMiddlewareOfConfGetter(a.app, a.Config),
+		middleware.LoggerMiddleware(a.app, a.Config()), // 日志
+		recoverMiddleware(), // panic恢复
+	)
+	useErrorCodeHandlers(adminApp, a.app, a.Config)
+	a.registryAdminRouter(adminApp.Party(a.conf.AdminPath, WrapMiddleware(a.adminAuth)))
+	a.adminApp = adminApp
+
+	zapp.AddHandler(zapp.BeforeExitHandler, func(app core.IApp, handlerType zapp.HandlerType) {
+		err := adminApp.Shutdown(context.Background())
+		if err != nil {
+			app.Error("api服务的admin接口关闭失败", zap.Error(err))
+		}
+	})
+}
+
+// 启动独立bind的admin服务
+func (a *ApiService) startAdmin(opts ...iris.Configurator) error {
+	if a.adminApp == nil {
+		return nil
+	}
+
+	a.app.Info("正在启动api服务的admin接口", zap.String("bind", a.conf.AdminBind))
+	lis, err := net.Listen("tcp", a.conf.AdminBind)
+	if err != nil {
+		return fmt.Errorf("api服务的admin接口监听失败: %v", err)
+	}
+	go func() {
+		err := a.adminApp.Run(iris.Listener(lis), opts...)
+		if err != nil && err != iris.ErrServerClosed {
+			a.app.Error("api服务的admin接口运行失败", zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+// 检查admin接口独立的bind地址, 没有设置token时只允许绑定在回环地址上
+func checkAdminBind(bind, token string) error {
+	if token != "" {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(bind)
+	if err != nil {
+		return fmt.Errorf("invalid admin bind %q: %v", bind, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("admin token is required when admin bind %q is not a loopback address", bind)
+}
+
+// admin接口认证
+func (a *ApiService) adminAuth(ctx *Context) error {
+	if a.conf.AdminToken == "" {
+		return nil
+	}
+
+	token := ctx.GetHeader(AdminTokenHeader)
+	if token == "" {
+		return AuthorizationRequired
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(a.conf.AdminToken)) != 1 {
+		return AuthorizationError
+	}
+	return nil
+}
+
+// 注册admin路由
+func (a *ApiService) registryAdminRouter(router Party) {
+	// pprof
+	router.Get("/debug/pprof/", iris.FromStd(pprof.Index))
+	router.Get("/debug/pprof/cmdline", iris.FromStd(pprof.Cmdline))
+	router.Get("/debug/pprof/profile", iris.FromStd(pprof.Profile))
+	router.Get("/debug/pprof/symbol", iris.FromStd(pprof.Symbol))
+	router.Get("/debug/pprof/trace", iris.FromStd(pprof.Trace))
+	router.Get("/debug/pprof/{name}", func(ctx iris.Context) {
+		pprof.Handler(ctx.Params().Get("name")).ServeHTTP(ctx.ResponseWriter(), ctx.Request())
+	})
+
+	router.Get("/routes", Wrap(func(ctx *Context) interface{} {
+		return a.Routes()
+	}))
+	router.Get("/config", Wrap(func(ctx *Context) interface{} {
+		return maskConfig(a.Config())
+	}))
+	router.Get("/gpool", Wrap(func(ctx *Context) interface{} {
+		return a.GPoolStats()
+	}))
+	router.Get("/bulkheads", Wrap(func(ctx *Context) interface{} {
+		return BulkheadStats()
+	}))
+	router.Get("/circuit-breakers", Wrap(func(ctx *Context) interface{} {
+		return CircuitBreakerStatsAll()
+	}))
+	router.Get("/ip-filter", Wrap(func(ctx *Context) interface{} {
+		return a.IPFilterConfig()
+	}))
+	router.Post("/ip-filter", Wrap(func(ctx *Context, req *IPFilterConfig) (interface{}, error) {
+		if err := a.SetIPFilterConfig(*req); err != nil {
+			return nil, ParamError.WithError(err)
+		}
+		ctx.Warn("api服务的ip过滤配置已更新", zap.Any("config", req))
+		return a.IPFilterConfig(), nil
+	}))
+	router.Get("/canary", Wrap(func(ctx *Context) interface{} {
+		return a.CanaryConfig()
+	}))
+	router.Post("/canary", Wrap(func(ctx *Context, req *CanaryConfig) (interface{}, error) {
+		if err := a.SetCanaryConfig(*req); err != nil {
+			return nil, ParamError.WithError(err)
+		}
+		ctx.Warn("api服务的灰度路由配置已更新", zap.Any("config", req))
+		return a.CanaryConfig(), nil
+	}))
+	router.Get("/log-flags", Wrap(func(ctx *Context) interface{} {
+		return a.LogFlags()
+	}))
+	router.Post("/log-flags", Wrap(func(ctx *Context, req *LogFlags) interface{} {
+		a.SetLogFlags(*req)
+		flags := a.LogFlags()
+		ctx.Warn("api服务的日志开关已切换", zap.Any("flags", flags))
+		return flags
+	}))
+}
+
+// 将配置转为map, 隐藏敏感字段
+func maskConfig(conf *config.Config) map[string]interface{} {
+	v := reflect.ValueOf(conf).Elem()
+	t := v.Type()
+	result := make(map[string]interface{}, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Name
+		value := v.Field(i).Interface()
+		if isSecretField(name) && !v.Field(i).IsZero() {
+			value = "******"
+		}
+		result[name] = value
+	}
+	return result
+}
+
+func isSecretField(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range adminSecretFieldKeywords {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestCheckAdminBind(t *testing.T) {
+	tests := []struct {
+		bind   string
+		token  string
+		hasErr bool
+	}{
+		{":8081", "tk", false},
+		{":8081", "", true},
+		{"0.0.0.0:8081", "", true},
+		{"10.0.0.1:8081", "", true},
+		{"127.0.0.1:8081", "", false},
+		{"localhost:8081", "", false},
+		{"[::1]:8081", "", false},
+		{"8081", "", true},
+	}
+	for _, test := range tests {
+		if err := checkAdminBind(test.bind, test.token); (err != nil) != test.hasErr {
+			t.Fatal("bind地址检查结果和预期不符", test.bind, test.token, err)
+		}
+	}
+}
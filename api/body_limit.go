@@ -0,0 +1,69 @@
+/*
+-------------------------------------------------
+   Author :       zlyuancn
+   date：         2026/10/19
+   Description :
+-------------------------------------------------
+*/
+
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/kataras/iris/v12"
+)
+
+// 请求body超出限制时保存在iris上下文中的字段
+const bodyTooLargeFieldKey = "_body_too_large"
+
+var errRequestBodyTooLarge = errors.New("request body too large")
+
+// 限制请求body大小的中间件, 用于为单个路由设置比 PostMaxMemory 更小的限制
+//
+// 如果请求header中的 Content-Length 超出限制会立即返回 RequestBodyTooLarge,
+// 否则会在读取body超出限制时返回 RequestBodyTooLarge, http状态码为413
+func BodyLimit(maxSize int64) iris.Handler {
+	return WrapMiddleware(func(ctx *Context) error {
+		if ctx.GetContentLength() > maxSize {
+			ctx.StatusCode(http.StatusRequestEntityTooLarge)
+			return RequestBodyTooLarge
+		}
+
+		ctx.Request().Body = &limitedBody{
+			ReadCloser: ctx.Request().Body,
+			ctx:        ctx,
+			remain:     maxSize,
+		}
+		return nil
+	})
+}
+
+// 限制读取大小的body
+type limitedBody struct {
+	io.ReadCloser
+	ctx    *Context
+	remain int64
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.remain <= 0 {
+		// 已经读取了允许的大小, 检查是否还有数据
+		var b [1]byte
+		n, err := l.ReadCloser.Read(b[:])
+		if n > 0 {
+			l.ctx.Values().Set(bodyTooLargeFieldKey, true)
+			return 0, errRequestBodyTooLarge
+		}
+		return 0, err
+	}
+
+	if int64(len(p)) > l.remain {
+		p = p[:l.remain]
+	}
+	n, err := l.ReadCloser.Read(p)
+	l.remain -= int64(n)
+	return n, err
+}
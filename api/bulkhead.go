@@ -0,0 +1,76 @@
+/*
+-------------------------------------------------
+   Author :       zlyuancn
+   date：         2026/10/19
+   Description :
+-------------------------------------------------
+*/
+
+package api
+
+import (
+	"sync"
+
+	"github.com/kataras/iris/v12"
+	"github.com/zly-app/zapp/logger"
+	"go.uber.org/zap"
+
+	"github.com/zly-app/service/api/utils"
+)
+
+// 已创建的隔离舱, name -> *gpoolLimiter
+var bulkheads sync.Map
+
+// 隔离舱中间件的实例标识, 全局协程池限制会跳过使用了隔离舱的路由
+var bulkheadHandlers sync.Map
+
+// 隔离舱中间件, 为路由提供独立的并发限制和等待队列, 避免某个路由占满全局协程池
+//
+// 使用了隔离舱的路由不占用全局协程池, 只受隔离舱的限制
+//
+// name: 隔离舱名, 同名的路由共享同一个隔离舱, 以第一次创建时的参数为准
+// threadCount: 同时处理请求的goroutine数, 设为0时取逻辑cpu数*2, 设为负数时不作任何限制
+// queueSize: 最大请求等待队列大小, 小于1时为10000, 队列已满时返回 ServiceBusy, http状态码为503
+func Bulkhead(name string, threadCount, queueSize int) iris.Handler {
+	v, loaded := bulkheads.Load(name)
+	if !loaded {
+		v, loaded = bulkheads.LoadOrStore(name, newGPoolLimiter(threadCount, queueSize))
+	}
+	limiter := v.(*gpoolLimiter)
+	if !loaded {
+		logger.Log.Debug("创建隔离舱", zap.String("name", name), zap.Int("threadCount", threadCount), zap.Int("queueSize", queueSize))
+	} else if limiter.threadCount != threadCount || limiter.queueSize != queueSize {
+		logger.Log.Warn("隔离舱已存在且参数不同, 将使用已创建的隔离舱",
+			zap.String("name", name),
+			zap.Int("threadCount", threadCount),
+			zap.Int("queueSize", queueSize),
+			zap.Int("existThreadCount", limiter.threadCount),
+			zap.Int("existQueueSize", limiter.queueSize),
+		)
+	}
+
+	h := WrapMiddleware(limiter.Handle)
+	bulkheadHandlers.Store(utils.GetFuncInstanceID(h), struct{}{})
+	return h
+}
+
+// 调用链中剩余的处理程序是否包含隔离舱
+func hasBulkhead(ctx *Context) bool {
+	handlers := ctx.Handlers()
+	for i := ctx.HandlerIndex(-1) + 1; i < len(handlers); i++ {
+		if _, ok := bulkheadHandlers.Load(utils.GetFuncInstanceID(handlers[i])); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// 获取所有隔离舱的统计, key为隔离舱名
+func BulkheadStats() map[string]GPoolStats {
+	stats := make(map[string]GPoolStats)
+	bulkheads.Range(func(key, value interface{}) bool {
+		stats[key.(string)] = value.(*gpoolLimiter).Stats()
+		return true
+	})
+	return stats
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/kataras/iris/v12"
+	"github.com/zly-app/zapp"
+	"github.com/zly-app/zapp/logger"
+
+	"github.com/zly-app/service/api/config"
+	"github.com/zly-app/service/api/utils"
+)
+
+var testAppOnce sync.Once
+
+// 保存请求需要的基础数据, 用于不创建服务直接测试中间件
+func testBaseMiddleware() iris.Handler {
+	testAppOnce.Do(func() {
+		zapp.NewApp("test")
+	})
+	conf := config.NewConfig()
+	conf.Check()
+	return func(irisCtx iris.Context) {
+		utils.Context.SaveConfToIrisContext(irisCtx, conf)
+		utils.Context.SaveLoggerToIrisContext(irisCtx, logger.Log)
+		utils.Context.SaveContextToIrisContext(irisCtx, context.Background())
+		irisCtx.Next()
+	}
+}
+
+func TestBulkheadSkipGlobalPool(t *testing.T) {
+	global := newGPoolLimiter(1, 1)
+	globalRunning := map[string]int64{}
+
+	app := iris.New()
+	app.Use(testBaseMiddleware())
+	app.Use(WrapMiddleware(global.GlobalHandle))
+	app.Get("/bulkhead", Bulkhead("test_skip_global", 1, 1), func(irisCtx iris.Context) {
+		globalRunning["/bulkhead"] = global.Stats().Running
+	})
+	app.Get("/global", func(irisCtx iris.Context) {
+		globalRunning["/global"] = global.Stats().Running
+	})
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path   string
+		expect int64
+	}{
+		{"/bulkhead", 0},
+		{"/global", 1},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatal("响应状态码和预期不符", test.path, w.Code)
+		}
+		if globalRunning[test.path] != test.expect {
+			t.Fatal("全局协程池占用和预期不符", test.path, globalRunning[test.path])
+		}
+	}
+}
@@ -0,0 +1,42 @@
+/*
+-------------------------------------------------
+   Author :       zlyuancn
+   date：         2026/10/19
+   Description :
+-------------------------------------------------
+*/
+
+package cache
+
+import (
+	"net/http"
+	"time"
+)
+
+// 缓存的响应数据
+type Item struct {
+	StatusCode int         // 状态码
+	Header     http.Header // 响应header
+	Body       []byte      // 响应body
+	ETag       string      // 根据body生成的ETag
+	ExpireAt   time.Time   // 过期时间
+}
+
+// 是否已过期
+func (i *Item) IsExpired(now time.Time) bool {
+	return !now.Before(i.ExpireAt)
+}
+
+// 响应缓存存储
+type ICache interface {
+	// 获取缓存, 已过期或不存在时返回 false
+	Get(key string) (*Item, bool)
+	// 设置缓存
+	Set(key string, item *Item)
+	// 删除缓存
+	Del(key string)
+	// 删除所有以 prefix 开头的缓存
+	DelPrefix(prefix string)
+	// 清空缓存
+	Clear()
+}
@@ -0,0 +1,109 @@
+/*
+-------------------------------------------------
+   Author :       zlyuancn
+   date：         2026/10/19
+   Description :
+-------------------------------------------------
+*/
+
+package cache
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 默认lru缓存最大条目数
+const DefaultLRUMaxEntries = 10000
+
+type lruEntry struct {
+	key  string
+	item *Item
+}
+
+// 有界的内存lru缓存
+type LRUCache struct {
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+	mx         sync.Mutex
+}
+
+// 创建一个内存lru缓存, maxEntries 为最大条目数, 超出时淘汰最久未使用的条目, 小于1时使用 DefaultLRUMaxEntries
+func NewLRUCache(maxEntries int) ICache {
+	if maxEntries < 1 {
+		maxEntries = DefaultLRUMaxEntries
+	}
+	return &LRUCache{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+func (l *LRUCache) Get(key string) (*Item, bool) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	e, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	item := e.Value.(*lruEntry).item
+	if item.IsExpired(time.Now()) {
+		l.removeElement(e)
+		return nil, false
+	}
+
+	l.ll.MoveToFront(e)
+	return item, true
+}
+
+func (l *LRUCache) Set(key string, item *Item) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	if e, ok := l.items[key]; ok {
+		e.Value.(*lruEntry).item = item
+		l.ll.MoveToFront(e)
+		return
+	}
+
+	l.items[key] = l.ll.PushFront(&lruEntry{key: key, item: item})
+	for l.ll.Len() > l.maxEntries {
+		l.removeElement(l.ll.Back())
+	}
+}
+
+func (l *LRUCache) Del(key string) {
+	l.mx.Lock()
+	if e, ok := l.items[key]; ok {
+		l.removeElement(e)
+	}
+	l.mx.Unlock()
+}
+
+func (l *LRUCache) DelPrefix(prefix string) {
+	l.mx.Lock()
+	for key, e := range l.items {
+		if strings.HasPrefix(key, prefix) {
+			l.removeElement(e)
+		}
+	}
+	l.mx.Unlock()
+}
+
+func (l *LRUCache) Clear() {
+	l.mx.Lock()
+	l.ll.Init()
+	l.items = make(map[string]*list.Element)
+	l.mx.Unlock()
+}
+
+func (l *LRUCache) removeElement(e *list.Element) {
+	l.ll.Remove(e)
+	delete(l.items, e.Value.(*lruEntry).key)
+}
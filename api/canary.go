@@ -0,0 +1,222 @@
+/*
+-------------------------------------------------
+   Author :       zlyuancn
+   date：         2026/10/19
+   Description :
+-------------------------------------------------
+*/
+
+package api
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/kataras/iris/v12"
+	"github.com/zly-app/zapp"
+	"github.com/zly-app/zapp/core"
+	"github.com/zly-app/zapp/logger"
+	zapp_utils "github.com/zly-app/zapp/pkg/utils"
+	"go.uber.org/zap"
+
+	"github.com/zly-app/service/api/config"
+)
+
+// 默认变体名, 没有匹配的变体规则时选择这个变体
+const DefaultCanaryVariant = "default"
+
+// 选择变体的原因
+const (
+	CanaryReasonOverride  = "override"   // 通过header强制选择
+	CanaryReasonAllowList = "allow_list" // 稳定key在允许列表中
+	CanaryReasonHeader    = "header"     // header匹配
+	CanaryReasonPercent   = "percent"    // 按百分比选中
+	CanaryReasonDefault   = "default"    // 没有匹配的变体规则
+)
+
+// 变体处理程序, key为变体名, 必须包含 DefaultCanaryVariant
+type CanaryVariants map[string]iris.Handler
+
+// 灰度路由配置
+type CanaryConfig struct {
+	Canaries map[string]config.CanaryRule `json:"Canaries" yaml:"Canaries"` // 灰度路由规则, key为规则名
+}
+
+// 灰度路由, 一个路由有多个变体处理程序, 使用配置中 Canaries 里名为 name 的规则选择变体
+//
+// 规则不存在时总是选择默认变体. 选择的变体会记录在链路和日志中, 规则可以通过 ApiService.SetCanaryConfig 或配置中心在运行时修改
+func Canary(name string, variants CanaryVariants) iris.Handler {
+	if _, ok := variants[DefaultCanaryVariant]; !ok {
+		logger.Log.Fatal("灰度路由必须包含默认变体", zap.String("name", name), zap.String("defaultVariant", DefaultCanaryVariant))
+	}
+	return func(irisCtx iris.Context) {
+		ctx := makeContext(irisCtx)
+		variant, reason := selectCanaryVariant(ctx, name)
+		handler, ok := variants[variant]
+		if !ok {
+			ctx.Warn("灰度路由的变体不存在, 使用默认变体", zap.String("name", name), zap.String("variant", variant))
+			variant, reason = DefaultCanaryVariant, CanaryReasonDefault
+			handler = variants[DefaultCanaryVariant]
+		}
+
+		span := zapp_utils.Otel.GetSpan(ctx.Context())
+		zapp_utils.Otel.SetSpanAttributes(span, zapp_utils.OtelSpanKey("canary."+name).String(variant))
+		fields := []interface{}{"api.canary", zap.String("name", name), zap.String("variant", variant), zap.String("reason", reason)}
+		if ctx.conf.ReqLogLevelIsInfo {
+			ctx.Info(fields...)
+		} else {
+			ctx.Debug(fields...)
+		}
+
+		handler(irisCtx)
+	}
+}
+
+// 选择变体
+func selectCanaryVariant(ctx *Context, name string) (variant string, reason string) {
+	if v := canaryOverride(ctx, name); v != "" {
+		return v, CanaryReasonOverride
+	}
+
+	rule, ok := ctx.conf.Canaries[name]
+	if !ok {
+		rule, ok = ctx.conf.Canaries[strings.ToLower(name)] // 从配置文件加载时key会被转为小写
+	}
+	if !ok {
+		return DefaultCanaryVariant, CanaryReasonDefault
+	}
+	for _, vr := range rule.Variants {
+		if reason = matchCanaryVariant(ctx, name, vr); reason != "" {
+			return vr.Variant, reason
+		}
+	}
+	return DefaultCanaryVariant, CanaryReasonDefault
+}
+
+// 获取强制选择的变体
+func canaryOverride(ctx *Context, name string) string {
+	if ctx.conf.CanaryOverrideHeader == "" || ctx.conf.CanaryOverrideHeader == "-" {
+		return ""
+	}
+	value := ctx.GetHeader(ctx.conf.CanaryOverrideHeader)
+	if value == "" {
+		return ""
+	}
+
+	global := ""
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		k := strings.IndexByte(item, '=')
+		if k == -1 {
+			global = item
+			continue
+		}
+		if strings.TrimSpace(item[:k]) == name {
+			return strings.TrimSpace(item[k+1:])
+		}
+	}
+	return global
+}
+
+// 检查变体规则是否匹配, 返回匹配的原因, 不匹配时返回空字符串
+func matchCanaryVariant(ctx *Context, name string, vr config.CanaryVariantRule) string {
+	key := canaryKey(ctx, vr.Key)
+	if key != "" && containsString(vr.AllowList, key) {
+		return CanaryReasonAllowList
+	}
+	if len(vr.Headers) > 0 {
+		matched := true
+		for k, v := range vr.Headers {
+			if ctx.GetHeader(k) != v {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return CanaryReasonHeader
+		}
+	}
+	if vr.Percent > 0 && key != "" && float64(canaryBucket(name, key)) < vr.Percent*100 {
+		return CanaryReasonPercent
+	}
+	return ""
+}
+
+// 获取稳定key
+func canaryKey(ctx *Context, source string) string {
+	if strings.HasPrefix(source, "header:") {
+		return ctx.GetHeader(source[len("header:"):])
+	}
+	return ctx.RemoteAddr()
+}
+
+// 计算稳定key所在的桶, 取值 0~9999. 不同规则使用不同的分桶, 避免同一批用户总是进入所有灰度
+func canaryBucket(name, key string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	_, _ = h.Write([]byte{':'})
+	_, _ = h.Write([]byte(key))
+	return h.Sum32() % 10000
+}
+
+// 检查灰度路由配置
+func checkCanaryConfig(c *CanaryConfig) error {
+	for name, rule := range c.Canaries {
+		for i, vr := range rule.Variants {
+			if vr.Variant == "" {
+				return fmt.Errorf("Canaries.%s.Variants[%d]: Variant is empty", name, i)
+			}
+			if vr.Percent < 0 || vr.Percent > 100 {
+				return fmt.Errorf("Canaries.%s.Variants[%d]: Percent must be between 0 and 100", name, i)
+			}
+			if vr.Key != "" && vr.Key != "ip" && (!strings.HasPrefix(vr.Key, "header:") || vr.Key == "header:") {
+				return fmt.Errorf("Canaries.%s.Variants[%d]: Key must be ip or header:<name>", name, i)
+			}
+		}
+	}
+	return nil
+}
+
+// 获取当前的灰度路由配置
+func (a *ApiService) CanaryConfig() CanaryConfig {
+	return CanaryConfig{Canaries: a.Config().Canaries}
+}
+
+// 在运行时替换灰度路由配置, 对之后的请求生效
+func (a *ApiService) SetCanaryConfig(c CanaryConfig) error {
+	if err := checkCanaryConfig(&c); err != nil {
+		return err
+	}
+	a.UpdateConfig(func(conf *config.Config) {
+		conf.Canaries = c.Canaries
+	})
+	return nil
+}
+
+// 初始化灰度路由
+func (a *ApiService) initCanary() {
+	if err := checkCanaryConfig(&CanaryConfig{Canaries: a.conf.Canaries}); err != nil {
+		a.app.Fatal("api服务的灰度路由配置错误", zap.Error(err))
+	}
+
+	if a.conf.CanaryWatchGroup == "" || a.conf.CanaryWatchKey == "" {
+		return
+	}
+	// 配置观察需要在app初始化完成后才能使用
+	zapp.AddHandler(zapp.AfterInitializeHandler, func(app core.IApp, handlerType zapp.HandlerType) {
+		w := app.GetConfig().WatchKey(a.conf.CanaryWatchGroup, a.conf.CanaryWatchKey)
+		w.AddCallback(func(isInit bool, oldData, newData []byte) {
+			var c CanaryConfig
+			if err := w.ParseYaml(&c); err != nil {
+				app.Error("解析api服务的灰度路由配置失败", zap.String("data", string(newData)), zap.Error(err))
+				return
+			}
+			if err := a.SetCanaryConfig(c); err != nil {
+				app.Error("api服务的灰度路由配置错误", zap.String("data", string(newData)), zap.Error(err))
+				return
+			}
+			app.Info("api服务的灰度路由配置已更新", zap.Bool("isInit", isInit), zap.Any("config", c))
+		})
+	})
+}
@@ -0,0 +1,309 @@
+/*
+-------------------------------------------------
+   Author :       zlyuancn
+   date：         2026/10/19
+   Description :
+-------------------------------------------------
+*/
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kataras/iris/v12"
+	iris_context "github.com/kataras/iris/v12/context"
+	"github.com/zly-app/zapp/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// 默认统计窗口
+	defaultCircuitBreakerWindow = time.Second * 10
+	// 默认窗口内最少请求数
+	defaultCircuitBreakerMinRequestCount = 20
+	// 默认错误率阈值
+	defaultCircuitBreakerErrorRateThreshold = 0.5
+	// 默认熔断持续时间
+	defaultCircuitBreakerOpenDuration = time.Second * 5
+	// 默认半开状态允许通过的探测请求数
+	defaultCircuitBreakerHalfOpenMaxRequests = 1
+)
+
+// 熔断器状态
+type CircuitBreakerState int32
+
+const (
+	// 关闭, 请求正常通过
+	CircuitBreakerClosed CircuitBreakerState = iota
+	// 打开, 请求直接失败
+	CircuitBreakerOpened
+	// 半开, 允许少量探测请求通过
+	CircuitBreakerHalfOpen
+)
+
+func (s CircuitBreakerState) String() string {
+	switch s {
+	case CircuitBreakerClosed:
+		return "closed"
+	case CircuitBreakerOpened:
+		return "open"
+	case CircuitBreakerHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("undefined circuit breaker state: %d", s)
+}
+
+// 熔断器配置
+type CircuitBreakerConfig struct {
+	Window                time.Duration // 统计窗口, 默认10秒
+	MinRequestCount       int           // 窗口内最少请求数, 达到后才会计算错误率和慢调用率, 默认20
+	ErrorRateThreshold    float64       // 错误率阈值, 取值0~1, 默认0.5, 设为负数表示不根据错误率熔断
+	SlowCallDuration      time.Duration // 慢调用耗时, 超过这个耗时视为慢调用, 0表示不统计慢调用
+	SlowCallRateThreshold float64       // 慢调用率阈值, 取值0~1, 只有 SlowCallDuration > 0 时生效
+	OpenDuration          time.Duration // 熔断持续时间, 之后进入半开状态, 默认5秒
+	HalfOpenMaxRequests   int           // 半开状态允许通过的探测请求数, 默认1
+}
+
+func (conf *CircuitBreakerConfig) check() {
+	if conf.Window <= 0 {
+		conf.Window = defaultCircuitBreakerWindow
+	}
+	if conf.MinRequestCount < 1 {
+		conf.MinRequestCount = defaultCircuitBreakerMinRequestCount
+	}
+	if conf.ErrorRateThreshold == 0 {
+		conf.ErrorRateThreshold = defaultCircuitBreakerErrorRateThreshold
+	}
+	if conf.OpenDuration <= 0 {
+		conf.OpenDuration = defaultCircuitBreakerOpenDuration
+	}
+	if conf.HalfOpenMaxRequests < 1 {
+		conf.HalfOpenMaxRequests = defaultCircuitBreakerHalfOpenMaxRequests
+	}
+}
+
+// 熔断器统计
+type CircuitBreakerStats struct {
+	State         string    // 状态
+	RequestCount  int       // 当前窗口内的请求数
+	ErrorCount    int       // 当前窗口内的错误数
+	SlowCallCount int       // 当前窗口内的慢调用数
+	OpenedAt      time.Time // 最近一次熔断的时间
+}
+
+// 熔断器
+type CircuitBreaker struct {
+	name string
+	conf CircuitBreakerConfig
+
+	state         CircuitBreakerState
+	windowStart   time.Time
+	requestCount  int
+	errorCount    int
+	slowCallCount int
+	openedAt      time.Time
+	halfOpenCount int // 半开状态已放行的探测请求数
+
+	mx sync.Mutex
+}
+
+// 已创建的熔断器, name -> *CircuitBreaker
+var circuitBreakers sync.Map
+
+// 创建熔断器, 同名的熔断器只会创建一次
+func NewCircuitBreaker(name string, conf CircuitBreakerConfig) *CircuitBreaker {
+	conf.check()
+	b := &CircuitBreaker{
+		name:        name,
+		conf:        conf,
+		state:       CircuitBreakerClosed,
+		windowStart: time.Now(),
+	}
+	v, _ := circuitBreakers.LoadOrStore(name, b)
+	return v.(*CircuitBreaker)
+}
+
+// 获取熔断器, 如果不存在返回nil
+func GetCircuitBreaker(name string) *CircuitBreaker {
+	v, ok := circuitBreakers.Load(name)
+	if !ok {
+		return nil
+	}
+	return v.(*CircuitBreaker)
+}
+
+// 获取所有熔断器的统计, key为熔断器名
+func CircuitBreakerStatsAll() map[string]CircuitBreakerStats {
+	stats := make(map[string]CircuitBreakerStats)
+	circuitBreakers.Range(func(key, value interface{}) bool {
+		stats[key.(string)] = value.(*CircuitBreaker).Stats()
+		return true
+	})
+	return stats
+}
+
+// 熔断器名
+func (b *CircuitBreaker) Name() string {
+	return b.name
+}
+
+// 当前状态
+func (b *CircuitBreaker) State() CircuitBreakerState {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.refreshState(time.Now())
+	return b.state
+}
+
+// 当前统计
+func (b *CircuitBreaker) Stats() CircuitBreakerStats {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.refreshState(time.Now())
+	return CircuitBreakerStats{
+		State:         b.state.String(),
+		RequestCount:  b.requestCount,
+		ErrorCount:    b.errorCount,
+		SlowCallCount: b.slowCallCount,
+		OpenedAt:      b.openedAt,
+	}
+}
+
+// 是否允许请求通过, 允许通过的请求必须在结束后调用 Report
+func (b *CircuitBreaker) Allow() bool {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	b.refreshState(time.Now())
+	switch b.state {
+	case CircuitBreakerOpened:
+		return false
+	case CircuitBreakerHalfOpen:
+		if b.halfOpenCount >= b.conf.HalfOpenMaxRequests {
+			return false
+		}
+		b.halfOpenCount++
+	}
+	return true
+}
+
+// 报告请求结果
+func (b *CircuitBreaker) Report(isErr bool, latency time.Duration) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	now := time.Now()
+	b.refreshState(now)
+	isSlow := b.conf.SlowCallDuration > 0 && latency >= b.conf.SlowCallDuration
+
+	switch b.state {
+	case CircuitBreakerHalfOpen: // 根据探测结果决定关闭还是重新打开
+		if isErr || isSlow {
+			b.setState(CircuitBreakerOpened, now, "探测请求失败")
+		} else {
+			b.setState(CircuitBreakerClosed, now, "探测请求成功")
+		}
+		return
+	case CircuitBreakerOpened:
+		return
+	}
+
+	b.requestCount++
+	if isErr {
+		b.errorCount++
+	}
+	if isSlow {
+		b.slowCallCount++
+	}
+	if b.requestCount < b.conf.MinRequestCount {
+		return
+	}
+
+	if b.conf.ErrorRateThreshold > 0 && float64(b.errorCount)/float64(b.requestCount) >= b.conf.ErrorRateThreshold {
+		b.setState(CircuitBreakerOpened, now, "错误率超出阈值")
+		return
+	}
+	if b.conf.SlowCallDuration > 0 && b.conf.SlowCallRateThreshold > 0 &&
+		float64(b.slowCallCount)/float64(b.requestCount) >= b.conf.SlowCallRateThreshold {
+		b.setState(CircuitBreakerOpened, now, "慢调用率超出阈值")
+	}
+}
+
+// 根据时间刷新状态和统计窗口
+func (b *CircuitBreaker) refreshState(now time.Time) {
+	if b.state == CircuitBreakerOpened && now.Sub(b.openedAt) >= b.conf.OpenDuration {
+		b.setState(CircuitBreakerHalfOpen, now, "熔断时间结束")
+		return
+	}
+	if b.state == CircuitBreakerClosed && now.Sub(b.windowStart) >= b.conf.Window {
+		b.resetWindow(now)
+	}
+}
+
+func (b *CircuitBreaker) setState(state CircuitBreakerState, now time.Time, reason string) {
+	logger.Log.Warn("熔断器状态变更",
+		zap.String("name", b.name),
+		zap.String("from", b.state.String()),
+		zap.String("to", state.String()),
+		zap.String("reason", reason),
+		zap.Int("requestCount", b.requestCount),
+		zap.Int("errorCount", b.errorCount),
+		zap.Int("slowCallCount", b.slowCallCount),
+	)
+
+	b.state = state
+	b.halfOpenCount = 0
+	if state == CircuitBreakerOpened {
+		b.openedAt = now
+	}
+	b.resetWindow(now)
+}
+
+func (b *CircuitBreaker) resetWindow(now time.Time) {
+	b.windowStart = now
+	b.requestCount = 0
+	b.errorCount = 0
+	b.slowCallCount = 0
+}
+
+// 熔断器中间件, 熔断器打开时直接返回 CircuitBreakerOpen, http状态码为503
+//
+// panic, 状态码>=500以及 ServiceInternalError 或非 api.Error 的错误会被视为错误, 同名的路由共享同一个熔断器
+func CircuitBreakerMiddleware(name string, conf CircuitBreakerConfig) iris.Handler {
+	b := NewCircuitBreaker(name, conf)
+	return func(irisCtx *iris_context.Context) {
+		if !b.Allow() {
+			ctx := makeContext(irisCtx)
+			ctx.StatusCode(http.StatusServiceUnavailable)
+			WriteToCtx(ctx, CircuitBreakerOpen)
+			ctx.StopExecution()
+			return
+		}
+
+		startTime := time.Now()
+		defer func() {
+			if e := recover(); e != nil {
+				b.Report(true, time.Since(startTime))
+				panic(e)
+			}
+		}()
+
+		irisCtx.Next()
+
+		isErr := irisCtx.GetStatusCode() >= http.StatusInternalServerError
+		if err, ok := irisCtx.Values().Get("error").(error); ok {
+			isErr = isErr || isCircuitBreakerErr(err)
+		}
+		b.Report(isErr, time.Since(startTime))
+	}
+}
+
+// 是否为熔断器需要统计的错误, 参数错误, 认证错误等业务错误不会被统计
+func isCircuitBreakerErr(err error) bool {
+	code, _ := decodeErr(err)
+	return code == ServiceInternalError.Code
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerOpen(t *testing.T) {
+	type report struct {
+		isErr   bool
+		latency time.Duration
+	}
+	ok, fail, slow := report{}, report{isErr: true}, report{latency: time.Second}
+
+	tests := []struct {
+		name    string
+		conf    CircuitBreakerConfig
+		reports []report
+		expect  CircuitBreakerState
+	}{
+		{"error_rate", CircuitBreakerConfig{MinRequestCount: 4}, []report{ok, fail, ok, fail}, CircuitBreakerOpened},
+		{"error_rate_below_threshold", CircuitBreakerConfig{MinRequestCount: 4}, []report{ok, fail, ok, ok}, CircuitBreakerClosed},
+		{"below_min_request_count", CircuitBreakerConfig{MinRequestCount: 4}, []report{fail, fail, fail}, CircuitBreakerClosed},
+		{"error_rate_disabled", CircuitBreakerConfig{MinRequestCount: 2, ErrorRateThreshold: -1}, []report{fail, fail, fail}, CircuitBreakerClosed},
+		{"slow_call_rate", CircuitBreakerConfig{MinRequestCount: 2, ErrorRateThreshold: -1, SlowCallDuration: time.Millisecond * 100, SlowCallRateThreshold: 0.5}, []report{ok, slow}, CircuitBreakerOpened},
+		{"slow_call_disabled", CircuitBreakerConfig{MinRequestCount: 2, ErrorRateThreshold: -1}, []report{slow, slow}, CircuitBreakerClosed},
+	}
+	for _, test := range tests {
+		b := NewCircuitBreaker("test_open_"+test.name, test.conf)
+		for _, r := range test.reports {
+			if !b.Allow() {
+				t.Fatal("熔断前应该允许请求通过", test.name)
+			}
+			b.Report(r.isErr, r.latency)
+		}
+		if state := b.State(); state != test.expect {
+			t.Fatal("熔断器状态和预期不符", test.name, state)
+		}
+		if test.expect == CircuitBreakerOpened && b.Allow() {
+			t.Fatal("熔断后不应该允许请求通过", test.name)
+		}
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	tests := []struct {
+		name        string
+		probeIsErr  bool
+		expectState CircuitBreakerState
+	}{
+		{"probe_success", false, CircuitBreakerClosed},
+		{"probe_failure", true, CircuitBreakerOpened},
+	}
+	for _, test := range tests {
+		b := NewCircuitBreaker("test_half_open_"+test.name, CircuitBreakerConfig{
+			MinRequestCount:     1,
+			OpenDuration:        time.Millisecond * 20,
+			HalfOpenMaxRequests: 1,
+		})
+		b.Report(true, 0)
+		if state := b.State(); state != CircuitBreakerOpened {
+			t.Fatal("熔断器应该打开", test.name, state)
+		}
+
+		time.Sleep(time.Millisecond * 30)
+		if state := b.State(); state != CircuitBreakerHalfOpen {
+			t.Fatal("熔断时间结束后应该进入半开状态", test.name, state)
+		}
+		if !b.Allow() {
+			t.Fatal("半开状态应该允许探测请求通过", test.name)
+		}
+		if b.Allow() {
+			t.Fatal("半开状态放行的探测请求数超出限制", test.name)
+		}
+
+		b.Report(test.probeIsErr, 0)
+		if state := b.State(); state != test.expectState {
+			t.Fatal("探测后的熔断器状态和预期不符", test.name, state)
+		}
+	}
+}
+
+func TestCircuitBreakerWindow(t *testing.T) {
+	b := NewCircuitBreaker("test_window", CircuitBreakerConfig{Window: time.Millisecond * 20, MinRequestCount: 2})
+	b.Report(true, 0)
+	time.Sleep(time.Millisecond * 30)
+	b.Report(true, 0)
+	if state := b.State(); state != CircuitBreakerClosed {
+		t.Fatal("统计窗口结束后应该重新统计", state)
+	}
+	if stats := b.Stats(); stats.RequestCount != 1 || stats.ErrorCount != 1 {
+		t.Fatal("统计窗口内的请求数和预期不符", stats)
+	}
+}
+
+func TestIsCircuitBreakerErr(t *testing.T) {
+	tests := []struct {
+		err    error
+		expect bool
+	}{
+		{errors.New("err"), true},
+		{ServiceInternalError, true},
+		{ParamError, false},
+		{AuthorizationError, false},
+	}
+	for _, test := range tests {
+		if isErr := isCircuitBreakerErr(test.err); isErr != test.expect {
+			t.Fatal("是否统计错误和预期不符", test.err, isErr)
+		}
+	}
+}
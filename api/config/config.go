@@ -9,7 +9,9 @@
 package config
 
 import (
+	"fmt"
 	"runtime"
+	"strings"
 )
 
 const (
@@ -23,6 +25,28 @@ const (
 	defaultIPWithProxyReal = true
 	// 默认post允许最大数据大小(128M)
 	defaultPostMaxMemory = 128 << 20
+	// 默认监听器网络类型
+	defaultListenerNetwork = "tcp"
+
+	// 默认链路透传格式
+	defaultTracePropagators = "tracecontext,baggage"
+	// 默认在响应header中返回traceID的字段名
+	defaultTraceIDHeader = "X-Trace-Id"
+	// 默认admin接口路径前缀
+	defaultAdminPath = "/_admin"
+	// 默认响应字段名
+	defaultResponseErrCodeField = "err_code"
+	defaultResponseErrMsgField  = "err_msg"
+	defaultResponseDataField    = "data"
+	defaultResponseTraceIDField = "trace_id"
+	// 列表请求默认每页数量
+	defaultListDefaultPageSize = 20
+	// 列表请求最大每页数量
+	defaultListMaxPageSize = 100
+	// 默认请求记录文件
+	defaultRecordPath = "./records/api.jsonl"
+	// 默认请求记录采样率
+	defaultRecordSampleRate = 1
 
 	// 同时处理请求的goroutine数
 	defThreadCount = 0
@@ -53,12 +77,48 @@ const (
 
 // api服务配置
 type Config struct {
-	Bind                   string // bind地址
+	Bind                   string // bind地址, 未设置 Listeners 时使用
 	IPWithIngressForwarded bool   // 适配ingress的X-Original-Forwarded-For获取ip, 优先级高于X-Forwarded-For
 	IPWithProxyForwarded   bool   // 适配proxy的X-Forwarded-For获取ip, 优先级高于X-Real-Ip
 	IPWithProxyReal        bool   // 适配proxy的X-Real-Ip获取ip, 优先级高于sock连接的ip
 	PostMaxMemory          int64  // post允许客户端传输最大数据大小, 单位字节
 
+	// 监听器列表, 设置后忽略 Bind. 每个监听器可以有自己的网络类型, 地址, tls配置和暴露的路由组
+	Listeners []ListenerConfig
+
+	// 信任的代理ip段, 支持CIDR和单个ip
+	//
+	// 设置后只有连接的对端在这些ip段中时才会通过 X-Forwarded-For 等header解析客户端ip, 防止客户端伪造ip.
+	// 为空时总是信任转发header
+	TrustedProxies []string
+	// ip过滤规则, key为规则名, 通过 api.IPFilter(name) 在路由组上使用, 可以在运行时修改
+	IPFilters map[string]IPFilterRule
+	// 从配置中心观察ip过滤配置, 配置内容为包含 TrustedProxies 和 IPFilters 的yaml, 变更后立即生效
+	IPFilterWatchGroup string
+	IPFilterWatchKey   string
+
+	// 灰度路由规则, key为规则名, 通过 api.Canary(name, variants) 在路由上使用, 可以在运行时修改
+	Canaries map[string]CanaryRule
+	// 从配置中心观察灰度路由配置, 配置内容为包含 Canaries 的yaml, 变更后立即生效
+	CanaryWatchGroup string
+	CanaryWatchKey   string
+	// 用于测试时强制选择变体的header, 例如 X-Canary-Variant, 为空或设为 - 表示不允许强制选择
+	//
+	// 值为变体名时对所有灰度路由生效, 也可以用 规则名=变体名 指定规则, 多个用英文逗号分隔
+	CanaryOverrideHeader string
+
+	// grpc服务bind地址, 为空时不提供grpc服务
+	//
+	// 通过 RegistryDualRouter 注册的handler会同时提供http和grpc服务, 设为和 Bind 或某个tcp监听器相同的地址时和http服务共用端口(h2c)
+	GrpcBind string
+
+	// 链路透传格式, 多个格式用英文逗号分隔, 从请求header中提取上游的链路数据
+	//
+	// 可选 tracecontext(W3C traceparent/tracestate), baggage, b3(单header), b3multi(多header), jaeger
+	TracePropagators string
+	// 在响应header中返回traceID的字段名, 设为 - 表示不返回
+	TraceIDHeader string
+
 	// 同时处理请求的goroutine数, 设为0时取逻辑cpu数*2, 设为负数时不作任何限制, 每个请求由独立的线程执行
 	ThreadCount int
 	// 最大请求等待队列大小
@@ -77,6 +137,59 @@ type Config struct {
 	AlwaysLogBody                 bool  // 总是输出body日志, 如果设为false, 只会在出现错误时才会输出body日志
 	LogApiResultMaxSize           int   // 日志输出结果最大大小
 	LogBodyMaxSize                int64 // 日志输出请求body最大大小
+
+	ListDefaultPageSize int // 列表请求默认每页数量, 可以在 list tag 中覆盖
+	ListMaxPageSize     int // 列表请求最大每页数量, 可以在 list tag 中覆盖
+
+	// 默认响应结构的字段名, 设置后不需要通过 api.SetWriteResponseFunc 重新实现整个响应写入函数
+	ResponseErrCodeField string // 错误码字段名, 默认 err_code
+	ResponseErrMsgField  string // 错误信息字段名, 默认 err_msg
+	ResponseDataField    string // 数据字段名, 默认 data
+	ResponseTraceIDField string // 链路id字段名, 默认 trace_id
+
+	AdminEnable bool   // 启用admin接口, 提供pprof, 路由列表, 配置查看, 协程池统计, 运行时切换日志开关
+	AdminBind   string // admin接口独立的bind地址, 为空时挂载在api服务的 AdminPath 下
+	AdminPath   string // admin接口路径前缀
+	AdminToken  string // admin接口认证token, 请求时需要带上header X-Admin-Token, 挂载在api服务下或 AdminBind 不是回环地址时必须设置
+
+	RecordEnable       bool     // 启用请求记录, 将采样的请求和响应写入文件, 用于之后重放比较结果
+	RecordPath         string   // 请求记录文件
+	RecordSampleRate   float64  // 请求记录采样率, 取值0~1
+	RecordMaxFileSize  int64    // 单个请求记录文件最大大小, 超出后轮转, 默认100M
+	RecordMaxBackups   int      // 保留的历史请求记录文件数, 默认10
+	RecordMaxBodySize  int64    // 记录请求body的最大大小, 超出时body会被截断且这条记录无法重放, 默认1M
+	RecordScrubHeaders []string // 除了默认的认证类header外, 需要额外隐藏的header
+}
+
+// 监听器配置
+type ListenerConfig struct {
+	Name        string   // 监听器名, 默认为 listener-序号
+	Network     string   // 网络类型, 可选 tcp, unix, 默认 tcp
+	Addr        string   // 监听地址, tcp为 host:port, unix为socket文件路径
+	TLSCertFile string   // tls证书文件, 和 TLSKeyFile 同时设置时启用tls
+	TLSKeyFile  string   // tls私钥文件
+	H2C         bool     // 允许不使用tls的http2(h2c)连接
+	Groups      []string // 暴露的路由组, 为空时暴露所有路由组
+}
+
+// 灰度路由规则, 按顺序检查变体规则, 选择第一个匹配的变体, 都不匹配时选择默认变体
+type CanaryRule struct {
+	Variants []CanaryVariantRule `json:"Variants" yaml:"Variants"`
+}
+
+// 变体规则, 满足任意一个条件时选择这个变体
+type CanaryVariantRule struct {
+	Variant   string            `json:"Variant" yaml:"Variant"`     // 变体名
+	Key       string            `json:"Key" yaml:"Key"`             // 稳定key的来源, ip 或 header:名称, 例如 header:X-User-Id, 默认为ip
+	Percent   float64           `json:"Percent" yaml:"Percent"`     // 按稳定key选择的百分比, 取值0~100, 相同的key总是得到相同的结果
+	Headers   map[string]string `json:"Headers" yaml:"Headers"`     // header匹配, 所有header都相等时选择这个变体
+	AllowList []string          `json:"AllowList" yaml:"AllowList"` // 允许列表, 稳定key在列表中时选择这个变体
+}
+
+// ip过滤规则, 先检查拒绝列表, 如果允许列表不为空, ip必须在允许列表中
+type IPFilterRule struct {
+	Allow []string `json:"Allow" yaml:"Allow"` // 允许的ip段, 支持CIDR和单个ip
+	Deny  []string `json:"Deny" yaml:"Deny"`   // 拒绝的ip段, 支持CIDR和单个ip
 }
 
 func NewConfig() *Config {
@@ -85,6 +198,16 @@ func NewConfig() *Config {
 		IPWithIngressForwarded: defaultIPWithIngressForwarded,
 		IPWithProxyForwarded:   defaultIPWithProxyForwarded,
 		IPWithProxyReal:        defaultIPWithProxyReal,
+		TracePropagators:       defaultTracePropagators,
+		TraceIDHeader:          defaultTraceIDHeader,
+
+		ListDefaultPageSize: defaultListDefaultPageSize,
+		ListMaxPageSize:     defaultListMaxPageSize,
+
+		ResponseErrCodeField: defaultResponseErrCodeField,
+		ResponseErrMsgField:  defaultResponseErrMsgField,
+		ResponseDataField:    defaultResponseDataField,
+		ResponseTraceIDField: defaultResponseTraceIDField,
 
 		ThreadCount: defThreadCount,
 
@@ -106,6 +229,12 @@ func (conf *Config) Check() {
 	if conf.PostMaxMemory < 1 {
 		conf.PostMaxMemory = defaultPostMaxMemory
 	}
+	if conf.TracePropagators == "" {
+		conf.TracePropagators = defaultTracePropagators
+	}
+	if conf.TraceIDHeader == "" {
+		conf.TraceIDHeader = defaultTraceIDHeader
+	}
 
 	if conf.ThreadCount == 0 {
 		conf.ThreadCount = runtime.NumCPU() * 2
@@ -123,4 +252,54 @@ func (conf *Config) Check() {
 	if conf.LogBodyMaxSize < 1 {
 		conf.LogBodyMaxSize = defaultLogBodyMaxSize
 	}
+
+	for i := range conf.Listeners {
+		l := &conf.Listeners[i]
+		if l.Name == "" {
+			l.Name = fmt.Sprintf("listener-%d", i)
+		}
+		l.Network = strings.ToLower(l.Network)
+		if l.Network == "" {
+			l.Network = defaultListenerNetwork
+		}
+	}
+
+	if conf.ListDefaultPageSize < 1 {
+		conf.ListDefaultPageSize = defaultListDefaultPageSize
+	}
+	if conf.ListMaxPageSize < 1 {
+		conf.ListMaxPageSize = defaultListMaxPageSize
+	}
+
+	if conf.ResponseErrCodeField == "" {
+		conf.ResponseErrCodeField = defaultResponseErrCodeField
+	}
+	if conf.ResponseErrMsgField == "" {
+		conf.ResponseErrMsgField = defaultResponseErrMsgField
+	}
+	if conf.ResponseDataField == "" {
+		conf.ResponseDataField = defaultResponseDataField
+	}
+	if conf.ResponseTraceIDField == "" {
+		conf.ResponseTraceIDField = defaultResponseTraceIDField
+	}
+
+	if conf.AdminPath == "" {
+		conf.AdminPath = defaultAdminPath
+	}
+
+	if conf.RecordPath == "" {
+		conf.RecordPath = defaultRecordPath
+	}
+	if conf.RecordSampleRate <= 0 {
+		conf.RecordSampleRate = defaultRecordSampleRate
+	}
+}
+
+// 是否使用默认的响应字段名
+func (conf *Config) IsDefaultResponseFields() bool {
+	return conf.ResponseErrCodeField == defaultResponseErrCodeField &&
+		conf.ResponseErrMsgField == defaultResponseErrMsgField &&
+		conf.ResponseDataField == defaultResponseDataField &&
+		conf.ResponseTraceIDField == defaultResponseTraceIDField
 }
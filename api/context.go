@@ -10,6 +10,7 @@ package api
 
 import (
 	"context"
+	"net/http"
 	"reflect"
 
 	"github.com/kataras/iris/v12"
@@ -43,7 +44,15 @@ func makeContext(irisCtx iris.Context) *Context {
 
 //  bind api数据, 它会将api数据反序列化到a中, 如果a是结构体会验证a
 func (c *Context) Bind(a interface{}) error {
-	if err := c.ReadBody(a); err != nil {
+	if call := getGrpcCall(c); call != nil { // grpc请求直接使用已解码的请求数据
+		if err := call.bindTo(a); err != nil {
+			return ParamError.WithError(err)
+		}
+	} else if err := c.ReadBody(a); err != nil {
+		if c.Values().GetBoolDefault(bodyTooLargeFieldKey, false) {
+			c.StatusCode(http.StatusRequestEntityTooLarge)
+			return RequestBodyTooLarge.WithError(err)
+		}
 		return ParamError.WithError(err)
 	}
 
@@ -65,6 +74,11 @@ func (c *Context) Bind(a interface{}) error {
 	if err != nil {
 		return ParamError.WithError(err)
 	}
+	if val.CanAddr() {
+		if err = bindListRequest(c.conf, val); err != nil {
+			return ParamError.WithError(err)
+		}
+	}
 	return nil
 }
 
@@ -77,3 +91,22 @@ func (c *Context) RemoteAddr() string {
 func (c *Context) Context() context.Context {
 	return c.ctx
 }
+
+// 获取panic的原始值, 只有在panic恢复后才会存在
+func (c *Context) PanicValue() (interface{}, bool) {
+	v := c.Values().Get("panic_value")
+	return v, v != nil
+}
+
+// 获取当前处理程序的名称
+func (c *Context) CurrentHandlerName() string {
+	return c.Values().GetStringDefault("_handler_name", c.HandlerName())
+}
+
+// 获取请求所在的监听器名, 不是来自监听器的请求返回空字符串
+func (c *Context) ListenerName() string {
+	if l := getListener(c); l != nil {
+		return l.conf.Name
+	}
+	return ""
+}
@@ -14,6 +14,11 @@ var (
 	ParamError            = &Error{Code: 2, Message: "param error"}
 	AuthorizationRequired = &Error{Code: 3, Message: "authorization required"}
 	AuthorizationError    = &Error{Code: 4, Message: "authorization error"}
+	RequestBodyTooLarge   = &Error{Code: 5, Message: "request body too large"}
+	ServiceBusy           = &Error{Code: 6, Message: "service busy"}
+	CircuitBreakerOpen    = &Error{Code: 7, Message: "circuit breaker open"}
+	RouteNotFound         = &Error{Code: 8, Message: "route not found"}
+	MethodNotAllowed      = &Error{Code: 9, Message: "method not allowed"}
 )
 
 type Error struct {
@@ -0,0 +1,59 @@
+/*
+-------------------------------------------------
+   Author :       zlyuancn
+   date：         2026/10/19
+   Description :
+-------------------------------------------------
+*/
+
+package api
+
+import (
+	"fmt"
+
+	"github.com/kataras/iris/v12"
+	app_config "github.com/zly-app/zapp/config"
+	"github.com/zly-app/zapp/core"
+	app_utils "github.com/zly-app/zapp/pkg/utils"
+
+	"github.com/zly-app/service/api/config"
+	"github.com/zly-app/service/api/middleware"
+)
+
+// panic恢复, 恢复后通过写入响应函数返回 ServiceInternalError
+func recoverMiddleware() iris.Handler {
+	isDebug := app_config.Conf.Config().Frame.Debug
+	recoverHandler := middleware.Recover()
+	return func(irisCtx iris.Context) {
+		recoverHandler(irisCtx)
+		if panicked, _ := irisCtx.Values().Get("panic").(bool); !panicked {
+			return
+		}
+
+		ctx := makeContext(irisCtx)
+		message := ServiceInternalError.Message
+		if isDebug || ctx.conf.SendDetailedErrorInProduction {
+			err, _ := irisCtx.Values().Get("error").(error)
+			message = fmt.Sprintf("Recovered from a route's Handler: %s\n%s",
+				ctx.CurrentHandlerName(), app_utils.Recover.GetRecoverErrorDetail(err))
+		}
+		writeErrorResponse(ctx, ServiceInternalError.Code, message)
+	}
+}
+
+// 错误状态码处理程序, 通过写入响应函数返回 e
+func errorCodeHandler(e *Error) iris.Handler {
+	return func(irisCtx iris.Context) {
+		writeErrorResponse(makeContext(irisCtx), e.Code, e.Message)
+	}
+}
+
+// 注册404, 405的处理程序
+func useErrorCodeHandlers(irisApp *iris.Application, app core.IApp, getConf func() *config.Config) {
+	irisApp.UseError(
+		middleware.BaseMiddlewareOfConfGetter(app, getConf),
+		middleware.LoggerMiddleware(app, getConf()),
+	)
+	irisApp.OnErrorCode(iris.StatusNotFound, errorCodeHandler(RouteNotFound))
+	irisApp.OnErrorCode(iris.StatusMethodNotAllowed, errorCodeHandler(MethodNotAllowed))
+}
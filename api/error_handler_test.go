@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kataras/iris/v12"
+	"github.com/zly-app/zapp/core"
+
+	"github.com/zly-app/service/api/config"
+)
+
+func TestWriteResponseFunc(t *testing.T) {
+	var (
+		codes []int
+		mx    sync.Mutex
+	)
+	old := defaultWriteResponseFunc
+	SetWriteResponseFunc(func(ctx *Context, code int, message string, data interface{}) {
+		mx.Lock()
+		codes = append(codes, code)
+		mx.Unlock()
+		_, _ = ctx.JSON(map[string]interface{}{"custom_code": code})
+	})
+	defer SetWriteResponseFunc(old)
+
+	a := newTestApiService(func(conf *config.Config) {
+		conf.ThreadCount = 1
+		conf.MaxReqWaitQueueSize = 1
+	})
+	block := make(chan struct{})
+	a.RegistryRouter(func(c core.IComponent, router Party) {
+		router.Get("/panic", Wrap(func(ctx *Context) interface{} {
+			panic("test panic")
+		}))
+		router.Post("/post", Wrap(func(ctx *Context) interface{} {
+			return nil
+		}))
+		router.Get("/block", Wrap(func(ctx *Context) interface{} {
+			<-block
+			return nil
+		}))
+	})
+	a.Configure(iris.WithFireMethodNotAllowed) // 和 Start 中的配置一致
+	if err := a.Build(); err != nil {
+		t.Fatal(err)
+	}
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		a.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		expectStatus int
+		expectCode   int
+	}{
+		{"panic", http.MethodGet, "/panic", http.StatusOK, ServiceInternalError.Code},
+		{"not_found", http.MethodGet, "/unknown", http.StatusNotFound, RouteNotFound.Code},
+		{"method_not_allowed", http.MethodGet, "/post", http.StatusMethodNotAllowed, MethodNotAllowed.Code},
+	}
+	for _, test := range tests {
+		mx.Lock()
+		codes = nil
+		mx.Unlock()
+
+		w := do(test.method, test.path)
+		var rsp struct {
+			Code int `json:"custom_code"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &rsp); err != nil {
+			t.Fatal(test.name, err, w.Body.String())
+		}
+		if w.Code != test.expectStatus || rsp.Code != test.expectCode {
+			t.Fatal("响应和预期不符", test.name, w.Code, w.Body.String())
+		}
+		if len(codes) != 1 {
+			t.Fatal("写入响应函数的调用次数和预期不符", test.name, codes)
+		}
+	}
+
+	// 协程池已满
+	mx.Lock()
+	codes = nil
+	mx.Unlock()
+	var wg sync.WaitGroup
+	busy := make(chan *httptest.ResponseRecorder, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if w := do(http.MethodGet, "/block"); w.Code == http.StatusServiceUnavailable {
+				busy <- w
+			}
+		}()
+	}
+	select {
+	case w := <-busy:
+		var rsp struct {
+			Code int `json:"custom_code"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &rsp); err != nil || rsp.Code != ServiceBusy.Code {
+			t.Fatal("协程池已满时的响应和预期不符", w.Body.String())
+		}
+	case <-time.After(time.Second * 3):
+		t.Fatal("协程池已满时应该返回503")
+	}
+	close(block)
+	wg.Wait()
+}
+
+func TestResponseFields(t *testing.T) {
+	a := newTestApiService(func(conf *config.Config) {
+		conf.ResponseErrCodeField = "code"
+		conf.ResponseErrMsgField = "msg"
+		conf.ResponseDataField = "result"
+	})
+	a.RegistryRouter(func(c core.IComponent, router Party) {
+		router.Get("/ok", Wrap(func(ctx *Context) interface{} {
+			return "v"
+		}))
+	})
+	if err := a.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path   string
+		expect map[string]interface{}
+	}{
+		{"/ok", map[string]interface{}{"code": float64(OK.Code), "msg": OK.Message, "result": "v"}},
+		{"/unknown", map[string]interface{}{"code": float64(RouteNotFound.Code), "msg": RouteNotFound.Message}},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		a.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+		var rsp map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &rsp); err != nil {
+			t.Fatal(err)
+		}
+		delete(rsp, "trace_id")
+		for k, v := range test.expect {
+			if rsp[k] != v {
+				t.Fatal("响应字段和预期不符", test.path, w.Body.String())
+			}
+		}
+		if _, ok := rsp["err_code"]; ok {
+			t.Fatal("不应该返回默认的字段名", test.path, w.Body.String())
+		}
+	}
+}
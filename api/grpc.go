@@ -0,0 +1,349 @@
+/*
+-------------------------------------------------
+   Author :       zlyuancn
+   date：         2026/10/19
+   Description :
+-------------------------------------------------
+*/
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/kataras/iris/v12"
+	"github.com/zly-app/zapp/core"
+	"github.com/zly-app/zapp/logger"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/zly-app/service/api/config"
+)
+
+// 双协议注入函数定义
+type RegisterDualRouterFunc = func(c core.IComponent, router *DualRouter)
+
+// grpc响应中携带api错误码的trailer
+const GrpcErrCodeTrailerKey = "err_code"
+
+var typeOfProtoMessage = reflect.TypeOf((*proto.Message)(nil)).Elem()
+
+// api错误码到grpc状态码的映射
+var (
+	errCodeToGrpcCode = map[int]codes.Code{
+		OK.Code:                    codes.OK,
+		ServiceInternalError.Code:  codes.Internal,
+		ParamError.Code:            codes.InvalidArgument,
+		AuthorizationRequired.Code: codes.Unauthenticated,
+		AuthorizationError.Code:    codes.PermissionDenied,
+		RequestBodyTooLarge.Code:   codes.ResourceExhausted,
+		ServiceBusy.Code:           codes.Unavailable,
+		CircuitBreakerOpen.Code:    codes.Unavailable,
+		RouteNotFound.Code:         codes.Unimplemented,
+		MethodNotAllowed.Code:      codes.Unimplemented,
+	}
+	errCodeToGrpcCodeMx sync.RWMutex
+)
+
+// 设置api错误码到grpc状态码的映射, 可以在运行时调用. 未设置映射的错误码会转为 codes.Unknown
+func SetGrpcCode(errCode int, code codes.Code) {
+	errCodeToGrpcCodeMx.Lock()
+	errCodeToGrpcCode[errCode] = code
+	errCodeToGrpcCodeMx.Unlock()
+}
+
+func toGrpcCode(errCode int) codes.Code {
+	errCodeToGrpcCodeMx.RLock()
+	code, ok := errCodeToGrpcCode[errCode]
+	errCodeToGrpcCodeMx.RUnlock()
+	if ok {
+		return code
+	}
+	return codes.Unknown
+}
+
+// 双协议路由, 通过 Handle 注册的handler会同时提供http和grpc服务
+type DualRouter struct {
+	Party
+	gateway *grpcGateway
+}
+
+// 注册同时提供http和grpc服务的handler, http和grpc请求都会经过相同的中间件(日志, panic恢复, 链路追踪, 认证等)
+//
+// httpMethod, path: http方法和路径, path不能包含路径参数
+// grpcMethod: grpc方法全名, 格式为 package.Service/Method
+// handler: 格式为 func(ctx *api.Context, req *pb.Req) (*pb.Rsp, error), req和rsp必须是 proto.Message
+// middlewares: 路由中间件
+func (d *DualRouter) Handle(httpMethod, path, grpcMethod string, handler interface{}, middlewares ...iris.Handler) {
+	reqType := checkDualHandler(handler)
+	route := d.Party.Handle(httpMethod, path, append(middlewares, Wrap(handler))...)
+	d.gateway.addMethod(grpcMethod, &grpcMethodInfo{
+		httpMethod: httpMethod,
+		httpPath:   route.Path,
+		reqType:    reqType,
+	})
+}
+
+// 检查双协议handler指纹, 返回req的类型
+func checkDualHandler(handler interface{}) reflect.Type {
+	hType := reflect.TypeOf(handler)
+	fingerprint := zap.String("fingerprint", fmt.Sprintf("%T", handler))
+	if hType == nil || hType.Kind() != reflect.Func || hType.NumIn() != 2 || hType.NumOut() != 2 {
+		logger.Log.Fatal("双协议handler的格式必须是 func(ctx *api.Context, req *pb.Req) (*pb.Rsp, error)", fingerprint)
+	}
+	if hType.In(1).Kind() != reflect.Ptr || !hType.In(1).Implements(typeOfProtoMessage) {
+		logger.Log.Fatal("双协议handler的第二个入参必须是 proto.Message", fingerprint)
+	}
+	if !hType.Out(0).Implements(typeOfProtoMessage) {
+		logger.Log.Fatal("双协议handler的第一个出参必须是 proto.Message", fingerprint)
+	}
+	return hType.In(1).Elem()
+}
+
+// grpc调用数据, 通过 http.Request 的context在grpc和http处理流程之间传递
+type grpcCall struct {
+	req       proto.Message
+	hasResult bool
+	errCode   int
+	errMsg    string
+	data      interface{}
+}
+
+type grpcCallKey struct{}
+
+// 获取grpc调用数据, 如果不是来自grpc的请求返回nil
+func getGrpcCall(ctx *Context) *grpcCall {
+	call, _ := ctx.Request().Context().Value(grpcCallKey{}).(*grpcCall)
+	return call
+}
+
+// 将grpc请求数据写入 a, a 必须是和请求数据相同类型的指针
+func (c *grpcCall) bindTo(a interface{}) error {
+	m, ok := a.(proto.Message)
+	if !ok || m.ProtoReflect().Descriptor() != c.req.ProtoReflect().Descriptor() {
+		return fmt.Errorf("grpc request type %T can not bind to %T", c.req, a)
+	}
+	proto.Merge(m, c.req)
+	return nil
+}
+
+// 记录处理结果
+func (c *grpcCall) setResult(code int, message string, data interface{}) {
+	c.hasResult = true
+	c.errCode = code
+	c.errMsg = message
+	c.data = data
+}
+
+type grpcMethodInfo struct {
+	httpMethod string
+	httpPath   string
+	reqType    reflect.Type
+}
+
+// grpc网关, 将grpc请求转为http请求交给iris处理
+type grpcGateway struct {
+	a        *ApiService
+	server   *grpc.Server
+	services map[string]*grpc.ServiceDesc
+	mx       sync.Mutex
+}
+
+func newGrpcGateway(a *ApiService) *grpcGateway {
+	return &grpcGateway{
+		a:        a,
+		server:   grpc.NewServer(),
+		services: make(map[string]*grpc.ServiceDesc),
+	}
+}
+
+// 添加grpc方法
+func (g *grpcGateway) addMethod(fullMethod string, info *grpcMethodInfo) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	k := strings.LastIndex(fullMethod, "/")
+	if k < 1 || k == len(fullMethod)-1 {
+		logger.Log.Fatal("grpc方法名格式必须是 package.Service/Method", zap.String("grpcMethod", fullMethod))
+	}
+	serviceName, methodName := fullMethod[:k], fullMethod[k+1:]
+
+	g.mx.Lock()
+	defer g.mx.Unlock()
+
+	desc, ok := g.services[serviceName]
+	if !ok {
+		desc = &grpc.ServiceDesc{
+			ServiceName: serviceName,
+			HandlerType: (*interface{})(nil),
+		}
+		g.services[serviceName] = desc
+	}
+	for _, m := range desc.Methods {
+		if m.MethodName == methodName {
+			logger.Log.Fatal("grpc方法重复注册", zap.String("grpcMethod", fullMethod))
+		}
+	}
+	desc.Methods = append(desc.Methods, grpc.MethodDesc{
+		MethodName: methodName,
+		Handler: func(_ interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+			req := reflect.New(info.reqType).Interface().(proto.Message)
+			if err := dec(req); err != nil {
+				return nil, status.Error(codes.InvalidArgument, err.Error())
+			}
+			return g.serve(ctx, info, req)
+		},
+	})
+}
+
+// 启动grpc服务
+func (g *grpcGateway) start() error {
+	conf := g.a.conf
+	if conf.GrpcBind == "" {
+		return nil
+	}
+
+	g.mx.Lock()
+	for _, desc := range g.services {
+		g.server.RegisterService(desc, struct{}{})
+	}
+	g.mx.Unlock()
+
+	if g.onSamePort() { // 和http共用端口时由http服务分发
+		g.a.WrapRouter(func(w http.ResponseWriter, r *http.Request, router http.HandlerFunc) {
+			if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+				g.server.ServeHTTP(w, r)
+				return
+			}
+			router(w, r)
+		})
+		return nil
+	}
+
+	g.a.app.Info("正在启动api服务的grpc服务", zap.String("bind", conf.GrpcBind))
+	lis, err := net.Listen("tcp", conf.GrpcBind)
+	if err != nil {
+		return fmt.Errorf("grpc服务监听失败: %v", err)
+	}
+	go func() {
+		if err := g.server.Serve(lis); err != nil {
+			g.a.app.Error("api服务的grpc服务运行失败", zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+// 是否和http共用端口
+func (g *grpcGateway) onSamePort() bool {
+	for _, c := range g.a.listenerConfigs() {
+		if g.onListener(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// 是否和指定的监听器共用端口
+func (g *grpcGateway) onListener(c config.ListenerConfig) bool {
+	return g.a.conf.GrpcBind != "" && c.Network == "tcp" && g.a.conf.GrpcBind == c.Addr
+}
+
+// 关闭grpc服务
+func (g *grpcGateway) close() {
+	if g.a.conf.GrpcBind != "" {
+		g.server.GracefulStop()
+	}
+}
+
+// 将grpc请求转为http请求交给iris处理
+func (g *grpcGateway) serve(ctx context.Context, info *grpcMethodInfo, req proto.Message) (interface{}, error) {
+	call := &grpcCall{req: req}
+	httpReq, err := http.NewRequest(info.httpMethod, info.httpPath, http.NoBody)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	httpReq = httpReq.WithContext(context.WithValue(ctx, grpcCallKey{}, call))
+
+	// metadata 转为 header, 用于链路追踪和认证等
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for k, vs := range md {
+			if strings.HasPrefix(k, ":") {
+				continue
+			}
+			for _, v := range vs {
+				httpReq.Header.Add(k, v)
+			}
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		httpReq.RemoteAddr = p.Addr.String()
+	}
+
+	w := newGrpcResponseWriter()
+	g.a.Router.ServeHTTP(w, httpReq)
+
+	code, message, data := call.errCode, call.errMsg, call.data
+	if !call.hasResult { // 没有经过 WriteToCtx 的响应, 例如panic
+		code, message = w.decodeErr()
+	}
+
+	_ = grpc.SetTrailer(ctx, metadata.Pairs(GrpcErrCodeTrailerKey, strconv.Itoa(code)))
+	if code != OK.Code {
+		return nil, status.Error(toGrpcCode(code), message)
+	}
+	rsp, ok := data.(proto.Message)
+	if !ok {
+		return nil, status.Errorf(codes.Internal, "result type %T is not proto.Message", data)
+	}
+	return rsp, nil
+}
+
+// 用于接收iris处理结果的ResponseWriter
+type grpcResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newGrpcResponseWriter() *grpcResponseWriter {
+	return &grpcResponseWriter{
+		header: make(http.Header),
+		status: http.StatusOK,
+	}
+}
+
+func (w *grpcResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *grpcResponseWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *grpcResponseWriter) WriteHeader(statusCode int) {
+	w.status = statusCode
+}
+
+// 从响应body中解析错误
+func (w *grpcResponseWriter) decodeErr() (int, string) {
+	var rsp Response
+	if err := jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(w.body.Bytes(), &rsp); err == nil && rsp.ErrCode != OK.Code {
+		return rsp.ErrCode, rsp.ErrMsg
+	}
+	if w.status == http.StatusNotFound || w.status == http.StatusMethodNotAllowed {
+		return ParamError.Code, http.StatusText(w.status)
+	}
+	return ServiceInternalError.Code, ServiceInternalError.Message
+}
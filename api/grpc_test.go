@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zly-app/zapp"
+	"github.com/zly-app/zapp/core"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/zly-app/service/api/config"
+)
+
+// 获取一个空闲的本地tcp地址
+func testFreeAddr(t *testing.T) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lis.Addr().String()
+	_ = lis.Close()
+	return addr
+}
+
+// 创建测试使用的api服务
+func newTestApiService(fn func(conf *config.Config)) *ApiService {
+	testAppOnce.Do(func() {
+		zapp.NewApp("test")
+	})
+	conf := config.NewConfig()
+	fn(conf)
+	conf.Check()
+	return NewApiService(zapp.App(), conf)
+}
+
+// 启动服务并在测试结束后关闭
+func startTestApiService(t *testing.T, a *ApiService) {
+	go func() {
+		_ = a.Start()
+	}()
+	t.Cleanup(func() {
+		a.grpcGateway.close()
+		_ = a.Shutdown(context.Background())
+	})
+}
+
+func TestToGrpcCode(t *testing.T) {
+	const customCode = 90001
+	SetGrpcCode(customCode, codes.FailedPrecondition)
+
+	tests := []struct {
+		errCode int
+		expect  codes.Code
+	}{
+		{OK.Code, codes.OK},
+		{ParamError.Code, codes.InvalidArgument},
+		{AuthorizationRequired.Code, codes.Unauthenticated},
+		{ServiceBusy.Code, codes.Unavailable},
+		{customCode, codes.FailedPrecondition},
+		{-12345, codes.Unknown},
+	}
+	for _, test := range tests {
+		if code := toGrpcCode(test.errCode); code != test.expect {
+			t.Fatal("grpc状态码和预期不符", test.errCode, code)
+		}
+	}
+}
+
+func TestGrpcGatewaySamePort(t *testing.T) {
+	addr := testFreeAddr(t)
+	a := newTestApiService(func(conf *config.Config) {
+		conf.Bind = addr
+		conf.GrpcBind = addr
+	})
+	a.RegistryDualRouter(func(c core.IComponent, router *DualRouter) {
+		router.Handle(http.MethodPost, "/echo", "test.Echo/Say", func(ctx *Context, req *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+			switch req.Value {
+			case "bad":
+				return nil, ParamError
+			case "panic":
+				panic("test panic")
+			}
+			return wrapperspb.String(req.Value + ":" + ctx.GetHeader("x-user") + ":" + ctx.Method() + ":" + ctx.Path()), nil
+		})
+	})
+	startTestApiService(t, a)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, addr, grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	tests := []struct {
+		name         string
+		value        string
+		expectCode   codes.Code
+		expectErrNo  int
+		expectResult string
+	}{
+		{"ok", "hi", codes.OK, OK.Code, "hi:u1:POST:/echo"},
+		{"api_error", "bad", codes.InvalidArgument, ParamError.Code, ""},
+		{"panic", "panic", codes.Internal, ServiceInternalError.Code, ""},
+	}
+	for _, test := range tests {
+		var trailer metadata.MD
+		rsp := new(wrapperspb.StringValue)
+		callCtx := metadata.AppendToOutgoingContext(ctx, "x-user", "u1")
+		err := conn.Invoke(callCtx, "/test.Echo/Say", wrapperspb.String(test.value), rsp, grpc.Trailer(&trailer))
+		if code := status.Code(err); code != test.expectCode {
+			t.Fatal("grpc状态码和预期不符", test.name, err)
+		}
+		if errNo := trailer.Get(GrpcErrCodeTrailerKey); len(errNo) != 1 || errNo[0] != strconv.Itoa(test.expectErrNo) {
+			t.Fatal("trailer中的错误码和预期不符", test.name, errNo)
+		}
+		if rsp.Value != test.expectResult {
+			t.Fatal("grpc响应和预期不符", test.name, rsp.Value)
+		}
+	}
+
+	// 未注册的方法
+	err = conn.Invoke(ctx, "/test.Echo/Unknown", wrapperspb.String(""), new(wrapperspb.StringValue))
+	if code := status.Code(err); code != codes.Unimplemented {
+		t.Fatal("未注册的grpc方法应该返回 Unimplemented", err)
+	}
+
+	// 同一个端口上的http请求
+	httpRsp, err := http.Post("http://"+addr+"/echo", "application/json", strings.NewReader(`{"value":"hi"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer httpRsp.Body.Close()
+	var rsp struct {
+		Code int `json:"err_code"`
+	}
+	if err := json.NewDecoder(httpRsp.Body).Decode(&rsp); err != nil {
+		t.Fatal(err)
+	}
+	if httpRsp.StatusCode != http.StatusOK || rsp.Code != OK.Code {
+		t.Fatal("同一个端口上的http请求失败", httpRsp.StatusCode, rsp.Code)
+	}
+}
@@ -11,12 +11,13 @@ import (
 )
 
 type handlerUtil struct {
-	handler interface{}
-	name    string
-	hType   reflect.Type
+	handler  interface{}
+	name     string
+	hType    reflect.Type
+	injector *Injector
 }
 
-func newHandler(handler interface{}) *handlerUtil {
+func newHandler(handler interface{}, injector *Injector) *handlerUtil {
 	if handler == nil {
 		logger.Log.Fatal("handler为nil", zap.String("handler", fmt.Sprintf("%T", handler)))
 	}
@@ -29,17 +30,18 @@ func newHandler(handler interface{}) *handlerUtil {
 	hName := utils.GetFuncName(handler)
 
 	return &handlerUtil{
-		handler: handler,
-		name:    hName,
-		hType:   hType,
+		handler:  handler,
+		name:     hName,
+		hType:    hType,
+		injector: injector,
 	}
 }
 
 // 检查 handler 指纹
 func (h *handlerUtil) checkHandlerFingerprint() {
 	// 检查入参
-	if h.hType.NumIn() < 1 || h.hType.NumIn() > 2 {
-		logger.Log.Fatal("handler的入参数量为1个或2个", zap.String("handlerName", h.name), zap.String("fingerprint", fmt.Sprintf("%T", h.handler)))
+	if h.hType.NumIn() < 1 {
+		logger.Log.Fatal("handler至少需要1个入参", zap.String("handlerName", h.name), zap.String("fingerprint", fmt.Sprintf("%T", h.handler)))
 	}
 
 	// 检查第一个参数
@@ -62,12 +64,45 @@ func (h *handlerUtil) checkHandlerFingerprint() {
 	}
 }
 
+// 获取req在入参中的位置, 没有req时返回-1
+//
+// 第二个入参如果不是注入的依赖就是req
+func (h *handlerUtil) reqIndex() int {
+	if h.hType.NumIn() < 2 || h.injector.has(h.hType.In(1)) {
+		return -1
+	}
+	return 1
+}
+
+// 解析 handler 需要注入的依赖, key为入参位置
+func (h *handlerUtil) mustResolveDeps() map[int]reflect.Value {
+	deps := make(map[int]reflect.Value)
+	reqIndex := h.reqIndex()
+	for i := 1; i < h.hType.NumIn(); i++ {
+		if i == reqIndex {
+			continue
+		}
+		v, err := h.injector.resolve(h.hType.In(i))
+		if err != nil {
+			logger.Log.Fatal("handler的依赖解析失败, 请通过 api.Provide 等注册依赖",
+				zap.String("handlerName", h.name),
+				zap.Int("argIndex", i),
+				zap.String("argType", h.hType.In(i).String()),
+				zap.String("fingerprint", fmt.Sprintf("%T", h.handler)),
+				zap.Error(err),
+			)
+		}
+		deps[i] = v
+	}
+	return deps
+}
+
 // 根据 handler 构建req建造者
 //
-// req 是 handler 的第二个入参, 如果入参数量小于 2 返回 nil
+// req 是 handler 的第二个入参, 如果没有req返回 nil
 // req 必须是 struct 或 *struct
 func (h *handlerUtil) mustMakeReqCreator() func(ctx *Context) (reflect.Value, error) {
-	if h.hType.NumIn() < 2 {
+	if h.reqIndex() < 0 {
 		return nil
 	}
 
@@ -80,6 +115,9 @@ func (h *handlerUtil) mustMakeReqCreator() func(ctx *Context) (reflect.Value, er
 	if arg1.Kind() != reflect.Struct {
 		logger.Log.Fatal("handler的第二个入参必须是 struct 或 *struct", zap.String("fingerprint", fmt.Sprintf("%T", h.handler)))
 	}
+	if _, err := getListRequestFields(arg1); err != nil { // 检查列表请求的 list tag
+		logger.Log.Fatal("handler的第二个入参的 list tag 错误", zap.String("fingerprint", fmt.Sprintf("%T", h.handler)), zap.Error(err))
+	}
 
 	// 返回建造者
 	return func(ctx *Context) (reflect.Value, error) {
@@ -99,20 +137,25 @@ func (h *handlerUtil) mustMakeReqCreator() func(ctx *Context) (reflect.Value, er
 func (h *handlerUtil) makeHandler() Handler {
 	hValue := reflect.ValueOf(h.handler)
 	reqCreator := h.mustMakeReqCreator()
+	reqIndex := h.reqIndex()
+	deps := h.mustResolveDeps()
 	return func(ctx *Context) interface{} {
-		var outValues []reflect.Value
-
-		// 调用handler
-		if reqCreator == nil { // 如果没有req建造者, 表示不需要req参数
-			outValues = hValue.Call([]reflect.Value{reflect.ValueOf(ctx)})
-		} else {
+		args := make([]reflect.Value, h.hType.NumIn())
+		args[0] = reflect.ValueOf(ctx)
+		for i, v := range deps {
+			args[i] = v
+		}
+		if reqCreator != nil {
 			reqValue, err := reqCreator(ctx)
 			if err != nil {
 				return err
 			}
-			outValues = hValue.Call([]reflect.Value{reflect.ValueOf(ctx), reqValue})
+			args[reqIndex] = reqValue
 		}
 
+		// 调用handler
+		outValues := hValue.Call(args)
+
 		// 检查结果
 		if len(outValues) == 1 { // 如果只有一个结果直接返回
 			return outValues[0].Interface()
@@ -0,0 +1,152 @@
+/*
+-------------------------------------------------
+   Author :       zlyuancn
+   date：         2026/10/19
+   Description :
+-------------------------------------------------
+*/
+
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/kataras/iris/v12"
+	"github.com/zly-app/zapp"
+	"github.com/zly-app/zapp/core"
+	"github.com/zly-app/zapp/logger"
+	"go.uber.org/zap"
+)
+
+// 依赖提供者
+type provider struct {
+	fn    reflect.Value // 提供函数, 格式为 func() T 或 func() (T, error)
+	once  sync.Once
+	value reflect.Value
+	err   error
+}
+
+// 获取依赖, 提供函数只会调用一次
+func (p *provider) get() (reflect.Value, error) {
+	p.once.Do(func() {
+		if !p.fn.IsValid() {
+			return
+		}
+		out := p.fn.Call(nil)
+		p.value = out[0]
+		if len(out) == 2 && !out[1].IsNil() {
+			p.err = out[1].Interface().(error)
+		}
+	})
+	return p.value, p.err
+}
+
+// 依赖注入器, 处理程序除了 ctx 和 req 外的入参会在包装时从注入器中解析
+//
+// 测试时可以创建新的注入器注入假的依赖, 然后通过注入器的 Wrap 包装处理程序
+type Injector struct {
+	providers map[reflect.Type]*provider
+	mx        sync.RWMutex
+}
+
+// 创建依赖注入器, 默认提供 core.IComponent
+func NewInjector() *Injector {
+	i := &Injector{providers: make(map[reflect.Type]*provider)}
+	i.ProvideFunc(func() core.IComponent { return zapp.App().GetComponent() })
+	return i
+}
+
+var defaultInjector = NewInjector()
+
+// 注册依赖, 以 v 的类型作为key, 重复注册时会替换之前的依赖
+func (i *Injector) Provide(v ...interface{}) {
+	for _, a := range v {
+		if a == nil {
+			logger.Log.Fatal("注册的依赖不能为nil")
+		}
+		i.set(reflect.TypeOf(a), &provider{value: reflect.ValueOf(a)})
+	}
+}
+
+// 以接口类型注册依赖, iface 必须是接口的指针, 例如 (*IUserRepo)(nil)
+func (i *Injector) ProvideAs(iface interface{}, v interface{}) {
+	t := reflect.TypeOf(iface)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Interface {
+		logger.Log.Fatal("ProvideAs的iface必须是接口的指针", zap.String("iface", fmt.Sprintf("%T", iface)))
+	}
+	t = t.Elem()
+	if v == nil || !reflect.TypeOf(v).Implements(t) {
+		logger.Log.Fatal("注册的依赖没有实现接口", zap.String("iface", t.String()), zap.String("type", fmt.Sprintf("%T", v)))
+	}
+	value := reflect.New(t).Elem()
+	value.Set(reflect.ValueOf(v))
+	i.set(t, &provider{value: value})
+}
+
+// 注册依赖提供函数, fn 的格式为 func() T 或 func() (T, error), 以 T 作为key
+//
+// 提供函数会在第一次包装需要这个依赖的处理程序时调用, 结果会被复用
+func (i *Injector) ProvideFunc(fn interface{}) {
+	fnType := reflect.TypeOf(fn)
+	if fnType == nil || fnType.Kind() != reflect.Func || fnType.NumIn() != 0 || fnType.NumOut() < 1 || fnType.NumOut() > 2 ||
+		(fnType.NumOut() == 2 && !fnType.Out(1).AssignableTo(typeOfError)) {
+		logger.Log.Fatal("依赖提供函数的格式必须是 func() T 或 func() (T, error)", zap.String("fingerprint", fmt.Sprintf("%T", fn)))
+	}
+	i.set(fnType.Out(0), &provider{fn: reflect.ValueOf(fn)})
+}
+
+func (i *Injector) set(t reflect.Type, p *provider) {
+	i.mx.Lock()
+	i.providers[t] = p
+	i.mx.Unlock()
+}
+
+// 是否有类型 t 的依赖
+func (i *Injector) has(t reflect.Type) bool {
+	i.mx.RLock()
+	_, ok := i.providers[t]
+	i.mx.RUnlock()
+	return ok
+}
+
+// 解析类型 t 的依赖
+func (i *Injector) resolve(t reflect.Type) (reflect.Value, error) {
+	i.mx.RLock()
+	p, ok := i.providers[t]
+	i.mx.RUnlock()
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("no provider for %s", t.String())
+	}
+	v, err := p.get()
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("provide %s failed: %v", t.String(), err)
+	}
+	return v, nil
+}
+
+// 包装处理程序, 和 api.Wrap 相同, 但是依赖从这个注入器中解析
+func (i *Injector) Wrap(handler interface{}) iris.Handler {
+	return wrapWithInjector(i, handler, false)
+}
+
+// 包装中间件, 和 api.WrapMiddleware 相同, 但是依赖从这个注入器中解析
+func (i *Injector) WrapMiddleware(handler interface{}) iris.Handler {
+	return wrapWithInjector(i, handler, true)
+}
+
+// 注册依赖, 以 v 的类型作为key, 这个函数应该在注册路由之前调用
+func Provide(v ...interface{}) {
+	defaultInjector.Provide(v...)
+}
+
+// 以接口类型注册依赖, iface 必须是接口的指针, 例如 (*IUserRepo)(nil), 这个函数应该在注册路由之前调用
+func ProvideAs(iface interface{}, v interface{}) {
+	defaultInjector.ProvideAs(iface, v)
+}
+
+// 注册依赖提供函数, fn 的格式为 func() T 或 func() (T, error), 这个函数应该在注册路由之前调用
+func ProvideFunc(fn interface{}) {
+	defaultInjector.ProvideFunc(fn)
+}
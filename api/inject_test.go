@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/kataras/iris/v12"
+)
+
+type testRepo interface {
+	Name() string
+}
+
+type testRepoImpl struct {
+	name string
+}
+
+func (r *testRepoImpl) Name() string { return r.name }
+
+type testDB struct {
+	dsn string
+}
+
+func TestInjectorResolve(t *testing.T) {
+	var calls int
+	injector := NewInjector()
+	injector.Provide(&testDB{dsn: "old"}, &testDB{dsn: "db"}) // 重复注册时替换
+	injector.ProvideAs((*testRepo)(nil), &testRepoImpl{name: "repo"})
+	injector.ProvideFunc(func() string {
+		calls++
+		return "lazy"
+	})
+	injector.ProvideFunc(func() (int, error) {
+		return 0, errors.New("provide err")
+	})
+
+	tests := []struct {
+		name   string
+		t      reflect.Type
+		expect interface{}
+		hasErr bool
+	}{
+		{"provide", reflect.TypeOf((*testDB)(nil)), &testDB{dsn: "db"}, false},
+		{"provide_as", reflect.TypeOf((*testRepo)(nil)).Elem(), testRepo(&testRepoImpl{name: "repo"}), false},
+		{"provide_func", reflect.TypeOf(""), "lazy", false},
+		{"provide_func_twice", reflect.TypeOf(""), "lazy", false},
+		{"provide_func_err", reflect.TypeOf(0), nil, true},
+		{"impl_not_registered", reflect.TypeOf((*testRepoImpl)(nil)), nil, true},
+		{"missing", reflect.TypeOf(0.0), nil, true},
+	}
+	for _, test := range tests {
+		v, err := injector.resolve(test.t)
+		if (err != nil) != test.hasErr {
+			t.Fatal("依赖解析结果和预期不符", test.name, err)
+		}
+		if err == nil && !reflect.DeepEqual(v.Interface(), test.expect) {
+			t.Fatal("解析的依赖和预期不符", test.name, v.Interface())
+		}
+	}
+	if calls != 1 {
+		t.Fatal("依赖提供函数应该只调用一次", calls)
+	}
+}
+
+func TestHandlerReqIndex(t *testing.T) {
+	type req struct{ A int }
+	injector := NewInjector()
+	injector.Provide(&testDB{})
+
+	tests := []struct {
+		name    string
+		handler interface{}
+		expect  int
+	}{
+		{"ctx", func(ctx *Context) error { return nil }, -1},
+		{"req", func(ctx *Context, req *req) error { return nil }, 1},
+		{"dep", func(ctx *Context, db *testDB) error { return nil }, -1},
+		{"req_and_dep", func(ctx *Context, req *req, db *testDB) error { return nil }, 1},
+	}
+	for _, test := range tests {
+		if i := newHandler(test.handler, injector).reqIndex(); i != test.expect {
+			t.Fatal("req的位置和预期不符", test.name, i)
+		}
+	}
+}
+
+func TestInjectorWrap(t *testing.T) {
+	injector := NewInjector()
+	injector.Provide(&testDB{dsn: "db"})
+	injector.ProvideAs((*testRepo)(nil), &testRepoImpl{name: "repo"})
+
+	app := iris.New()
+	app.Use(testBaseMiddleware())
+	app.Get("/", injector.Wrap(func(ctx *Context, db *testDB, repo testRepo) interface{} {
+		return db.dsn + ":" + repo.Name()
+	}))
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	var resp struct {
+		Data string `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data != "db:repo" {
+		t.Fatal("注入的依赖和预期不符", w.Body.String())
+	}
+}
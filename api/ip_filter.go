@@ -0,0 +1,144 @@
+/*
+-------------------------------------------------
+   Author :       zlyuancn
+   date：         2026/10/19
+   Description :
+-------------------------------------------------
+*/
+
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/kataras/iris/v12"
+	"github.com/zly-app/zapp"
+	"github.com/zly-app/zapp/core"
+	"go.uber.org/zap"
+
+	"github.com/zly-app/service/api/config"
+	"github.com/zly-app/service/api/utils"
+)
+
+// ip被过滤规则拒绝
+var IPForbidden = AuthorizationError.WithMessage("ip forbidden")
+
+// ip过滤配置
+type IPFilterConfig struct {
+	TrustedProxies []string                       `json:"TrustedProxies" yaml:"TrustedProxies"` // 信任的代理ip段
+	IPFilters      map[string]config.IPFilterRule `json:"IPFilters" yaml:"IPFilters"`           // ip过滤规则, key为规则名
+}
+
+// ip过滤中间件, 使用配置中 IPFilters 里名为 name 的规则, 规则不存在时不做任何限制
+//
+// 被拒绝时返回 IPForbidden, http状态码为403. 规则可以通过 ApiService.SetIPFilterConfig 或配置中心在运行时修改
+//
+// 没有配置 TrustedProxies 时使用连接的对端ip, 不会信任任何转发header, 防止客户端伪造header绕过过滤
+func IPFilter(name string) iris.Handler {
+	return WrapMiddleware(func(ctx *Context) error {
+		rule, ok := ctx.conf.IPFilters[name]
+		if !ok {
+			rule, ok = ctx.conf.IPFilters[strings.ToLower(name)] // 从配置文件加载时key会被转为小写
+		}
+		if !ok {
+			return nil
+		}
+
+		ip := ctx.RemoteAddr()
+		if len(ctx.conf.TrustedProxies) == 0 {
+			ip = utils.Context.GetPeerIP(ctx.IrisContext)
+		}
+		if ipAllowed(rule, net.ParseIP(ip)) {
+			return nil
+		}
+
+		ctx.Warn("ip被拒绝访问", zap.String("rule", name), zap.String("ip", ip))
+		ctx.StatusCode(http.StatusForbidden)
+		return IPForbidden.WithError(fmt.Errorf("ip %s forbidden by rule %s", ip, name))
+	})
+}
+
+// 检查ip是否允许访问
+func ipAllowed(rule config.IPFilterRule, ip net.IP) bool {
+	if utils.IP.CachedIPNets(rule.Deny).Contains(ip) {
+		return false
+	}
+	if len(rule.Allow) == 0 {
+		return true
+	}
+	return utils.IP.CachedIPNets(rule.Allow).Contains(ip)
+}
+
+// 检查ip过滤配置
+func checkIPFilterConfig(c *IPFilterConfig) error {
+	if _, err := utils.IP.ParseIPNets(c.TrustedProxies); err != nil {
+		return fmt.Errorf("TrustedProxies: %v", err)
+	}
+	for name, rule := range c.IPFilters {
+		if _, err := utils.IP.ParseIPNets(rule.Allow); err != nil {
+			return fmt.Errorf("IPFilters.%s.Allow: %v", name, err)
+		}
+		if _, err := utils.IP.ParseIPNets(rule.Deny); err != nil {
+			return fmt.Errorf("IPFilters.%s.Deny: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// 获取当前的ip过滤配置
+func (a *ApiService) IPFilterConfig() IPFilterConfig {
+	conf := a.Config()
+	return IPFilterConfig{
+		TrustedProxies: conf.TrustedProxies,
+		IPFilters:      conf.IPFilters,
+	}
+}
+
+// 在运行时替换ip过滤配置, 对之后的请求生效
+func (a *ApiService) SetIPFilterConfig(c IPFilterConfig) error {
+	if err := checkIPFilterConfig(&c); err != nil {
+		return err
+	}
+	a.UpdateConfig(func(conf *config.Config) {
+		conf.TrustedProxies = c.TrustedProxies
+		conf.IPFilters = c.IPFilters
+	})
+	return nil
+}
+
+// 初始化ip过滤
+func (a *ApiService) initIPFilter() {
+	err := checkIPFilterConfig(&IPFilterConfig{
+		TrustedProxies: a.conf.TrustedProxies,
+		IPFilters:      a.conf.IPFilters,
+	})
+	if err != nil {
+		a.app.Fatal("api服务的ip过滤配置错误", zap.Error(err))
+	}
+	if len(a.conf.IPFilters) > 0 && len(a.conf.TrustedProxies) == 0 {
+		a.app.Warn("api服务配置了ip过滤但没有配置 TrustedProxies, ip过滤将使用连接的对端ip, 不会使用转发header")
+	}
+
+	if a.conf.IPFilterWatchGroup == "" || a.conf.IPFilterWatchKey == "" {
+		return
+	}
+	// 配置观察需要在app初始化完成后才能使用
+	zapp.AddHandler(zapp.AfterInitializeHandler, func(app core.IApp, handlerType zapp.HandlerType) {
+		w := app.GetConfig().WatchKey(a.conf.IPFilterWatchGroup, a.conf.IPFilterWatchKey)
+		w.AddCallback(func(isInit bool, oldData, newData []byte) {
+			var c IPFilterConfig
+			if err := w.ParseYaml(&c); err != nil {
+				app.Error("解析api服务的ip过滤配置失败", zap.String("data", string(newData)), zap.Error(err))
+				return
+			}
+			if err := a.SetIPFilterConfig(c); err != nil {
+				app.Error("api服务的ip过滤配置错误", zap.String("data", string(newData)), zap.Error(err))
+				return
+			}
+			app.Info("api服务的ip过滤配置已更新", zap.Bool("isInit", isInit), zap.Any("config", c))
+		})
+	})
+}
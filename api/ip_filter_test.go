@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kataras/iris/v12"
+
+	"github.com/zly-app/service/api/config"
+	"github.com/zly-app/service/api/utils"
+)
+
+func TestIPFilterForwardedHeader(t *testing.T) {
+	tests := []struct {
+		name       string
+		trusted    []string
+		expectCode int
+	}{
+		{"untrusted_use_peer", nil, IPForbidden.Code},
+		{"trusted_proxy_use_header", []string{"1.1.1.1"}, OK.Code},
+	}
+	for _, test := range tests {
+		conf := config.NewConfig()
+		conf.Check()
+		conf.TrustedProxies = test.trusted
+		conf.IPFilters = map[string]config.IPFilterRule{"test": {Deny: []string{"1.1.1.1"}}}
+
+		app := iris.New()
+		app.Configure(iris.WithRemoteAddrHeader("X-Forwarded-For"))
+		app.Use(testBaseMiddleware(), func(irisCtx iris.Context) {
+			utils.Context.SaveConfToIrisContext(irisCtx, conf)
+			irisCtx.Next()
+		})
+		app.Get("/", IPFilter("test"), Wrap(func(ctx *Context) interface{} {
+			return nil
+		}))
+		if err := app.Build(); err != nil {
+			t.Fatal(err)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "1.1.1.1:1234"
+		req.Header.Set("X-Forwarded-For", "3.3.3.3")
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		var resp struct {
+			Code int `json:"err_code"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Code != test.expectCode {
+			t.Fatal("ip过滤结果和预期不符", test.name, w.Body.String())
+		}
+	}
+}
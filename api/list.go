@@ -0,0 +1,355 @@
+/*
+-------------------------------------------------
+   Author :       zlyuancn
+   date：         2026/10/19
+   Description :
+-------------------------------------------------
+*/
+
+package api
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/zly-app/service/api/config"
+)
+
+// 过滤操作符
+const (
+	FilterEq   = "eq"   // 等于
+	FilterNe   = "ne"   // 不等于
+	FilterGt   = "gt"   // 大于
+	FilterGte  = "gte"  // 大于等于
+	FilterLt   = "lt"   // 小于
+	FilterLte  = "lte"  // 小于等于
+	FilterLike = "like" // 模糊匹配
+	FilterIn   = "in"   // 在列表中, 多个值用 | 分隔
+)
+
+var filterOps = map[string]struct{}{
+	FilterEq: {}, FilterNe: {}, FilterGt: {}, FilterGte: {}, FilterLt: {}, FilterLte: {}, FilterLike: {}, FilterIn: {},
+}
+
+// 排序字段
+type SortField struct {
+	Field string
+	Desc  bool // 是否降序
+}
+
+// 过滤条件
+type Filter struct {
+	Field  string
+	Op     string   // 操作符, 参考 FilterEq 等
+	Value  string   // 值
+	Values []string // 操作符为 FilterIn 时的值列表
+}
+
+// 列表请求选项
+type ListOptions struct {
+	DefaultPageSize int      // 默认每页数量
+	MaxPageSize     int      // 最大每页数量
+	SortFields      []string // 允许排序的字段, 为空时不允许排序
+	FilterFields    []string // 允许过滤的字段, 为空时不允许过滤
+	DefaultSort     string   // 默认排序, 格式和 ListRequest.Sort 相同
+}
+
+// 列表请求, 可以嵌入到请求结构体中
+//
+// 在请求结构体中嵌入时通过 list tag 设置选项, 多个选项用英文逗号分隔, 多个值用 | 分隔. 示例:
+//
+//	type ListUserReq struct {
+//		api.ListRequest `list:"max_page_size=50,sort=created_at|name,filter=status|name,default_sort=-created_at"`
+//	}
+//
+// bind时会校验和解析列表参数, 未设置的选项使用配置中的默认值
+type ListRequest struct {
+	Page     int      `json:"page" form:"page" url:"page"`                // 页码, 从1开始, 使用游标分页时忽略
+	PageSize int      `json:"page_size" form:"page_size" url:"page_size"` // 每页数量
+	Cursor   string   `json:"cursor" form:"cursor" url:"cursor"`          // 游标, 设置后使用游标分页
+	Sort     string   `json:"sort" form:"sort" url:"sort"`                // 排序, 多个字段用英文逗号分隔, 字段前加-表示降序, 例如 -created_at,name
+	Filter   []string `json:"filter" form:"filter" url:"filter"`          // 过滤条件, 格式为 字段:操作符:值, 省略操作符时为 eq, 例如 status:1, age:gte:18, id:in:1|2|3
+
+	sorts   []SortField
+	filters []Filter
+}
+
+// 按选项校验和解析列表参数
+func (r *ListRequest) Parse(opts ListOptions) error {
+	if r.Page < 0 || r.PageSize < 0 {
+		return fmt.Errorf("page and page_size must not be negative")
+	}
+	if r.Page == 0 {
+		r.Page = 1
+	}
+	if r.PageSize == 0 {
+		r.PageSize = opts.DefaultPageSize
+		if opts.MaxPageSize > 0 && r.PageSize > opts.MaxPageSize {
+			r.PageSize = opts.MaxPageSize
+		}
+	}
+	if opts.MaxPageSize > 0 && r.PageSize > opts.MaxPageSize {
+		return fmt.Errorf("page_size must not be greater than %d", opts.MaxPageSize)
+	}
+
+	sort := r.Sort
+	if sort == "" {
+		sort = opts.DefaultSort
+	}
+	sorts, err := parseSort(sort, opts.SortFields)
+	if err != nil {
+		return err
+	}
+	r.sorts = sorts
+
+	filters, err := parseFilters(r.Filter, opts.FilterFields)
+	if err != nil {
+		return err
+	}
+	r.filters = filters
+	return nil
+}
+
+// 是否使用游标分页
+func (r *ListRequest) IsCursor() bool {
+	return r.Cursor != ""
+}
+
+// 偏移分页的偏移量
+func (r *ListRequest) Offset() int {
+	if r.Page <= 1 {
+		return 0
+	}
+	return (r.Page - 1) * r.PageSize
+}
+
+// 每页数量
+func (r *ListRequest) Limit() int {
+	return r.PageSize
+}
+
+// 解析后的排序字段
+func (r *ListRequest) Sorts() []SortField {
+	return r.sorts
+}
+
+// 解析后的过滤条件
+func (r *ListRequest) Filters() []Filter {
+	return r.filters
+}
+
+// 解码游标到 v, 游标应该是由 EncodeCursor 生成的
+func (r *ListRequest) DecodeCursor(v interface{}) error {
+	bs, err := base64.RawURLEncoding.DecodeString(r.Cursor)
+	if err != nil {
+		return ParamError.WithError(fmt.Errorf("invalid cursor: %v", err))
+	}
+	if err = jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(bs, v); err != nil {
+		return ParamError.WithError(fmt.Errorf("invalid cursor: %v", err))
+	}
+	return nil
+}
+
+// 将 v 编码为游标
+func EncodeCursor(v interface{}) (string, error) {
+	bs, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bs), nil
+}
+
+// 解析排序
+func parseSort(sort string, allowed []string) ([]SortField, error) {
+	if sort == "" {
+		return nil, nil
+	}
+	var sorts []SortField
+	for _, s := range strings.Split(sort, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		f := SortField{Field: s}
+		if strings.HasPrefix(s, "-") {
+			f = SortField{Field: s[1:], Desc: true}
+		} else if strings.HasPrefix(s, "+") {
+			f.Field = s[1:]
+		}
+		if !containsString(allowed, f.Field) {
+			return nil, fmt.Errorf("sort field %q is not allowed", f.Field)
+		}
+		sorts = append(sorts, f)
+	}
+	return sorts, nil
+}
+
+// 解析过滤条件
+func parseFilters(exprs []string, allowed []string) ([]Filter, error) {
+	var filters []Filter
+	for _, expr := range exprs {
+		if expr == "" {
+			continue
+		}
+		parts := strings.SplitN(expr, ":", 3)
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("invalid filter %q, it must be field:op:value or field:value", expr)
+		}
+		f := Filter{Field: parts[0], Op: FilterEq, Value: parts[1]}
+		if len(parts) == 3 {
+			f.Op, f.Value = strings.ToLower(parts[1]), parts[2]
+		}
+		if _, ok := filterOps[f.Op]; !ok {
+			return nil, fmt.Errorf("filter op %q is not supported", f.Op)
+		}
+		if !containsString(allowed, f.Field) {
+			return nil, fmt.Errorf("filter field %q is not allowed", f.Field)
+		}
+		if f.Op == FilterIn {
+			f.Values = strings.Split(f.Value, "|")
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// 列表结果
+type ListResult[T any] struct {
+	Total      int64  // 总数
+	NextCursor string // 下一页的游标, 为空表示没有更多数据或使用偏移分页
+	Items      []T    // 数据
+}
+
+// 创建列表结果
+func NewListResult[T any](items []T, total int64) *ListResult[T] {
+	return &ListResult[T]{Total: total, Items: items}
+}
+
+// 设置下一页的游标
+func (r *ListResult[T]) WithNextCursor(cursor string) *ListResult[T] {
+	r.NextCursor = cursor
+	return r
+}
+
+// 列表结果的序列化结构, 没有数据时 items 为空数组而不是null
+type listResultJSON struct {
+	Total      int64       `json:"total"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	Items      interface{} `json:"items"`
+}
+
+func (r ListResult[T]) MarshalJSON() ([]byte, error) {
+	items := r.Items
+	if items == nil {
+		items = []T{}
+	}
+	return jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(listResultJSON{
+		Total:      r.Total,
+		NextCursor: r.NextCursor,
+		Items:      items,
+	})
+}
+
+var typeOfListRequest = reflect.TypeOf(ListRequest{})
+
+// 结构体中的列表请求字段
+type listRequestField struct {
+	index []int
+	opts  ListOptions
+}
+
+// 结构体类型 -> []*listRequestField
+var listRequestFields sync.Map
+
+// 获取结构体中类型为 ListRequest 的字段
+func getListRequestFields(t reflect.Type) ([]*listRequestField, error) {
+	if v, ok := listRequestFields.Load(t); ok {
+		return v.([]*listRequestField), nil
+	}
+
+	var fields []*listRequestField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Type != typeOfListRequest {
+			continue
+		}
+		opts, err := parseListTag(sf.Tag.Get("list"))
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", t.String(), sf.Name, err)
+		}
+		fields = append(fields, &listRequestField{index: sf.Index, opts: opts})
+	}
+	listRequestFields.Store(t, fields)
+	return fields, nil
+}
+
+// 解析 list tag
+func parseListTag(tag string) (ListOptions, error) {
+	var opts ListOptions
+	for _, item := range strings.Split(tag, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			return opts, fmt.Errorf("invalid list tag item %q", item)
+		}
+		var err error
+		switch kv[0] {
+		case "page_size":
+			opts.DefaultPageSize, err = strconv.Atoi(kv[1])
+		case "max_page_size":
+			opts.MaxPageSize, err = strconv.Atoi(kv[1])
+		case "sort":
+			opts.SortFields = strings.Split(kv[1], "|")
+		case "filter":
+			opts.FilterFields = strings.Split(kv[1], "|")
+		case "default_sort":
+			opts.DefaultSort = strings.ReplaceAll(kv[1], "|", ",")
+		default:
+			return opts, fmt.Errorf("unknown list tag option %q", kv[0])
+		}
+		if err != nil {
+			return opts, fmt.Errorf("invalid list tag item %q: %v", item, err)
+		}
+	}
+	return opts, nil
+}
+
+// 校验和解析结构体中的列表请求字段
+func bindListRequest(conf *config.Config, val reflect.Value) error {
+	fields, err := getListRequestFields(val.Type())
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		opts := f.opts
+		if opts.DefaultPageSize == 0 {
+			opts.DefaultPageSize = conf.ListDefaultPageSize
+		}
+		if opts.MaxPageSize == 0 {
+			opts.MaxPageSize = conf.ListMaxPageSize
+		}
+		r := val.FieldByIndex(f.index).Addr().Interface().(*ListRequest)
+		if err = r.Parse(opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
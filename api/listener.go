@@ -0,0 +1,249 @@
+/*
+-------------------------------------------------
+   Author :       zlyuancn
+   date：         2026/10/19
+   Description :
+-------------------------------------------------
+*/
+
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/kataras/iris/v12"
+	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"github.com/zly-app/service/api/config"
+	"github.com/zly-app/service/api/utils"
+)
+
+const (
+	// 默认路由组, 通过 RegistryRouter 和 RegistryDualRouter 注册的路由属于这个组
+	DefaultRouteGroup = "default"
+	// admin路由组, 挂载在api服务下的admin接口属于这个组
+	AdminRouteGroup = "admin"
+)
+
+// unix socket连接的对端ip
+const unixSocketRemoteAddr = "127.0.0.1:0"
+
+type listenerKey struct{}
+
+// 监听器
+type listener struct {
+	conf   config.ListenerConfig
+	groups map[string]struct{} // 暴露的路由组, 为nil表示暴露所有路由组
+	lis    net.Listener
+	server *http.Server
+}
+
+// 检查路由组是否暴露在这个监听器上
+func (l *listener) exposes(group string) bool {
+	if l.groups == nil {
+		return true
+	}
+	_, ok := l.groups[group]
+	return ok
+}
+
+// 获取生效的监听器配置, 未设置 Listeners 时使用 Bind
+func (a *ApiService) listenerConfigs() []config.ListenerConfig {
+	if len(a.conf.Listeners) > 0 {
+		return a.conf.Listeners
+	}
+	return []config.ListenerConfig{{Name: "default", Network: "tcp", Addr: a.conf.Bind}}
+}
+
+// 检查监听器配置
+func checkListenerConfigs(confs []config.ListenerConfig) error {
+	names := make(map[string]struct{}, len(confs))
+	for _, c := range confs {
+		if _, ok := names[c.Name]; ok {
+			return fmt.Errorf("listener name %q is duplicated", c.Name)
+		}
+		names[c.Name] = struct{}{}
+
+		if c.Network != "tcp" && c.Network != "unix" {
+			return fmt.Errorf("listener %q: unsupported network %q", c.Name, c.Network)
+		}
+		if c.Addr == "" {
+			return fmt.Errorf("listener %q: addr is empty", c.Name)
+		}
+		if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+			return fmt.Errorf("listener %q: TLSCertFile and TLSKeyFile must be set together", c.Name)
+		}
+	}
+	return nil
+}
+
+// 创建所有监听器, 任何一个监听失败时关闭已创建的监听器
+func (a *ApiService) listen() ([]*listener, error) {
+	confs := a.listenerConfigs()
+	if err := checkListenerConfigs(confs); err != nil {
+		return nil, err
+	}
+
+	listeners := make([]*listener, 0, len(confs))
+	for _, c := range confs {
+		l, err := a.newListener(c)
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.lis.Close()
+			}
+			return nil, fmt.Errorf("listener %q: %v", c.Name, err)
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+func (a *ApiService) newListener(c config.ListenerConfig) (*listener, error) {
+	l := &listener{conf: c}
+	if len(c.Groups) > 0 {
+		l.groups = make(map[string]struct{}, len(c.Groups))
+		for _, g := range c.Groups {
+			l.groups[g] = struct{}{}
+		}
+	}
+
+	if c.Network == "unix" {
+		_ = os.Remove(c.Addr) // 移除上次未清理的socket文件
+	}
+	lis, err := net.Listen(c.Network, c.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var handler http.Handler = a.Router
+	if c.H2C || a.grpcGateway.onListener(c) { // 和grpc共用端口时需要h2c
+		handler = h2c.NewHandler(handler, &http2.Server{})
+	}
+	if c.Network == "unix" { // unix socket没有对端ip, 视为本机连接
+		next := handler
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.RemoteAddr == "" || r.RemoteAddr == "@" {
+				r.RemoteAddr = unixSocketRemoteAddr
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	l.server = &http.Server{
+		Handler: handler,
+		BaseContext: func(net.Listener) context.Context {
+			return context.WithValue(context.Background(), listenerKey{}, l)
+		},
+	}
+	if c.Network == "tcp" {
+		l.server.Addr = lis.Addr().String()
+	}
+
+	if c.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.TLSCertFile, c.TLSKeyFile)
+		if err != nil {
+			_ = lis.Close()
+			return nil, err
+		}
+		l.server.TLSConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			NextProtos:   []string{"h2", "http/1.1"},
+		}
+		lis = tls.NewListener(lis, l.server.TLSConfig)
+	}
+	l.lis = lis
+	return l, nil
+}
+
+// 在所有监听器上提供服务, 所有监听器共享同一个iris应用, 关闭时会一起优雅的关闭
+//
+// 任何一个监听器出现错误时返回这个错误, 正常关闭时返回nil
+func serveListeners(listeners []*listener) iris.Runner {
+	return func(app *iris.Application) error {
+		errChan := make(chan error, len(listeners))
+		for _, l := range listeners {
+			host := app.NewHost(l.server)
+			go func(l *listener) {
+				errChan <- host.Serve(l.lis)
+			}(l)
+		}
+		for range listeners {
+			if err := <-errChan; err != nil && err != http.ErrServerClosed {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// 获取请求所在的监听器, 不是来自监听器的请求返回nil
+func getListener(ctx *Context) *listener {
+	l, _ := ctx.Request().Context().Value(listenerKey{}).(*listener)
+	return l
+}
+
+// 所有路由组中间件的实例
+var routeGroupHandlers sync.Map
+
+// 路由组中间件, 路由组没有暴露在请求所在的监听器上时和路由不存在一样返回 RouteNotFound
+func routeGroupMiddleware(group string) iris.Handler {
+	notFound := errorCodeHandler(RouteNotFound)
+	h := func(irisCtx iris.Context) {
+		l, _ := irisCtx.Request().Context().Value(listenerKey{}).(*listener)
+		if l == nil || l.exposes(group) {
+			irisCtx.Next()
+			return
+		}
+		irisCtx.StatusCode(http.StatusNotFound)
+		notFound(irisCtx)
+		irisCtx.StopExecution()
+	}
+	routeGroupHandlers.Store(utils.GetFuncInstanceID(h), struct{}{})
+	return h
+}
+
+// 默认路由组中间件, 作为全局中间件使用
+//
+// 没有使用路由组中间件的路由, 例如直接在iris应用上注册的路由, 视为属于默认路由组, 避免它们暴露在所有监听器上
+func defaultRouteGroupMiddleware() iris.Handler {
+	defaultGroup := routeGroupMiddleware(DefaultRouteGroup)
+	return func(irisCtx iris.Context) {
+		if hasRouteGroup(irisCtx) {
+			irisCtx.Next()
+			return
+		}
+		defaultGroup(irisCtx)
+	}
+}
+
+// 调用链中剩余的处理程序是否包含路由组中间件
+func hasRouteGroup(irisCtx iris.Context) bool {
+	handlers := irisCtx.Handlers()
+	for i := irisCtx.HandlerIndex(-1) + 1; i < len(handlers); i++ {
+		if _, ok := routeGroupHandlers.Load(utils.GetFuncInstanceID(handlers[i])); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// 输出监听器信息
+func (a *ApiService) logListeners(listeners []*listener) {
+	for _, l := range listeners {
+		a.app.Info("api服务监听器",
+			zap.String("name", l.conf.Name),
+			zap.String("network", l.conf.Network),
+			zap.String("addr", l.conf.Addr),
+			zap.Bool("tls", l.conf.TLSCertFile != ""),
+			zap.Strings("groups", l.conf.Groups),
+		)
+	}
+}
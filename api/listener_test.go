@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zly-app/zapp/core"
+	"golang.org/x/net/http2"
+
+	"github.com/zly-app/service/api/config"
+)
+
+func TestListeners(t *testing.T) {
+	publicAddr, internalAddr := testFreeAddr(t), testFreeAddr(t)
+	sockFile := filepath.Join(t.TempDir(), "api.sock")
+	a := newTestApiService(func(conf *config.Config) {
+		conf.Listeners = []config.ListenerConfig{
+			{Name: "public", Network: "tcp", Addr: publicAddr, Groups: []string{DefaultRouteGroup}},
+			{Name: "internal", Network: "tcp", Addr: internalAddr, H2C: true, Groups: []string{"internal"}},
+			{Name: "sidecar", Network: "unix", Addr: sockFile},
+		}
+	})
+	handler := Wrap(func(ctx *Context) interface{} {
+		return ctx.ListenerName() + "," + ctx.RemoteAddr() + "," + ctx.Request().Proto
+	})
+	a.RegistryRouter(func(c core.IComponent, router Party) {
+		router.Get("/public", handler)
+	})
+	a.RegistryRouterGroup("internal", func(c core.IComponent, router Party) {
+		router.Get("/internal", handler)
+	})
+	a.Get("/direct", handler) // 直接在iris应用上注册的路由
+	startTestApiService(t, a)
+
+	tcpClient := &http.Client{Timeout: time.Second}
+	h2cClient := &http.Client{Timeout: time.Second, Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+	unixClient := &http.Client{Timeout: time.Second, Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", sockFile)
+		},
+	}}
+
+	// 等待服务启动
+	for i := 0; ; i++ {
+		rsp, err := unixClient.Get("http://unix/public")
+		if err == nil {
+			rsp.Body.Close()
+			break
+		}
+		if i == 50 {
+			t.Fatal("服务没有启动", err)
+		}
+		time.Sleep(time.Millisecond * 20)
+	}
+
+	tests := []struct {
+		name       string
+		client     *http.Client
+		url        string
+		expectCode int
+		expectData string
+	}{
+		{"public_default_group", tcpClient, "http://" + publicAddr + "/public", OK.Code, "public,127.0.0.1,HTTP/1.1"},
+		{"public_hide_internal_group", tcpClient, "http://" + publicAddr + "/internal", RouteNotFound.Code, ""},
+		{"public_direct_route", tcpClient, "http://" + publicAddr + "/direct", OK.Code, "public,127.0.0.1,HTTP/1.1"},
+		{"internal_h2c", h2cClient, "http://" + internalAddr + "/internal", OK.Code, "internal,127.0.0.1,HTTP/2.0"},
+		{"internal_hide_default_group", h2cClient, "http://" + internalAddr + "/public", RouteNotFound.Code, ""},
+		{"internal_hide_direct_route", h2cClient, "http://" + internalAddr + "/direct", RouteNotFound.Code, ""},
+		{"unix_all_groups", unixClient, "http://unix/internal", OK.Code, "sidecar,127.0.0.1,HTTP/1.1"},
+		{"unix_direct_route", unixClient, "http://unix/direct", OK.Code, "sidecar,127.0.0.1,HTTP/1.1"},
+	}
+	for _, test := range tests {
+		httpRsp, err := test.client.Get(test.url)
+		if err != nil {
+			t.Fatal(test.name, err)
+		}
+		var rsp struct {
+			Code int    `json:"err_code"`
+			Data string `json:"data"`
+		}
+		err = json.NewDecoder(httpRsp.Body).Decode(&rsp)
+		httpRsp.Body.Close()
+		if err != nil {
+			t.Fatal(test.name, err)
+		}
+		if rsp.Code != test.expectCode || rsp.Data != test.expectData {
+			t.Fatal("响应和预期不符", test.name, rsp.Code, rsp.Data)
+		}
+	}
+}
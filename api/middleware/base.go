@@ -2,10 +2,16 @@ package middleware
 
 import (
 	"context"
+	"strings"
 
 	"github.com/kataras/iris/v12"
 	iris_context "github.com/kataras/iris/v12/context"
 	"github.com/zly-app/zapp/core"
+	"github.com/zly-app/zapp/logger"
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/contrib/propagators/jaeger"
+	"go.opentelemetry.io/otel/propagation"
+	"go.uber.org/zap"
 
 	zapp_utils "github.com/zly-app/zapp/pkg/utils"
 
@@ -15,14 +21,30 @@ import (
 
 // 用于构建相关log, trace等基础数据
 func BaseMiddleware(app core.IApp, conf *config.Config) iris.Handler {
+	return BaseMiddlewareOfConfGetter(app, func() *config.Config { return conf })
+}
+
+// 用于构建相关log, trace等基础数据
+//
+// getConf 用于获取当前生效的配置, 每个请求开始时获取一次并保存在iris上下文中
+func BaseMiddlewareOfConfGetter(app core.IApp, getConf func() *config.Config) iris.Handler {
+	propagator := makeTracePropagator(getConf().TracePropagators)
 	return func(irisCtx *iris_context.Context) {
+		conf := getConf()
 		name := irisCtx.Method() + ": " + irisCtx.Path()
-		// 链路追踪
-		span := zapp_utils.Trace.GetChildSpan(context.Background(), name)
-		defer span.Finish()
-		ctx := zapp_utils.Trace.SaveSpan(context.Background(), span)
+		// 链路追踪, 从请求header中提取上游的链路数据
+		ctx := propagator.Extract(context.Background(), propagation.HeaderCarrier(irisCtx.Request().Header))
+		ctx, span := zapp_utils.Otel.StartSpan(ctx, name)
+		defer zapp_utils.Otel.EndSpan(span)
 		utils.Context.SaveContextToIrisContext(irisCtx, ctx)
 
+		// 在响应中返回traceID
+		if conf.TraceIDHeader != "-" {
+			if traceID := utils.Context.GetTraceID(ctx); traceID != "" {
+				irisCtx.Header(conf.TraceIDHeader, traceID)
+			}
+		}
+
 		// conf
 		utils.Context.SaveConfToIrisContext(irisCtx, conf)
 
@@ -34,3 +56,26 @@ func BaseMiddleware(app core.IApp, conf *config.Config) iris.Handler {
 		irisCtx.Next()
 	}
 }
+
+// 根据配置构建链路透传器, 多个格式用英文逗号分隔
+func makeTracePropagator(propagators string) propagation.TextMapPropagator {
+	var ps []propagation.TextMapPropagator
+	for _, name := range strings.Split(propagators, ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "":
+		case "tracecontext":
+			ps = append(ps, propagation.TraceContext{})
+		case "baggage":
+			ps = append(ps, propagation.Baggage{})
+		case "b3":
+			ps = append(ps, b3.New(b3.WithInjectEncoding(b3.B3SingleHeader)))
+		case "b3multi":
+			ps = append(ps, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		case "jaeger":
+			ps = append(ps, jaeger.Jaeger{})
+		default:
+			logger.Log.Fatal("不支持的链路透传格式", zap.String("propagator", name))
+		}
+	}
+	return propagation.NewCompositeTextMapPropagator(ps...)
+}
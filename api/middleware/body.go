@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// 有上限的缓冲区, 超出上限的数据会被丢弃, 但是依然会记录总长度
+type limitedBuffer struct {
+	buf     bytes.Buffer
+	maxSize int64
+	total   int64
+}
+
+func (l *limitedBuffer) Write(p []byte) (int, error) {
+	l.total += int64(len(p))
+	if remain := l.maxSize - int64(l.buf.Len()); remain > 0 {
+		if int64(len(p)) > remain {
+			l.buf.Write(p[:remain])
+		} else {
+			l.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// 请求body捕获器
+//
+// 它通过 tee reader 在handler读取body时最多保留前 maxSize 字节用于日志输出, 不会强制将整个body读入内存
+type bodyCapture struct {
+	io.Reader
+	io.Closer
+	lb  *limitedBuffer
+	eof bool
+}
+
+func newBodyCapture(body io.ReadCloser, maxSize int64) *bodyCapture {
+	lb := &limitedBuffer{maxSize: maxSize}
+	return &bodyCapture{
+		Reader: io.TeeReader(body, lb),
+		Closer: body,
+		lb:     lb,
+	}
+}
+
+func (b *bodyCapture) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	if err == io.EOF {
+		b.eof = true
+	}
+	return n, err
+}
+
+// 如果handler没有读完body, 补充读取body直到达到 maxSize 或者读完, 不会读取超过 maxSize 的数据
+func (b *bodyCapture) fill() {
+	remain := b.lb.maxSize - int64(b.lb.buf.Len())
+	if b.eof || remain <= 0 {
+		return
+	}
+	_, _ = io.CopyN(ioutil.Discard, b, remain)
+}
+
+// 获取用于日志输出的body文本, contentLength 为请求header中的body长度
+func (b *bodyCapture) Text(contentLength int64) string {
+	b.fill()
+
+	size := b.lb.total
+	if contentLength > size {
+		size = contentLength
+	}
+	if size > int64(b.lb.buf.Len()) { // 超长
+		return fmt.Sprintf("<len=%d>{%s...}", size, b.lb.buf.String())
+	}
+	return b.lb.buf.String()
+}
+
+// 获取捕获的body, 如果body超过 maxSize 返回的数据会被截断, truncated 为 true
+func (b *bodyCapture) Bytes(contentLength int64) (body []byte, truncated bool) {
+	b.fill()
+
+	size := b.lb.total
+	if contentLength > size {
+		size = contentLength
+	}
+	return b.lb.buf.Bytes(), size > int64(b.lb.buf.Len())
+}
@@ -0,0 +1,216 @@
+package middleware
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kataras/iris/v12"
+	iris_context "github.com/kataras/iris/v12/context"
+
+	zapp_utils "github.com/zly-app/zapp/pkg/utils"
+
+	"github.com/zly-app/service/api/cache"
+	"github.com/zly-app/service/api/utils"
+)
+
+// 默认响应缓存存储
+var DefaultCacheStore = cache.NewLRUCache(cache.DefaultLRUMaxEntries)
+
+// 不能缓存的响应header, 包括逐跳header
+var uncachedHeaders = []string{
+	"Set-Cookie",
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+type cacheOptions struct {
+	Store   cache.ICache // 缓存存储
+	Headers []string     // 参与构建缓存key的header
+}
+
+type CacheOption func(o *cacheOptions)
+
+func newCacheOptions(opts ...CacheOption) *cacheOptions {
+	o := &cacheOptions{
+		Store: DefaultCacheStore,
+	}
+	for _, fn := range opts {
+		fn(o)
+	}
+	return o
+}
+
+// 设置缓存存储, 默认为 DefaultCacheStore
+func WithCacheStore(store cache.ICache) CacheOption {
+	return func(o *cacheOptions) {
+		o.Store = store
+	}
+}
+
+// 设置参与构建缓存key的header, 例如根据 Accept-Language 缓存不同的结果
+func WithCacheHeaders(headers ...string) CacheOption {
+	return func(o *cacheOptions) {
+		o.Headers = append(o.Headers, headers...)
+	}
+}
+
+// 响应缓存中间件, 只会缓存GET请求中状态码为200且没有错误的响应
+//
+// 设置了cookie或 Cache-Control 为 private, no-store 的响应不会被缓存, 因为它们可能是用户私有的数据.
+// 缓存时会删除逐跳header.
+//
+// 缓存key由path, 排序后的query以及指定的header构建.
+// 响应会带上 ETag 和 Cache-Control, 客户端带上 If-None-Match 且匹配时返回304.
+// 客户端带上 Cache-Control: no-cache 时会跳过缓存重新执行handler.
+func CacheMiddleware(ttl time.Duration, opts ...CacheOption) iris.Handler {
+	o := newCacheOptions(opts...)
+	return func(irisCtx *iris_context.Context) {
+		if irisCtx.Method() != http.MethodGet || ttl <= 0 {
+			irisCtx.Next()
+			return
+		}
+
+		ctx := utils.Context.MustGetContextFromIrisContext(irisCtx)
+		span := zapp_utils.Otel.GetSpan(ctx)
+		key := makeCacheKey(irisCtx, o.Headers)
+
+		if !strings.Contains(irisCtx.GetHeader("Cache-Control"), "no-cache") {
+			if item, ok := o.Store.Get(key); ok {
+				zapp_utils.Otel.SetSpanAttributes(span, zapp_utils.OtelSpanKey("cache").String("hit"))
+				writeCacheItem(irisCtx, item)
+				irisCtx.StopExecution()
+				return
+			}
+		}
+		zapp_utils.Otel.SetSpanAttributes(span, zapp_utils.OtelSpanKey("cache").String("miss"))
+
+		// handler
+		irisCtx.Record()
+		irisCtx.Next()
+
+		if irisCtx.Values().Get("error") != nil || irisCtx.GetStatusCode() != http.StatusOK {
+			return
+		}
+
+		recorder := irisCtx.Recorder()
+		if !isCacheableHeader(recorder.Header()) {
+			return
+		}
+		body := append([]byte(nil), recorder.Body()...)
+		header := recorder.Header().Clone()
+		conf := utils.Context.MustGetConfFromIrisContext(irisCtx)
+		header.Del(conf.TraceIDHeader) // 每个请求的traceID不同, 不能缓存
+		for _, h := range uncachedHeaders {
+			header.Del(h)
+		}
+		item := &cache.Item{
+			StatusCode: http.StatusOK,
+			Header:     header,
+			Body:       body,
+			ETag:       makeETag(body),
+			ExpireAt:   time.Now().Add(ttl),
+		}
+		o.Store.Set(key, item)
+
+		setCacheHeaders(irisCtx, item)
+		if etagMatch(irisCtx.GetHeader("If-None-Match"), item.ETag) {
+			recorder.ResetBody()
+			irisCtx.WriteNotModified()
+		}
+	}
+}
+
+// 检查响应是否可以被共享缓存
+func isCacheableHeader(header http.Header) bool {
+	if header.Get("Set-Cookie") != "" {
+		return false
+	}
+	for _, v := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(v, ",") {
+			switch strings.ToLower(strings.TrimSpace(directive)) {
+			case "private", "no-store":
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// 使指定path的所有缓存失效
+func InvalidateCache(store cache.ICache, path string) {
+	store.DelPrefix(path + "?")
+}
+
+// 构建缓存key
+func makeCacheKey(irisCtx *iris_context.Context, headers []string) string {
+	var sb strings.Builder
+	sb.WriteString(irisCtx.Path())
+	sb.WriteByte('?')
+	sb.WriteString(strings.Join(valuesToTexts(irisCtx.Request().URL.Query(), "="), "&"))
+	for _, h := range headers {
+		sb.WriteByte('\n')
+		sb.WriteString(h)
+		sb.WriteString(": ")
+		sb.WriteString(irisCtx.GetHeader(h))
+	}
+	return sb.String()
+}
+
+// 将缓存写入响应
+func writeCacheItem(irisCtx *iris_context.Context, item *cache.Item) {
+	h := irisCtx.ResponseWriter().Header()
+	for k, vs := range item.Header {
+		if _, ok := h[k]; !ok {
+			h[k] = vs
+		}
+	}
+	setCacheHeaders(irisCtx, item)
+
+	if etagMatch(irisCtx.GetHeader("If-None-Match"), item.ETag) {
+		irisCtx.WriteNotModified()
+		return
+	}
+
+	irisCtx.Values().Set("result", item.Body)
+	irisCtx.StatusCode(item.StatusCode)
+	_, _ = irisCtx.Write(item.Body)
+}
+
+// 设置 ETag 和 Cache-Control
+func setCacheHeaders(irisCtx *iris_context.Context, item *cache.Item) {
+	maxAge := int64(time.Until(item.ExpireAt) / time.Second)
+	if maxAge < 0 {
+		maxAge = 0
+	}
+	irisCtx.Header(iris_context.ETagHeaderKey, item.ETag)
+	irisCtx.Header("Cache-Control", "max-age="+strconv.FormatInt(maxAge, 10))
+}
+
+func makeETag(body []byte) string {
+	sum := sha1.Sum(body)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// 检查 If-None-Match 是否匹配 etag
+func etagMatch(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, v := range strings.Split(ifNoneMatch, ",") {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+		if v == "*" || v == etag {
+			return true
+		}
+	}
+	return false
+}
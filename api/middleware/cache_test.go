@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kataras/iris/v12"
+
+	"github.com/zly-app/service/api/cache"
+	"github.com/zly-app/service/api/config"
+	"github.com/zly-app/service/api/utils"
+)
+
+func TestCacheMiddlewareSkipPrivate(t *testing.T) {
+	conf := config.NewConfig()
+	conf.Check()
+
+	tests := []struct {
+		name        string
+		header      http.Header
+		expectCache bool
+	}{
+		{"public", http.Header{"X-Data": {"v"}}, true},
+		{"set_cookie", http.Header{"Set-Cookie": {"session=u1"}}, false},
+		{"private", http.Header{"Cache-Control": {"Private, max-age=60"}}, false},
+		{"no_store", http.Header{"Cache-Control": {"no-store"}}, false},
+		{"hop_by_hop", http.Header{"Connection": {"close"}, "X-Data": {"v"}}, true},
+	}
+	for _, test := range tests {
+		store := cache.NewLRUCache(10)
+		app := iris.New()
+		app.Use(func(irisCtx iris.Context) {
+			utils.Context.SaveConfToIrisContext(irisCtx, conf)
+			utils.Context.SaveContextToIrisContext(irisCtx, context.Background())
+			irisCtx.Next()
+		})
+		app.Get("/", CacheMiddleware(time.Minute, WithCacheStore(store)), func(irisCtx iris.Context) {
+			for k, vs := range test.header {
+				for _, v := range vs {
+					irisCtx.ResponseWriter().Header().Add(k, v)
+				}
+			}
+			_, _ = irisCtx.WriteString("ok")
+		})
+		if err := app.Build(); err != nil {
+			t.Fatal(err)
+		}
+
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		item, ok := store.Get("/?")
+		if ok != test.expectCache {
+			t.Fatal("是否缓存和预期不符", test.name, ok)
+		}
+		if ok && (item.Header.Get("Connection") != "" || item.Header.Get("X-Data") != "v") {
+			t.Fatal("缓存的header和预期不符", test.name, item.Header)
+		}
+	}
+}
@@ -18,7 +18,6 @@ import (
 	jsoniter "github.com/json-iterator/go"
 	"github.com/kataras/iris/v12"
 	iris_context "github.com/kataras/iris/v12/context"
-	open_log "github.com/opentracing/opentracing-go/log"
 	app_config "github.com/zly-app/zapp/config"
 	app_utils "github.com/zly-app/zapp/pkg/utils"
 	"go.uber.org/zap"
@@ -41,6 +40,9 @@ func valuesToTexts(values map[string][]string, sep string) []string {
 	return texts
 }
 
+// 日志中间件
+//
+// 优先使用 BaseMiddleware 保存在iris上下文中的配置, 没有时使用 conf
 func LoggerMiddleware(app core.IApp, conf *config.Config) iris.Handler {
 	if app_config.Conf.Config().Frame.Log.Json {
 		return loggerMiddlewareWithJson(app, conf)
@@ -48,26 +50,35 @@ func LoggerMiddleware(app core.IApp, conf *config.Config) iris.Handler {
 	return loggerMiddleware(app, conf)
 }
 
+// 获取当前请求生效的配置
+func getRequestConf(irisCtx iris.Context, def *config.Config) *config.Config {
+	if conf, ok := irisCtx.Values().Get(utils.ConfContextFieldKey).(*config.Config); ok {
+		return conf
+	}
+	return def
+}
+
 // 以文本方式输出
-func loggerMiddleware(app core.IApp, conf *config.Config) iris.Handler {
+func loggerMiddleware(app core.IApp, defConf *config.Config) iris.Handler {
 	isDebug := app_config.Conf.Config().Frame.Debug
 	return func(irisCtx iris.Context) {
 		startTime := time.Now()
+		conf := getRequestConf(irisCtx, defConf)
 
 		// log
 		log := utils.Context.MustGetLoggerFromIrisContext(irisCtx)
 
 		// 链路追踪
 		ctx := utils.Context.MustGetContextFromIrisContext(irisCtx)
-		span := zapp_utils.Trace.GetSpan(ctx)
+		span := zapp_utils.Otel.GetSpan(ctx)
 
 		// request
 		ip := utils.Context.GetRemoteIP(irisCtx)
 		params := valuesToTexts(irisCtx.Request().URL.Query(), "=")
-		span.SetTag("method", irisCtx.Method())
-		span.SetTag("path", irisCtx.Path())
-		span.LogFields(open_log.String("params", strings.Join(params, "\n")))
-		span.LogFields(open_log.String("ip", irisCtx.RemoteAddr()))
+		zapp_utils.Otel.SetSpanAttributes(span, zapp_utils.OtelSpanKey("method").String(irisCtx.Method()))
+		zapp_utils.Otel.SetSpanAttributes(span, zapp_utils.OtelSpanKey("path").String(irisCtx.Path()))
+		zapp_utils.Otel.AddSpanEvent(span, "params", zapp_utils.OtelSpanKey("params").String(strings.Join(params, "\n")))
+		zapp_utils.Otel.AddSpanEvent(span, "ip", zapp_utils.OtelSpanKey("ip").String(irisCtx.RemoteAddr()))
 		var msgBuff bytes.Buffer
 		msgBuff.WriteString("api.request path: ")
 		msgBuff.WriteString(irisCtx.Method())
@@ -86,6 +97,10 @@ func loggerMiddleware(app core.IApp, conf *config.Config) iris.Handler {
 			log.Debug(msgBuff.String(), zap.String("ip", ip))
 		}
 
+		// 捕获body用于日志输出
+		body := newBodyCapture(irisCtx.Request().Body, conf.LogBodyMaxSize)
+		irisCtx.Request().Body = body
+
 		// handler
 		irisCtx.Next()
 
@@ -122,7 +137,7 @@ func loggerMiddleware(app core.IApp, conf *config.Config) iris.Handler {
 		// headers
 		if hasErr || conf.AlwaysLogHeaders {
 			headers := valuesToTexts(irisCtx.Request().Header, ": ")
-			span.LogFields(open_log.String("headers", strings.Join(headers, "\n")))
+			zapp_utils.Otel.AddSpanEvent(span, "headers", zapp_utils.OtelSpanKey("headers").String(strings.Join(headers, "\n")))
 			msgBuff.WriteString("headers:\n")
 			for _, s := range headers {
 				msgBuff.WriteString("  ")
@@ -138,13 +153,9 @@ func loggerMiddleware(app core.IApp, conf *config.Config) iris.Handler {
 			if irisCtx.GetContentTypeRequested() == iris_context.ContentBinaryHeaderValue { // 流
 				bodyText = fmt.Sprintf("<bytesLen=%d>", irisCtx.GetContentLength())
 			} else {
-				body, _ := irisCtx.GetBody()
-				bodyText = string(body)
-				if len(bodyText) > int(conf.LogBodyMaxSize) { // 超长
-					bodyText = fmt.Sprintf("<len=%d>{%s...}", irisCtx.GetContentLength(), bodyText[:conf.LogBodyMaxSize])
-				}
+				bodyText = body.Text(irisCtx.GetContentLength())
 			}
-			span.LogFields(open_log.String("body", bodyText))
+			zapp_utils.Otel.AddSpanEvent(span, "body", zapp_utils.OtelSpanKey("body").String(bodyText))
 			msgBuff.WriteString("body:")
 			msgBuff.WriteString(bodyText)
 			msgBuff.WriteString("\n\n")
@@ -171,7 +182,7 @@ func loggerMiddleware(app core.IApp, conf *config.Config) iris.Handler {
 					result = fmt.Sprintf("<len=%d>{%s...}", irisCtx.ResponseWriter().Written(), result[:conf.LogApiResultMaxSize])
 				}
 			}
-			span.LogFields(open_log.String("result", result))
+			zapp_utils.Otel.AddSpanEvent(span, "result", zapp_utils.OtelSpanKey("result").String(result))
 			if (isDebug && conf.LogApiResultInDevelop) || (!isDebug && conf.LogApiResultInProd) {
 				msgBuff.WriteString("result: ")
 				msgBuff.WriteString(result)
@@ -187,8 +198,8 @@ func loggerMiddleware(app core.IApp, conf *config.Config) iris.Handler {
 
 		// error
 		if !hasPanic {
-			span.SetTag("error", true)
-			span.LogFields(open_log.Error(err))
+			zapp_utils.Otel.MarkSpanAnError(span, true)
+			zapp_utils.Otel.AddSpanEvent(span, "err", zapp_utils.OtelSpanKey("err").String(err.Error()))
 			msgBuff.WriteString("err: ")
 			msgBuff.WriteString(err.Error())
 			msgBuff.WriteString("\n\n")
@@ -200,11 +211,11 @@ func loggerMiddleware(app core.IApp, conf *config.Config) iris.Handler {
 		// panic
 		panicErrDetail := app_utils.Recover.GetRecoverErrorDetail(err)
 		panicErrInfos := strings.Split(panicErrDetail, "\n")
-		span.SetTag("error", true)
-		span.SetTag("panic", true)
-		span.SetTag("handler_name", handlerName)
-		span.LogFields(open_log.String("err", err.Error()))
-		span.LogFields(open_log.String("detail", panicErrDetail))
+		zapp_utils.Otel.MarkSpanAnError(span, true)
+		zapp_utils.Otel.SetSpanAttributes(span, zapp_utils.OtelSpanKey("panic").Bool(true))
+		zapp_utils.Otel.SetSpanAttributes(span, zapp_utils.OtelSpanKey("handler_name").String(handlerName))
+		zapp_utils.Otel.AddSpanEvent(span, "err", zapp_utils.OtelSpanKey("err").String(err.Error()))
+		zapp_utils.Otel.AddSpanEvent(span, "detail", zapp_utils.OtelSpanKey("detail").String(panicErrDetail))
 
 		msgBuff.WriteString("panic:\n")
 		msgBuff.WriteString("  Recovered from a route's Handler: ")
@@ -213,43 +224,30 @@ func loggerMiddleware(app core.IApp, conf *config.Config) iris.Handler {
 		msgBuff.WriteString(strings.Join(panicErrInfos, "\n  "))
 		msgBuff.WriteString("\n\n")
 		log.Error(append([]interface{}{msgBuff.String()}, fields...)...)
-
-		// send_error_result
-		result := map[string]interface{}{
-			"err_code": 1,
-			"err_msg":  "service internal error",
-		}
-		if isDebug || conf.SendDetailedErrorInProduction {
-			result["err_msg"] = append(
-				[]string{fmt.Sprintf("Recovered from a route's Handler: %s", handlerName)},
-				panicErrInfos...,
-			)
-		}
-		_, _ = irisCtx.JSON(result)
-		irisCtx.StopExecution()
 	}
 }
 
 // 以json方式输出
-func loggerMiddlewareWithJson(app core.IApp, conf *config.Config) iris.Handler {
+func loggerMiddlewareWithJson(app core.IApp, defConf *config.Config) iris.Handler {
 	isDebug := app_config.Conf.Config().Frame.Debug
 	return func(irisCtx *iris_context.Context) {
 		startTime := time.Now()
+		conf := getRequestConf(irisCtx, defConf)
 
 		// log
 		log := utils.Context.MustGetLoggerFromIrisContext(irisCtx)
 
 		// 链路追踪
 		ctx := utils.Context.MustGetContextFromIrisContext(irisCtx)
-		span := zapp_utils.Trace.GetSpan(ctx)
+		span := zapp_utils.Otel.GetSpan(ctx)
 
 		// request
 		ip := utils.Context.GetRemoteIP(irisCtx)
 		params := valuesToTexts(irisCtx.Request().URL.Query(), "=")
-		span.SetTag("method", irisCtx.Method())
-		span.SetTag("path", irisCtx.Path())
-		span.LogFields(open_log.String("params", strings.Join(params, "\n")))
-		span.LogFields(open_log.String("ip", irisCtx.RemoteAddr()))
+		zapp_utils.Otel.SetSpanAttributes(span, zapp_utils.OtelSpanKey("method").String(irisCtx.Method()))
+		zapp_utils.Otel.SetSpanAttributes(span, zapp_utils.OtelSpanKey("path").String(irisCtx.Path()))
+		zapp_utils.Otel.AddSpanEvent(span, "params", zapp_utils.OtelSpanKey("params").String(strings.Join(params, "\n")))
+		zapp_utils.Otel.AddSpanEvent(span, "ip", zapp_utils.OtelSpanKey("ip").String(irisCtx.RemoteAddr()))
 
 		fields := []interface{}{
 			"api.request",
@@ -264,6 +262,10 @@ func loggerMiddlewareWithJson(app core.IApp, conf *config.Config) iris.Handler {
 			log.Debug(fields...)
 		}
 
+		// 捕获body用于日志输出
+		body := newBodyCapture(irisCtx.Request().Body, conf.LogBodyMaxSize)
+		irisCtx.Request().Body = body
+
 		// handler
 		irisCtx.Next()
 
@@ -291,7 +293,7 @@ func loggerMiddlewareWithJson(app core.IApp, conf *config.Config) iris.Handler {
 		// headers
 		if hasErr || conf.AlwaysLogHeaders {
 			headers := valuesToTexts(irisCtx.Request().Header, ": ")
-			span.LogFields(open_log.String("headers", strings.Join(headers, "\n")))
+			zapp_utils.Otel.AddSpanEvent(span, "headers", zapp_utils.OtelSpanKey("headers").String(strings.Join(headers, "\n")))
 			fields = append(fields, zap.Strings("headers", headers))
 		}
 
@@ -301,13 +303,9 @@ func loggerMiddlewareWithJson(app core.IApp, conf *config.Config) iris.Handler {
 			if irisCtx.GetContentTypeRequested() == iris_context.ContentBinaryHeaderValue { // 流
 				bodyText = fmt.Sprintf("<bytesLen=%d>", irisCtx.GetContentLength())
 			} else {
-				body, _ := irisCtx.GetBody()
-				bodyText = string(body)
-				if len(bodyText) > int(conf.LogBodyMaxSize) { // 超长
-					bodyText = fmt.Sprintf("<len=%d>{%s...}", irisCtx.GetContentLength(), bodyText[:conf.LogBodyMaxSize])
-				}
+				bodyText = body.Text(irisCtx.GetContentLength())
 			}
-			span.LogFields(open_log.String("body", bodyText))
+			zapp_utils.Otel.AddSpanEvent(span, "body", zapp_utils.OtelSpanKey("body").String(bodyText))
 			fields = append(fields, zap.String("body", bodyText))
 		}
 
@@ -332,7 +330,7 @@ func loggerMiddlewareWithJson(app core.IApp, conf *config.Config) iris.Handler {
 					result = fmt.Sprintf("<len=%d>{%s...}", irisCtx.ResponseWriter().Written(), result[:conf.LogApiResultMaxSize])
 				}
 			}
-			span.LogFields(open_log.String("result", result))
+			zapp_utils.Otel.AddSpanEvent(span, "result", zapp_utils.OtelSpanKey("result").String(result))
 			if (isDebug && conf.LogApiResultInDevelop) || (!isDebug && conf.LogApiResultInProd) {
 				fields = append(fields, zap.String("result", result))
 			}
@@ -346,8 +344,8 @@ func loggerMiddlewareWithJson(app core.IApp, conf *config.Config) iris.Handler {
 
 		// error
 		if !hasPanic {
-			span.SetTag("error", true)
-			span.LogFields(open_log.String("err", err.Error()))
+			zapp_utils.Otel.MarkSpanAnError(span, true)
+			zapp_utils.Otel.AddSpanEvent(span, "err", zapp_utils.OtelSpanKey("err").String(err.Error()))
 			fields = append(fields, zap.String("err", err.Error()))
 			log.Error(fields...)
 			return
@@ -357,11 +355,11 @@ func loggerMiddlewareWithJson(app core.IApp, conf *config.Config) iris.Handler {
 		// panic
 		panicErrDetail := app_utils.Recover.GetRecoverErrorDetail(err)
 		panicErrInfos := strings.Split(panicErrDetail, "\n")
-		span.SetTag("error", true)
-		span.SetTag("panic", true)
-		span.SetTag("handler_name", handlerName)
-		span.LogFields(open_log.String("err", err.Error()))
-		span.LogFields(open_log.String("detail", panicErrDetail))
+		zapp_utils.Otel.MarkSpanAnError(span, true)
+		zapp_utils.Otel.SetSpanAttributes(span, zapp_utils.OtelSpanKey("panic").Bool(true))
+		zapp_utils.Otel.SetSpanAttributes(span, zapp_utils.OtelSpanKey("handler_name").String(handlerName))
+		zapp_utils.Otel.AddSpanEvent(span, "err", zapp_utils.OtelSpanKey("err").String(err.Error()))
+		zapp_utils.Otel.AddSpanEvent(span, "detail", zapp_utils.OtelSpanKey("detail").String(panicErrDetail))
 
 		fields = append(fields,
 			zap.Bool("panic", true),
@@ -370,19 +368,5 @@ func loggerMiddlewareWithJson(app core.IApp, conf *config.Config) iris.Handler {
 			zap.Strings("detail", panicErrInfos),
 		)
 		log.Error(fields...)
-
-		// send_error_result
-		result := map[string]interface{}{
-			"err_code": 1,
-			"err_msg":  "service internal error",
-		}
-		if isDebug || conf.SendDetailedErrorInProduction {
-			result["err_msg"] = append(
-				[]string{fmt.Sprintf("Recovered from a route's Handler: %s", handlerName)},
-				panicErrInfos...,
-			)
-		}
-		_, _ = irisCtx.JSON(result)
-		irisCtx.StopExecution()
 	}
 }
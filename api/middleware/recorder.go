@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/kataras/iris/v12"
+	iris_context "github.com/kataras/iris/v12/context"
+	"go.uber.org/zap"
+
+	"github.com/zly-app/service/api/recorder"
+	"github.com/zly-app/service/api/utils"
+)
+
+// 默认记录请求body的最大大小
+const defaultRecordMaxBodySize = 1 << 20
+
+// 默认需要隐藏的header
+var DefaultScrubHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Admin-Token", "X-Api-Key"}
+
+type recorderOptions struct {
+	SampleRate   float64  // 采样率
+	MaxBodySize  int64    // 记录请求body的最大大小
+	ScrubHeaders []string // 需要隐藏的header
+}
+
+type RecorderOption func(o *recorderOptions)
+
+func newRecorderOptions(opts ...RecorderOption) *recorderOptions {
+	o := &recorderOptions{
+		SampleRate:   1,
+		MaxBodySize:  defaultRecordMaxBodySize,
+		ScrubHeaders: DefaultScrubHeaders,
+	}
+	for _, fn := range opts {
+		fn(o)
+	}
+	return o
+}
+
+// 设置采样率, 取值0~1, 默认为1
+func WithRecordSampleRate(rate float64) RecorderOption {
+	return func(o *recorderOptions) {
+		o.SampleRate = rate
+	}
+}
+
+// 设置记录请求body的最大大小, 超出时body会被截断且这条记录无法重放, 默认1M
+func WithRecordMaxBodySize(size int64) RecorderOption {
+	return func(o *recorderOptions) {
+		if size > 0 {
+			o.MaxBodySize = size
+		}
+	}
+}
+
+// 添加需要隐藏的header, 默认会隐藏 DefaultScrubHeaders
+func WithRecordScrubHeaders(headers ...string) RecorderOption {
+	return func(o *recorderOptions) {
+		o.ScrubHeaders = append(append([]string(nil), o.ScrubHeaders...), headers...)
+	}
+}
+
+// 请求记录中间件, 将采样的请求和响应写入 w, 用于之后通过 recorder.Replay 重放并比较结果
+func RecorderMiddleware(w recorder.IWriter, opts ...RecorderOption) iris.Handler {
+	o := newRecorderOptions(opts...)
+	return func(irisCtx *iris_context.Context) {
+		if o.SampleRate < 1 && rand.Float64() >= o.SampleRate {
+			irisCtx.Next()
+			return
+		}
+
+		record := &recorder.Record{
+			Time:   time.Now(),
+			Method: irisCtx.Method(),
+			Path:   irisCtx.Request().URL.EscapedPath(), // 保留转义, 重放时原样使用
+			Query:  irisCtx.Request().URL.RawQuery,
+			Header: scrubHeader(irisCtx.Request().Header, o.ScrubHeaders),
+		}
+		if route := irisCtx.GetCurrentRoute(); route != nil {
+			record.Route = route.Path()
+		}
+		body := newBodyCapture(irisCtx.Request().Body, o.MaxBodySize)
+		irisCtx.Request().Body = body
+
+		// handler
+		irisCtx.Record()
+		irisCtx.Next()
+
+		record.Body, record.BodyTruncated = body.Bytes(irisCtx.GetContentLength())
+		record.StatusCode = irisCtx.GetStatusCode()
+		rsp := irisCtx.Recorder().Body()
+		if json.Valid(rsp) {
+			record.Response = append(json.RawMessage(nil), rsp...)
+		} else {
+			record.RawResponse = append([]byte(nil), rsp...)
+		}
+
+		if err := w.Write(record); err != nil {
+			log := utils.Context.MustGetLoggerFromIrisContext(irisCtx)
+			log.Error("写入请求记录失败", zap.Error(err))
+		}
+	}
+}
+
+// 复制header并隐藏敏感字段
+func scrubHeader(header http.Header, scrubHeaders []string) http.Header {
+	h := header.Clone()
+	for _, k := range scrubHeaders {
+		if _, ok := h[http.CanonicalHeaderKey(k)]; ok {
+			h.Set(k, recorder.ScrubbedValue)
+		}
+	}
+	return h
+}
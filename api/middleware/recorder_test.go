@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kataras/iris/v12"
+
+	"github.com/zly-app/service/api/recorder"
+	"github.com/zly-app/service/api/utils"
+)
+
+type testRecordWriter struct {
+	records []*recorder.Record
+}
+
+func (w *testRecordWriter) Write(record *recorder.Record) error {
+	w.records = append(w.records, record)
+	return nil
+}
+func (w *testRecordWriter) Close() error { return nil }
+
+func TestRecorderMiddleware(t *testing.T) {
+	w := &testRecordWriter{}
+	app := iris.New()
+	app.Configure(iris.WithPathEscape)
+	app.Use(func(irisCtx iris.Context) {
+		utils.Context.SaveContextToIrisContext(irisCtx, context.Background())
+		irisCtx.Next()
+	})
+	app.Post("/file/{name:path}", RecorderMiddleware(w, WithRecordMaxBodySize(4)), func(irisCtx iris.Context) {
+		body, _ := irisCtx.GetBody()
+		_, _ = irisCtx.WriteString(string(body))
+	})
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		body          string
+		expectBody    string
+		expectTrunc   bool
+		expectRawResp string
+	}{
+		{"abc", "abc", false, "abc"},
+		{"abcdef", "abcd", true, "abcdef"},
+	}
+	for i, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/file/a%20b%2Fc?x=1", strings.NewReader(test.body))
+		req.Header.Set("Authorization", "secret")
+		app.ServeHTTP(httptest.NewRecorder(), req)
+
+		if len(w.records) != i+1 {
+			t.Fatal("记录数和预期不符", len(w.records))
+		}
+		r := w.records[i]
+		if r.Path != "/file/a%20b%2Fc" || r.Query != "x=1" || r.Route != "/file/{name:path}" {
+			t.Fatal("记录的请求和预期不符", r.Path, r.Query, r.Route)
+		}
+		if r.Header.Get("Authorization") != recorder.ScrubbedValue {
+			t.Fatal("敏感header没有被隐藏", r.Header)
+		}
+		if string(r.Body) != test.expectBody || r.BodyTruncated != test.expectTrunc || string(r.RawResponse) != test.expectRawResp {
+			t.Fatal("记录的body和预期不符", string(r.Body), r.BodyTruncated, string(r.RawResponse))
+		}
+	}
+}
@@ -16,6 +16,12 @@ import (
 func Recover() iris.Handler {
 	return func(ctx iris.Context) {
 		err := app_utils.Recover.WrapCall(func() error {
+			defer func() {
+				if e := recover(); e != nil {
+					ctx.Values().Set("panic_value", e) // 保存原始的panic值
+					panic(e)
+				}
+			}()
 			ctx.Next()
 			return nil
 		})
@@ -0,0 +1,90 @@
+/*
+-------------------------------------------------
+   Author :       zlyuancn
+   date：         2026/10/19
+   Description :
+-------------------------------------------------
+*/
+
+package recorder
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// 被隐藏的header值
+const ScrubbedValue = "******"
+
+// 一次请求和响应的记录, 每条记录在文件中占一行json
+type Record struct {
+	Time          time.Time       `json:"time"`
+	Method        string          `json:"method"`
+	Route         string          `json:"route"` // 路由模板, 例如 /user/{id}
+	Path          string          `json:"path"` // 转义后的path, 例如 /file/a%20b, 重放时原样使用
+	Query         string          `json:"query,omitempty"`
+	Header        http.Header     `json:"header,omitempty"`
+	Body          []byte          `json:"body,omitempty"`
+	BodyTruncated bool            `json:"body_truncated,omitempty"` // body超出记录上限被截断, 这种记录无法重放
+	StatusCode    int             `json:"status_code"`
+	Response      json.RawMessage `json:"response,omitempty"`     // json响应, 一般为 api.Response
+	RawResponse   []byte          `json:"raw_response,omitempty"` // 非json响应
+}
+
+// 从 r 中读取记录
+func ReadRecords(r io.Reader) ([]*Record, error) {
+	var records []*Record
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1<<30)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		record := new(Record)
+		if err := json.Unmarshal(scanner.Bytes(), record); err != nil {
+			return records, fmt.Errorf("parse record failed at line %d: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// 从文件中读取记录, 支持通配符, 例如 ./records/api*.jsonl, 结果按记录时间排序
+func LoadFiles(patterns ...string) ([]*Record, error) {
+	var records []*Record
+	for _, pattern := range patterns {
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			rs, err := loadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("load record file %s failed: %v", file, err)
+			}
+			records = append(records, rs...)
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time.Before(records[j].Time)
+	})
+	return records, nil
+}
+
+func loadFile(file string) ([]*Record, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadRecords(f)
+}
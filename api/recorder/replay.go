@@ -0,0 +1,242 @@
+/*
+-------------------------------------------------
+   Author :       zlyuancn
+   date：         2026/10/19
+   Description :
+-------------------------------------------------
+*/
+
+package recorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type replayOptions struct {
+	IgnoreFields []string    // 比较时忽略的字段
+	Header       http.Header // 覆盖记录中的header
+}
+
+type ReplayOption func(o *replayOptions)
+
+func newReplayOptions(opts ...ReplayOption) *replayOptions {
+	o := &replayOptions{
+		IgnoreFields: []string{"trace_id"},
+		Header:       make(http.Header),
+	}
+	for _, fn := range opts {
+		fn(o)
+	}
+	return o
+}
+
+// 设置比较响应时忽略的字段, 用.分隔层级, *匹配任意一层, 例如 data.create_time, data.list.*.id. 默认忽略 trace_id
+func WithReplayIgnoreFields(fields ...string) ReplayOption {
+	return func(o *replayOptions) {
+		o.IgnoreFields = append(o.IgnoreFields, fields...)
+	}
+}
+
+// 设置重放时的header, 会覆盖记录中的同名header, 一般用于替换记录时被隐藏的认证信息
+func WithReplayHeader(key, value string) ReplayOption {
+	return func(o *replayOptions) {
+		o.Header.Set(key, value)
+	}
+}
+
+// 响应差异
+type Diff struct {
+	Field    string      `json:"field"` // 字段路径, 状态码差异为 status_code, 非json响应差异为 raw_response
+	Expected interface{} `json:"expected"`
+	Actual   interface{} `json:"actual"`
+}
+
+func (d Diff) String() string {
+	return fmt.Sprintf("%s: expected %v, actual %v", d.Field, d.Expected, d.Actual)
+}
+
+// 单条记录的重放结果
+type ReplayResult struct {
+	Record  *Record `json:"record"`
+	Skipped bool    `json:"skipped,omitempty"` // 记录无法重放, 例如body被截断
+	Diffs   []Diff  `json:"diffs,omitempty"`
+}
+
+// 重放报告
+type ReplayReport struct {
+	Total   int             `json:"total"`
+	Passed  int             `json:"passed"`
+	Failed  int             `json:"failed"`
+	Skipped int             `json:"skipped"`
+	Results []*ReplayResult `json:"results"` // 只包含有差异和被跳过的结果
+}
+
+// 将记录的请求发送到 handler 并比较响应
+//
+// handler 可以是已经 Build 的 *api.ApiService, 也可以通过 NewRemoteHandler 发送到远程服务
+func Replay(handler http.Handler, records []*Record, opts ...ReplayOption) *ReplayReport {
+	o := newReplayOptions(opts...)
+	report := &ReplayReport{Total: len(records)}
+	for _, record := range records {
+		result := replayOne(handler, record, o)
+		switch {
+		case result.Skipped:
+			report.Skipped++
+		case len(result.Diffs) > 0:
+			report.Failed++
+		default:
+			report.Passed++
+			continue
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report
+}
+
+func replayOne(handler http.Handler, record *Record, o *replayOptions) *ReplayResult {
+	result := &ReplayResult{Record: record}
+	if record.BodyTruncated {
+		result.Skipped = true
+		return result
+	}
+
+	target := record.Path // 记录的是转义后的path, 不能再次转义
+	if record.Query != "" {
+		target += "?" + record.Query
+	}
+	req := httptest.NewRequest(record.Method, target, bytes.NewReader(record.Body))
+	for k, vs := range record.Header {
+		for _, v := range vs {
+			if v != ScrubbedValue {
+				req.Header.Add(k, v)
+			}
+		}
+	}
+	for k, vs := range o.Header {
+		req.Header[k] = vs
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != record.StatusCode {
+		result.Diffs = append(result.Diffs, Diff{Field: "status_code", Expected: record.StatusCode, Actual: w.Code})
+	}
+
+	body := w.Body.Bytes()
+	if len(record.Response) == 0 {
+		if !bytes.Equal(record.RawResponse, body) {
+			result.Diffs = append(result.Diffs, Diff{Field: "raw_response", Expected: string(record.RawResponse), Actual: string(body)})
+		}
+		return result
+	}
+
+	var expected, actual interface{}
+	_ = json.Unmarshal(record.Response, &expected)
+	if err := json.Unmarshal(body, &actual); err != nil {
+		result.Diffs = append(result.Diffs, Diff{Field: "response", Expected: string(record.Response), Actual: string(body)})
+		return result
+	}
+	result.Diffs = append(result.Diffs, diffValue(nil, expected, actual, o.IgnoreFields)...)
+	return result
+}
+
+// 递归比较json值
+func diffValue(path []string, expected, actual interface{}, ignoreFields []string) []Diff {
+	if isIgnoredField(path, ignoreFields) {
+		return nil
+	}
+
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(e)+len(a))
+		for k := range e {
+			keys = append(keys, k)
+		}
+		for k := range a {
+			if _, ok := e[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+
+		var diffs []Diff
+		for _, k := range keys {
+			diffs = append(diffs, diffValue(append(path, k), e[k], a[k], ignoreFields)...)
+		}
+		return diffs
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok {
+			break
+		}
+		var diffs []Diff
+		n := len(e)
+		if len(a) > n {
+			n = len(a)
+		}
+		for i := 0; i < n; i++ {
+			var ev, av interface{}
+			if i < len(e) {
+				ev = e[i]
+			}
+			if i < len(a) {
+				av = a[i]
+			}
+			diffs = append(diffs, diffValue(append(path, strconv.Itoa(i)), ev, av, ignoreFields)...)
+		}
+		return diffs
+	}
+
+	if reflect.DeepEqual(expected, actual) {
+		return nil
+	}
+	return []Diff{{Field: strings.Join(path, "."), Expected: expected, Actual: actual}}
+}
+
+// 检查字段是否被忽略
+func isIgnoredField(path []string, ignoreFields []string) bool {
+	if len(path) == 0 {
+		return false
+	}
+	for _, field := range ignoreFields {
+		parts := strings.Split(field, ".")
+		if len(parts) != len(path) {
+			continue
+		}
+		match := true
+		for i, p := range parts {
+			if p != "*" && p != path[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// 创建一个将请求转发到远程服务的 handler, 用于重放到其它进程中的服务, 例如 http://127.0.0.1:8080
+func NewRemoteHandler(baseURL string) (http.Handler, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	return httputil.NewSingleHostReverseProxy(u), nil
+}
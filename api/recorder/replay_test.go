@@ -0,0 +1,89 @@
+package recorder
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIsIgnoredField(t *testing.T) {
+	tests := []struct {
+		path   []string
+		fields []string
+		expect bool
+	}{
+		{nil, []string{"trace_id"}, false},
+		{[]string{"trace_id"}, []string{"trace_id"}, true},
+		{[]string{"data", "trace_id"}, []string{"trace_id"}, false},
+		{[]string{"data", "list", "0", "id"}, []string{"data.list.*.id"}, true},
+		{[]string{"data", "list", "0", "name"}, []string{"data.list.*.id"}, false},
+		{[]string{"data", "list"}, []string{"data.list.*.id"}, false},
+	}
+	for _, test := range tests {
+		if ignored := isIgnoredField(test.path, test.fields); ignored != test.expect {
+			t.Fatal("字段是否忽略和预期不符", test.path, test.fields, ignored)
+		}
+	}
+}
+
+func TestDiffValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		actual   string
+		ignore   []string
+		expect   []Diff
+	}{
+		{"equal", `{"a":1,"b":[1,2]}`, `{"b":[1,2],"a":1}`, nil, nil},
+		{"value", `{"a":1}`, `{"a":2}`, nil, []Diff{{Field: "a", Expected: 1.0, Actual: 2.0}}},
+		{"missing_and_extra_keys", `{"a":1}`, `{"b":1}`, nil, []Diff{{Field: "a", Expected: 1.0}, {Field: "b", Actual: 1.0}}},
+		{"array_length", `[1]`, `[1,2]`, nil, []Diff{{Field: "1", Actual: 2.0}}},
+		{"type", `{"a":{"b":1}}`, `{"a":[1]}`, nil, []Diff{{Field: "a", Expected: map[string]interface{}{"b": 1.0}, Actual: []interface{}{1.0}}}},
+		{"ignored", `{"data":{"list":[{"id":1,"v":1}]},"trace_id":"x"}`, `{"data":{"list":[{"id":2,"v":1}]},"trace_id":"y"}`, []string{"trace_id", "data.list.*.id"}, nil},
+	}
+	for _, test := range tests {
+		var expected, actual interface{}
+		_ = json.Unmarshal([]byte(test.expected), &expected)
+		_ = json.Unmarshal([]byte(test.actual), &actual)
+		if diffs := diffValue(nil, expected, actual, test.ignore); !reflect.DeepEqual(diffs, test.expect) {
+			t.Fatal("差异和预期不符", test.name, diffs)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"path":     r.URL.EscapedPath(),
+			"query":    r.URL.RawQuery,
+			"token":    r.Header.Get("X-Token"),
+			"body":     string(body),
+			"trace_id": "new",
+		})
+	})
+
+	records := []*Record{
+		{Method: http.MethodPost, Path: "/file/a%20b%2Fc", Query: "x=1", Body: []byte("hi"), StatusCode: http.StatusOK,
+			Header:   http.Header{"X-Token": {ScrubbedValue}},
+			Response: json.RawMessage(`{"path":"/file/a%20b%2Fc","query":"x=1","token":"t","body":"hi","trace_id":"old"}`)},
+		{Method: http.MethodGet, Path: "/", StatusCode: http.StatusOK,
+			Response: json.RawMessage(`{"path":"/other","query":"","token":"t","body":"","trace_id":"old"}`)},
+		{Method: http.MethodGet, Path: "/", BodyTruncated: true},
+		{Method: http.MethodGet, Path: "/", StatusCode: http.StatusOK, RawResponse: []byte("text")},
+	}
+	report := Replay(handler, records, WithReplayHeader("X-Token", "t"))
+	if report.Total != 4 || report.Passed != 1 || report.Failed != 2 || report.Skipped != 1 {
+		t.Fatal("重放报告和预期不符", report.Total, report.Passed, report.Failed, report.Skipped)
+	}
+	if diffs := report.Results[0].Diffs; len(diffs) != 1 || diffs[0].Field != "path" {
+		t.Fatal("差异和预期不符", diffs)
+	}
+	if diffs := report.Results[2].Diffs; len(diffs) != 1 || diffs[0].Field != "raw_response" || !strings.Contains(diffs[0].Actual.(string), "/") {
+		t.Fatal("非json响应的差异和预期不符", diffs)
+	}
+}
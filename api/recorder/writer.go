@@ -0,0 +1,145 @@
+/*
+-------------------------------------------------
+   Author :       zlyuancn
+   date：         2026/10/19
+   Description :
+-------------------------------------------------
+*/
+
+package recorder
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// 默认单个记录文件最大大小
+	DefaultMaxFileSize = 100 << 20
+	// 默认保留的历史记录文件数
+	DefaultMaxBackups = 10
+)
+
+// 记录写入器
+type IWriter interface {
+	Write(record *Record) error
+	Close() error
+}
+
+// 可轮转的文件写入器
+//
+// 记录写入到 Path, 文件大小超过 MaxFileSize 时会被重命名为 <name>.<时间>.<ext> 并创建新的文件, 最多保留 MaxBackups 个历史文件
+type FileWriter struct {
+	path        string
+	maxFileSize int64
+	maxBackups  int
+
+	f    *os.File
+	size int64
+	mx   sync.Mutex
+}
+
+// 创建可轮转的文件写入器, maxFileSize 小于1时使用 DefaultMaxFileSize, maxBackups 小于1时使用 DefaultMaxBackups
+func NewFileWriter(path string, maxFileSize int64, maxBackups int) (*FileWriter, error) {
+	if maxFileSize < 1 {
+		maxFileSize = DefaultMaxFileSize
+	}
+	if maxBackups < 1 {
+		maxBackups = DefaultMaxBackups
+	}
+	w := &FileWriter{
+		path:        path,
+		maxFileSize: maxFileSize,
+		maxBackups:  maxBackups,
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *FileWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	w.f = f
+	w.size = info.Size()
+	return nil
+}
+
+func (w *FileWriter) Write(record *Record) error {
+	bs, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	bs = append(bs, '\n')
+
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	if w.f == nil {
+		return os.ErrClosed
+	}
+	if w.size > 0 && w.size+int64(len(bs)) > w.maxFileSize {
+		if err = w.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := w.f.Write(bs)
+	w.size += int64(n)
+	return err
+}
+
+// 轮转文件
+func (w *FileWriter) rotate() error {
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	w.f = nil
+
+	ext := filepath.Ext(w.path)
+	base := strings.TrimSuffix(w.path, ext)
+	backup := base + "." + time.Now().Format("20060102-150405.000") + ext
+	if err := os.Rename(w.path, backup); err != nil {
+		return err
+	}
+	w.removeOldBackups(base, ext)
+	return w.open()
+}
+
+// 删除超出数量的历史文件
+func (w *FileWriter) removeOldBackups(base, ext string) {
+	files, err := filepath.Glob(base + ".*" + ext)
+	if err != nil || len(files) <= w.maxBackups {
+		return
+	}
+	sort.Strings(files) // 文件名中的时间可以直接按字符串排序
+	for _, file := range files[:len(files)-w.maxBackups] {
+		_ = os.Remove(file)
+	}
+}
+
+func (w *FileWriter) Close() error {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	if w.f == nil {
+		return nil
+	}
+	err := w.f.Close()
+	w.f = nil
+	return err
+}
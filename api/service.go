@@ -11,6 +11,9 @@ package api
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
 
 	"github.com/iris-contrib/middleware/cors"
 	"github.com/kataras/iris/v12"
@@ -21,6 +24,7 @@ import (
 
 	"github.com/zly-app/service/api/config"
 	"github.com/zly-app/service/api/middleware"
+	"github.com/zly-app/service/api/recorder"
 )
 
 type Party = iris.Party
@@ -29,26 +33,94 @@ type Party = iris.Party
 type RegisterApiRouterFunc = func(c core.IComponent, router Party)
 
 type ApiService struct {
-	app  core.IApp
-	conf *config.Config
+	app          core.IApp
+	conf         *config.Config
+	runtimeConf  atomic.Value // 当前生效的配置, 可以在运行时替换
+	gpoolLimiter *gpoolLimiter
+	adminApp     *iris.Application // 独立bind的admin服务
+	grpcGateway  *grpcGateway
 	*iris.Application
 }
 
-// 协程池限制
+var errGPoolLimit = errors.New("gPool Limit")
+
+// 协程池限制, 使用了隔离舱的路由不受这个限制
 func GPoolLimitMiddleware(app core.IApp, conf *config.Config) func(ctx *Context) error {
+	return newGPoolLimiter(conf.ThreadCount, conf.MaxReqWaitQueueSize).GlobalHandle
+}
+
+// 协程池统计
+type GPoolStats struct {
+	ThreadCount int   // 同时处理请求的goroutine数, -1表示不限制
+	QueueSize   int   // 最大请求等待队列大小
+	Running     int64 // 正在处理的请求数
+	Waiting     int64 // 正在排队的请求数
+	Rejected    int64 // 累计被拒绝的请求数
+}
+
+type gpoolLimiter struct {
+	pool        core.IGPool
+	threadCount int
+	queueSize   int
+	running     int64
+	waiting     int64
+	rejected    int64
+}
+
+func newGPoolLimiter(threadCount, queueSize int) *gpoolLimiter {
 	pool := gpool.NewGPool(&gpool.GPoolConfig{
-		JobQueueSize: conf.MaxReqWaitQueueSize,
-		ThreadCount:  conf.ThreadCount,
+		JobQueueSize: queueSize,
+		ThreadCount:  threadCount,
 	})
-	return func(ctx *Context) error {
-		err, ok := pool.TryGoSync(func() error {
-			ctx.Next()
-			return nil
-		})
-		if !ok {
-			return errors.New("gPool Limit")
-		}
-		return err
+	return &gpoolLimiter{
+		pool:        pool,
+		threadCount: threadCount,
+		queueSize:   queueSize,
+	}
+}
+
+func (g *gpoolLimiter) Middleware(ctx *Context) error {
+	atomic.AddInt64(&g.waiting, 1)
+	err, ok := g.pool.TryGoSync(func() error {
+		atomic.AddInt64(&g.waiting, -1)
+		atomic.AddInt64(&g.running, 1)
+		ctx.Next()
+		atomic.AddInt64(&g.running, -1)
+		return nil
+	})
+	if !ok {
+		atomic.AddInt64(&g.waiting, -1)
+		atomic.AddInt64(&g.rejected, 1)
+		return errGPoolLimit
+	}
+	return err
+}
+
+// 协程池已满时返回 ServiceBusy, http状态码为503
+func (g *gpoolLimiter) Handle(ctx *Context) error {
+	err := g.Middleware(ctx)
+	if err == errGPoolLimit {
+		ctx.StatusCode(http.StatusServiceUnavailable)
+		return ServiceBusy
+	}
+	return err
+}
+
+// 作为全局协程池限制, 使用了隔离舱的路由直接放行, 避免在隔离舱排队时占用全局协程池
+func (g *gpoolLimiter) GlobalHandle(ctx *Context) error {
+	if hasBulkhead(ctx) {
+		return nil
+	}
+	return g.Handle(ctx)
+}
+
+func (g *gpoolLimiter) Stats() GPoolStats {
+	return GPoolStats{
+		ThreadCount: g.threadCount,
+		QueueSize:   g.queueSize,
+		Running:     atomic.LoadInt64(&g.running),
+		Waiting:     atomic.LoadInt64(&g.waiting),
+		Rejected:    atomic.LoadInt64(&g.rejected),
 	}
 }
 
@@ -56,16 +128,31 @@ func NewApiService(app core.IApp, conf *config.Config, opts ...Option) *ApiServi
 	// 处理选项
 	o := newOptions(opts...)
 
+	a := &ApiService{
+		app:          app,
+		conf:         conf,
+		gpoolLimiter: newGPoolLimiter(conf.ThreadCount, conf.MaxReqWaitQueueSize),
+	}
+	a.runtimeConf.Store(conf)
+	a.grpcGateway = newGrpcGateway(a)
+
 	// irisApp
 	irisApp := iris.New()
 	irisApp.Logger().SetLevel("disable") // 关闭默认日志
 	irisApp.Use(
-		middleware.BaseMiddleware(app, conf),
-		middleware.LoggerMiddleware(app, conf),          // 日志
-		WrapMiddleware(GPoolLimitMiddleware(app, conf)), // 协程池限制
+		middleware.BaseMiddlewareOfConfGetter(app, a.Config),
+		middleware.LoggerMiddleware(app, a.Config()), // 日志
+	)
+	if conf.RecordEnable {
+		irisApp.Use(a.makeRecorder()) // 请求记录
+	}
+	irisApp.Use(
+		defaultRouteGroupMiddleware(),               // 没有路由组的路由属于默认路由组
+		WrapMiddleware(a.gpoolLimiter.GlobalHandle), // 协程池限制
 		cors.AllowAll(),
-		middleware.Recover(), // panic恢复
+		recoverMiddleware(), // panic恢复
 	)
+	useErrorCodeHandlers(irisApp, app, a.Config) // 404, 405
 	irisApp.AllowMethods(iris.MethodOptions)
 
 	// 配置项
@@ -78,6 +165,7 @@ func NewApiService(app core.IApp, conf *config.Config, opts ...Option) *ApiServi
 
 	// 在app关闭前优雅的关闭服务
 	zapp.AddHandler(zapp.BeforeExitHandler, func(app core.IApp, handlerType zapp.HandlerType) {
+		a.grpcGateway.close()
 		err := irisApp.Shutdown(context.Background())
 		if err != nil {
 			app.Error("irisApp关闭失败", zap.Error(err))
@@ -86,15 +174,15 @@ func NewApiService(app core.IApp, conf *config.Config, opts ...Option) *ApiServi
 		app.Warn("api服务已关闭")
 	})
 
-	return &ApiService{
-		app:         app,
-		conf:        conf,
-		Application: irisApp,
-	}
+	a.Application = irisApp
+	a.initIPFilter()
+	a.initCanary()
+	a.initAdmin()
+	return a
 }
 
 func (a *ApiService) Start() error {
-	a.app.Info("正在启动api服务", zap.String("bind", a.conf.Bind))
+	a.app.Info("正在启动api服务")
 	opts := []iris.Configurator{
 		iris.WithoutBodyConsumptionOnUnmarshal,       // 重复消费
 		iris.WithoutPathCorrection,                   // 不自动补全斜杠
@@ -113,13 +201,72 @@ func (a *ApiService) Start() error {
 	if a.conf.IPWithProxyReal {
 		opts = append(opts, iris.WithRemoteAddrHeader("X-Real-IP"))
 	}
-	return a.Run(iris.Addr(a.conf.Bind), opts...)
+	if err := a.startAdmin(opts...); err != nil {
+		return err
+	}
+	if err := a.grpcGateway.start(); err != nil {
+		return err
+	}
+
+	listeners, err := a.listen()
+	if err != nil {
+		return fmt.Errorf("api服务监听失败: %v", err)
+	}
+	a.logListeners(listeners)
+	return a.Run(serveListeners(listeners), opts...)
+}
+
+// 根据配置创建请求记录中间件
+func (a *ApiService) makeRecorder() iris.Handler {
+	w, err := recorder.NewFileWriter(a.conf.RecordPath, a.conf.RecordMaxFileSize, a.conf.RecordMaxBackups)
+	if err != nil {
+		a.app.Fatal("创建api服务请求记录文件失败", zap.String("path", a.conf.RecordPath), zap.Error(err))
+	}
+	zapp.AddHandler(zapp.AfterExitHandler, func(app core.IApp, handlerType zapp.HandlerType) {
+		_ = w.Close()
+	})
+	return middleware.RecorderMiddleware(w,
+		middleware.WithRecordSampleRate(a.conf.RecordSampleRate),
+		middleware.WithRecordMaxBodySize(a.conf.RecordMaxBodySize),
+		middleware.WithRecordScrubHeaders(a.conf.RecordScrubHeaders...),
+	)
 }
 
-// 注册路由
+// 获取当前生效的配置
+func (a *ApiService) Config() *config.Config {
+	return a.runtimeConf.Load().(*config.Config)
+}
+
+// 在运行时修改配置, fn 会收到当前配置的副本, 修改完成后会替换当前配置并对之后的请求生效
+//
+// 只有日志相关等在请求时读取的配置才能在运行时生效, 例如 Bind, ThreadCount 等启动时使用的配置修改后不会生效
+func (a *ApiService) UpdateConfig(fn func(conf *config.Config)) {
+	conf := *a.Config()
+	fn(&conf)
+	a.runtimeConf.Store(&conf)
+}
+
+// 获取协程池统计
+func (a *ApiService) GPoolStats() GPoolStats {
+	return a.gpoolLimiter.Stats()
+}
+
+// 注册路由, 路由属于默认路由组
 func (a *ApiService) RegistryRouter(fn ...RegisterApiRouterFunc) {
+	a.RegistryRouterGroup(DefaultRouteGroup, fn...)
+}
+
+// 注册路由到指定的路由组, 只有暴露了这个路由组的监听器才能访问这些路由
+func (a *ApiService) RegistryRouterGroup(group string, fn ...RegisterApiRouterFunc) {
+	for _, h := range fn {
+		h(a.app.GetComponent(), a.Party("/", routeGroupMiddleware(group)))
+	}
+}
+
+// 注册同时提供http和grpc服务的路由, 路由属于默认路由组
+func (a *ApiService) RegistryDualRouter(fn ...RegisterDualRouterFunc) {
 	for _, h := range fn {
-		h(a.app.GetComponent(), a.Party("/"))
+		h(a.app.GetComponent(), &DualRouter{Party: a.Party("/", routeGroupMiddleware(DefaultRouteGroup)), gateway: a.grpcGateway})
 	}
 }
 
@@ -0,0 +1,378 @@
+/*
+-------------------------------------------------
+   Author :       zlyuancn
+   date：         2026/10/19
+   Description :
+-------------------------------------------------
+*/
+
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kataras/iris/v12"
+	"github.com/tjfoc/gmsm/sm3"
+	"github.com/zly-app/zapp/logger"
+	"go.uber.org/zap"
+)
+
+// 签名算法
+type SignAlgorithm string
+
+const (
+	SignHmacSHA256 SignAlgorithm = "HMAC-SHA256"
+	SignHmacSM3    SignAlgorithm = "HMAC-SM3"
+)
+
+// 签名编码
+type SignEncoding string
+
+const (
+	SignEncodingHex    SignEncoding = "hex"
+	SignEncodingBase64 SignEncoding = "base64"
+)
+
+const (
+	defaultSignAppIDHeader     = "X-App-Id"
+	defaultSignTimestampHeader = "X-Timestamp"
+	defaultSignNonceHeader     = "X-Nonce"
+	defaultSignatureHeader     = "X-Signature"
+	defaultSignClockSkew       = time.Minute * 5
+	defaultSignMaxBodySize     = 4 << 20
+
+	// 签名校验通过后保存在iris上下文中的appID字段
+	signAppIDFieldKey = "_sign_app_id"
+)
+
+// 签名校验失败的错误
+var (
+	SignHeaderMissing    = AuthorizationError.WithMessage("sign header missing")
+	SignTimestampInvalid = AuthorizationError.WithMessage("sign timestamp invalid")
+	SignTimestampExpired = AuthorizationError.WithMessage("sign timestamp expired")
+	SignAppUnknown       = AuthorizationError.WithMessage("sign app unknown")
+	SignatureMismatch    = AuthorizationError.WithMessage("signature mismatch")
+	SignNonceReplayed    = AuthorizationError.WithMessage("sign nonce replayed")
+)
+
+// 参与签名的数据
+type SignPayload struct {
+	Method    string
+	Path      string
+	Query     string // 按key排序后的query
+	AppID     string
+	Timestamp string
+	Nonce     string
+	Headers   []string // 参与签名的header, 格式为 小写key:value, 顺序和 SignConfig.SignedHeaders 一致
+	Body      []byte
+}
+
+// 构建待签名数据
+type SignCanonicalizeFunc func(p *SignPayload) []byte
+
+// 默认的待签名数据, 每行一个字段: method, path, query, appID, timestamp, nonce, 参与签名的header, body
+func DefaultSignCanonicalize(p *SignPayload) []byte {
+	var buf bytes.Buffer
+	for _, s := range []string{p.Method, p.Path, p.Query, p.AppID, p.Timestamp, p.Nonce} {
+		buf.WriteString(s)
+		buf.WriteByte('\n')
+	}
+	for _, h := range p.Headers {
+		buf.WriteString(h)
+		buf.WriteByte('\n')
+	}
+	buf.Write(p.Body)
+	return buf.Bytes()
+}
+
+// 只有 timestamp, nonce 和 body 参与签名, 格式为 timestamp\nnonce\nbody
+func SignCanonicalizeTimestampNonceBody(p *SignPayload) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(p.Timestamp)
+	buf.WriteByte('\n')
+	buf.WriteString(p.Nonce)
+	buf.WriteByte('\n')
+	buf.Write(p.Body)
+	return buf.Bytes()
+}
+
+// 根据appID获取密钥, 返回空字符串表示appID不存在
+type SignSecretLookupFunc func(ctx *Context, appID string) (string, error)
+
+// 签名校验配置
+type SignConfig struct {
+	AppIDHeader     string        // appID的header, 默认 X-App-Id
+	TimestampHeader string        // 时间戳的header, 值为unix秒, 默认 X-Timestamp
+	NonceHeader     string        // 随机数的header, 默认 X-Nonce
+	SignatureHeader string        // 签名的header, 默认 X-Signature
+	SignedHeaders   []string      // 额外参与签名的header
+	Algorithm       SignAlgorithm // 签名算法, 默认 HMAC-SHA256
+	Encoding        SignEncoding  // 签名编码, 默认 hex
+	ClockSkew       time.Duration // 允许的时钟偏差, 默认5分钟
+	NonceTTL        time.Duration // nonce的有效期, 默认为 ClockSkew 的两倍
+	NonceStore      INonceStore   // nonce存储, 默认为内存存储
+	MaxBodySize     int64         // 参与签名的body最大大小, 单位字节, 默认4M, 超出时返回 RequestBodyTooLarge, http状态码为413
+
+	SecretLookup SignSecretLookupFunc // 根据appID获取密钥, 必须设置
+	Canonicalize SignCanonicalizeFunc // 构建待签名数据, 默认为 DefaultSignCanonicalize
+}
+
+func (conf *SignConfig) check() {
+	if conf.AppIDHeader == "" {
+		conf.AppIDHeader = defaultSignAppIDHeader
+	}
+	if conf.TimestampHeader == "" {
+		conf.TimestampHeader = defaultSignTimestampHeader
+	}
+	if conf.NonceHeader == "" {
+		conf.NonceHeader = defaultSignNonceHeader
+	}
+	if conf.SignatureHeader == "" {
+		conf.SignatureHeader = defaultSignatureHeader
+	}
+	if conf.Algorithm == "" {
+		conf.Algorithm = SignHmacSHA256
+	}
+	if conf.Encoding == "" {
+		conf.Encoding = SignEncodingHex
+	}
+	if conf.ClockSkew <= 0 {
+		conf.ClockSkew = defaultSignClockSkew
+	}
+	if conf.NonceTTL <= 0 {
+		conf.NonceTTL = conf.ClockSkew * 2
+	}
+	if conf.MaxBodySize <= 0 {
+		conf.MaxBodySize = defaultSignMaxBodySize
+	}
+	if conf.NonceStore == nil {
+		conf.NonceStore = NewMemoryNonceStore()
+	}
+	if conf.Canonicalize == nil {
+		conf.Canonicalize = DefaultSignCanonicalize
+	}
+
+	if conf.SecretLookup == nil {
+		logger.Log.Fatal("签名校验必须设置 SecretLookup")
+	}
+	if conf.Encoding != SignEncodingHex && conf.Encoding != SignEncodingBase64 {
+		logger.Log.Fatal("不支持的签名编码", zap.String("encoding", string(conf.Encoding)))
+	}
+	if _, err := conf.newHash(); err != nil {
+		logger.Log.Fatal("不支持的签名算法", zap.String("algorithm", string(conf.Algorithm)))
+	}
+}
+
+func (conf *SignConfig) newHash() (func() hash.Hash, error) {
+	switch conf.Algorithm {
+	case SignHmacSHA256:
+		return sha256.New, nil
+	case SignHmacSM3:
+		return sm3.New, nil
+	}
+	return nil, fmt.Errorf("unsupported sign algorithm %s", conf.Algorithm)
+}
+
+// 计算签名, 可以用于调用方生成签名
+func (conf *SignConfig) Sign(secret string, p *SignPayload) (string, error) {
+	h, err := conf.newHash()
+	if err != nil {
+		return "", err
+	}
+	canonicalize := conf.Canonicalize
+	if canonicalize == nil {
+		canonicalize = DefaultSignCanonicalize
+	}
+
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(canonicalize(p))
+	sum := mac.Sum(nil)
+	if conf.Encoding == SignEncodingBase64 {
+		return base64.StdEncoding.EncodeToString(sum), nil
+	}
+	return hex.EncodeToString(sum), nil
+}
+
+// 签名校验中间件
+//
+// 依次校验header是否完整, 时间戳是否在允许的时钟偏差内, appID是否存在, 签名是否正确, nonce是否重复,
+// 失败时返回对应的 AuthorizationError, 校验通过后可以通过 GetSignAppID 获取appID.
+// 时间戳和appID校验通过后才会读取body, body超出 MaxBodySize 时返回 RequestBodyTooLarge
+func SignVerify(conf SignConfig) iris.Handler {
+	conf.check()
+	return WrapMiddleware(func(ctx *Context) error {
+		p, signature, err := makeSignPayload(ctx, &conf)
+		if err != nil {
+			return err
+		}
+
+		ts, err := strconv.ParseInt(p.Timestamp, 10, 64)
+		if err != nil {
+			return SignTimestampInvalid.WithError(fmt.Errorf("sign timestamp %q invalid: %v", p.Timestamp, err))
+		}
+		if skew := time.Since(time.Unix(ts, 0)); skew > conf.ClockSkew || skew < -conf.ClockSkew {
+			return SignTimestampExpired.WithError(fmt.Errorf("sign timestamp skew %s out of %s", skew, conf.ClockSkew))
+		}
+
+		secret, err := conf.SecretLookup(ctx, p.AppID)
+		if err != nil {
+			return err
+		}
+		if secret == "" {
+			return SignAppUnknown.WithError(fmt.Errorf("sign app %q unknown", p.AppID))
+		}
+
+		if p.Body, err = readSignBody(ctx, conf.MaxBodySize); err != nil {
+			return err
+		}
+
+		expected, err := conf.Sign(secret, p)
+		if err != nil {
+			return err
+		}
+		if !hmac.Equal([]byte(expected), []byte(signature)) {
+			return SignatureMismatch
+		}
+
+		// 签名正确后才记录nonce, 避免伪造的请求占用nonce
+		if !conf.NonceStore.CheckAndSet(p.AppID+":"+p.Nonce, conf.NonceTTL) {
+			return SignNonceReplayed.WithError(fmt.Errorf("sign nonce %q replayed", p.Nonce))
+		}
+
+		ctx.Values().Set(signAppIDFieldKey, p.AppID)
+		return nil
+	})
+}
+
+// 从请求header中构建待签名数据, 不包含body
+func makeSignPayload(ctx *Context, conf *SignConfig) (*SignPayload, string, error) {
+	p := &SignPayload{
+		Method:    ctx.Method(),
+		Path:      ctx.Path(),
+		Query:     sortedQuery(ctx.Request().URL.Query()),
+		AppID:     ctx.GetHeader(conf.AppIDHeader),
+		Timestamp: ctx.GetHeader(conf.TimestampHeader),
+		Nonce:     ctx.GetHeader(conf.NonceHeader),
+	}
+	signature := ctx.GetHeader(conf.SignatureHeader)
+	for _, kv := range [][2]string{
+		{conf.AppIDHeader, p.AppID},
+		{conf.TimestampHeader, p.Timestamp},
+		{conf.NonceHeader, p.Nonce},
+		{conf.SignatureHeader, signature},
+	} {
+		if kv[1] == "" {
+			return nil, "", SignHeaderMissing.WithError(fmt.Errorf("sign header %s missing", kv[0]))
+		}
+	}
+	for _, h := range conf.SignedHeaders {
+		p.Headers = append(p.Headers, strings.ToLower(h)+":"+strings.TrimSpace(ctx.GetHeader(h)))
+	}
+
+	return p, signature, nil
+}
+
+// 读取参与签名的body, 读取后放回, 后续的handler可以再次读取
+func readSignBody(ctx *Context, maxSize int64) ([]byte, error) {
+	if ctx.GetContentLength() > maxSize {
+		ctx.StatusCode(http.StatusRequestEntityTooLarge)
+		return nil, RequestBodyTooLarge
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(ctx.Request().Body, maxSize+1))
+	if err != nil {
+		if ctx.Values().GetBoolDefault(bodyTooLargeFieldKey, false) {
+			ctx.StatusCode(http.StatusRequestEntityTooLarge)
+			return nil, RequestBodyTooLarge.WithError(err)
+		}
+		return nil, ParamError.WithError(err)
+	}
+	if int64(len(body)) > maxSize {
+		ctx.StatusCode(http.StatusRequestEntityTooLarge)
+		return nil, RequestBodyTooLarge
+	}
+	ctx.Request().Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// 按key排序query, 相同key的值保持原顺序
+func sortedQuery(query map[string][]string) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var texts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			texts = append(texts, k+"="+v)
+		}
+	}
+	return strings.Join(texts, "&")
+}
+
+// 获取签名校验通过的appID
+func GetSignAppID(ctx *Context) string {
+	return ctx.Values().GetString(signAppIDFieldKey)
+}
+
+// nonce存储
+type INonceStore interface {
+	// 检查nonce是否未使用过, 未使用过时记录nonce并返回true, 记录在 ttl 后过期
+	CheckAndSet(nonce string, ttl time.Duration) bool
+}
+
+// 内存nonce存储
+type MemoryNonceStore struct {
+	nonces    map[string]time.Time // nonce -> 过期时间
+	lastClean time.Time
+	mx        sync.Mutex
+}
+
+// 内存nonce存储清理过期数据的间隔
+const memoryNonceStoreCleanInterval = time.Minute
+
+// 创建内存nonce存储
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{
+		nonces:    make(map[string]time.Time),
+		lastClean: time.Now(),
+	}
+}
+
+func (m *MemoryNonceStore) CheckAndSet(nonce string, ttl time.Duration) bool {
+	now := time.Now()
+
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	if now.Sub(m.lastClean) >= memoryNonceStoreCleanInterval {
+		for k, expireAt := range m.nonces {
+			if !now.Before(expireAt) {
+				delete(m.nonces, k)
+			}
+		}
+		m.lastClean = now
+	}
+
+	if expireAt, ok := m.nonces[nonce]; ok && now.Before(expireAt) {
+		return false
+	}
+	m.nonces[nonce] = now.Add(ttl)
+	return true
+}
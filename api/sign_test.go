@@ -0,0 +1,193 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kataras/iris/v12"
+)
+
+func TestSignCanonicalize(t *testing.T) {
+	p := &SignPayload{
+		Method:    "POST",
+		Path:      "/callback",
+		Query:     "a=1&b=2",
+		AppID:     "app",
+		Timestamp: "1700000000",
+		Nonce:     "n1",
+		Headers:   []string{"x-user:u1"},
+		Body:      []byte(`{"k":"v"}`),
+	}
+	tests := []struct {
+		name         string
+		canonicalize SignCanonicalizeFunc
+		expect       string
+	}{
+		{"default", DefaultSignCanonicalize, "POST\n/callback\na=1&b=2\napp\n1700000000\nn1\nx-user:u1\n{\"k\":\"v\"}"},
+		{"timestamp_nonce_body", SignCanonicalizeTimestampNonceBody, "1700000000\nn1\n{\"k\":\"v\"}"},
+	}
+	for _, test := range tests {
+		if s := string(test.canonicalize(p)); s != test.expect {
+			t.Fatalf("%s: 待签名数据和预期不符: %q", test.name, s)
+		}
+	}
+}
+
+func TestSortedQuery(t *testing.T) {
+	tests := []struct {
+		query  map[string][]string
+		expect string
+	}{
+		{nil, ""},
+		{map[string][]string{"b": {"2"}, "a": {"1"}}, "a=1&b=2"},
+		{map[string][]string{"b": {"2", "1"}, "a": {"3"}}, "a=3&b=2&b=1"},
+	}
+	for _, test := range tests {
+		if s := sortedQuery(test.query); s != test.expect {
+			t.Fatal("排序后的query和预期不符", test.query, s)
+		}
+	}
+}
+
+func TestSignatureEncoding(t *testing.T) {
+	p := &SignPayload{Timestamp: "1700000000", Nonce: "n1", Body: []byte("body")}
+	tests := []struct {
+		conf   SignConfig
+		expect string
+	}{
+		{SignConfig{Canonicalize: SignCanonicalizeTimestampNonceBody}, "833b26642521728c54bbc03bcc458fef5704c57826b87f358f30641ef3f377b8"},
+		{SignConfig{Canonicalize: SignCanonicalizeTimestampNonceBody, Encoding: SignEncodingBase64}, "gzsmZCUhcoxUu8A7zEWP71cExXgmuH81jzBkHvPzd7g="},
+		{SignConfig{Canonicalize: SignCanonicalizeTimestampNonceBody, Algorithm: SignHmacSM3}, "c027c7eb142024db80171a9b3ec6176e2e332cf42a57396ac038eba2a3a2be6b"},
+	}
+	for _, test := range tests {
+		test.conf.SecretLookup = func(ctx *Context, appID string) (string, error) { return "", nil }
+		test.conf.check()
+		s, err := test.conf.Sign("secret", p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s != test.expect {
+			t.Fatal("签名和预期不符", test.conf.Algorithm, test.conf.Encoding, s)
+		}
+	}
+}
+
+// 构建一个使用签名校验的服务
+func newSignTestApp(t *testing.T, conf SignConfig) *iris.Application {
+	app := iris.New()
+	app.Use(testBaseMiddleware())
+	app.Post("/callback", SignVerify(conf), Wrap(func(ctx *Context) interface{} {
+		return GetSignAppID(ctx)
+	}))
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+	return app
+}
+
+func TestSignVerify(t *testing.T) {
+	secrets := map[string]string{"app": "secret"}
+	var lookups int
+	conf := SignConfig{
+		MaxBodySize: 16,
+		SecretLookup: func(ctx *Context, appID string) (string, error) {
+			lookups++
+			return secrets[appID], nil
+		},
+	}
+	conf.check()
+	app := newSignTestApp(t, conf)
+
+	type req struct {
+		appID     string
+		timestamp time.Time
+		nonce     string
+		body      string
+		signature string // 为空时自动生成
+	}
+	do := func(r req) *httptest.ResponseRecorder {
+		httpReq := httptest.NewRequest(http.MethodPost, "/callback?b=2&a=1", strings.NewReader(r.body))
+		p := &SignPayload{
+			Method:    http.MethodPost,
+			Path:      "/callback",
+			Query:     "a=1&b=2",
+			AppID:     r.appID,
+			Timestamp: strconv.FormatInt(r.timestamp.Unix(), 10),
+			Nonce:     r.nonce,
+			Body:      []byte(r.body),
+		}
+		signature := r.signature
+		if signature == "" {
+			secret := secrets[r.appID]
+			if secret == "" {
+				secret = "unknown"
+			}
+			signature, _ = conf.Sign(secret, p)
+		}
+		httpReq.Header.Set(conf.AppIDHeader, p.AppID)
+		httpReq.Header.Set(conf.TimestampHeader, p.Timestamp)
+		httpReq.Header.Set(conf.NonceHeader, p.Nonce)
+		httpReq.Header.Set(conf.SignatureHeader, signature)
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httpReq)
+		return w
+	}
+
+	now := time.Now()
+	tests := []struct {
+		name         string
+		req          req
+		expectCode   int
+		expectLookup bool
+	}{
+		{"ok", req{appID: "app", timestamp: now, nonce: "n1", body: `{"k":"v"}`}, OK.Code, true},
+		{"nonce_replayed", req{appID: "app", timestamp: now, nonce: "n1", body: `{"k":"v"}`}, SignNonceReplayed.Code, true},
+		{"signature_mismatch", req{appID: "app", timestamp: now, nonce: "n2", signature: "bad"}, SignatureMismatch.Code, true},
+		{"header_missing", req{timestamp: now, nonce: "n3"}, SignHeaderMissing.Code, false},
+		{"timestamp_expired", req{appID: "app", timestamp: now.Add(-time.Hour), nonce: "n4"}, SignTimestampExpired.Code, false},
+		{"app_unknown", req{appID: "other", timestamp: now, nonce: "n5"}, SignAppUnknown.Code, true},
+		{"body_too_large", req{appID: "app", timestamp: now, nonce: "n6", body: strings.Repeat("a", 17)}, RequestBodyTooLarge.Code, true},
+	}
+	for _, test := range tests {
+		lookups = 0
+		w := do(test.req)
+		var resp struct {
+			Code int `json:"err_code"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Code != test.expectCode {
+			t.Fatal("签名校验结果和预期不符", test.name, w.Body.String())
+		}
+		if (lookups > 0) != test.expectLookup {
+			t.Fatal("获取密钥的调用和预期不符", test.name, lookups)
+		}
+	}
+}
+
+func TestMemoryNonceStore(t *testing.T) {
+	store := NewMemoryNonceStore()
+	tests := []struct {
+		nonce  string
+		ttl    time.Duration
+		sleep  time.Duration
+		expect bool
+	}{
+		{"a", time.Millisecond * 20, 0, true},
+		{"a", time.Millisecond * 20, 0, false},
+		{"b", time.Millisecond * 20, 0, true},
+		{"a", time.Millisecond * 20, time.Millisecond * 30, true},
+	}
+	for i, test := range tests {
+		time.Sleep(test.sleep)
+		if ok := store.CheckAndSet(test.nonce, test.ttl); ok != test.expect {
+			t.Fatal("nonce检查结果和预期不符", i, test.nonce, ok)
+		}
+	}
+}
@@ -45,6 +45,26 @@ func RegistryRouter(fn ...RegisterApiRouterFunc) {
 	zapp.App().InjectService(nowServiceType, a...)
 }
 
+// 路由组注入数据
+type routeGroupInject struct {
+	group string
+	fn    []RegisterApiRouterFunc
+}
+
+// 注册路由到指定的路由组, 只有暴露了这个路由组的监听器才能访问这些路由
+func RegistryRouterGroup(group string, fn ...RegisterApiRouterFunc) {
+	zapp.App().InjectService(nowServiceType, &routeGroupInject{group: group, fn: fn})
+}
+
+// 注册同时提供http和grpc服务的路由
+func RegistryDualRouter(fn ...RegisterDualRouterFunc) {
+	a := make([]interface{}, len(fn))
+	for i, h := range fn {
+		a[i] = h
+	}
+	zapp.App().InjectService(nowServiceType, a...)
+}
+
 type Service struct {
 	app core.IApp
 	api *ApiService
@@ -52,12 +72,16 @@ type Service struct {
 
 func (s *Service) Inject(a ...interface{}) {
 	for _, h := range a {
-		fn, ok := h.(RegisterApiRouterFunc)
-		if !ok {
-			s.app.Fatal("api服务注入类型错误, 它必须能转为 api.RegisterApiRouterFunc")
+		switch fn := h.(type) {
+		case RegisterApiRouterFunc:
+			s.api.RegistryRouter(fn)
+		case RegisterDualRouterFunc:
+			s.api.RegistryDualRouter(fn)
+		case *routeGroupInject:
+			s.api.RegistryRouterGroup(fn.group, fn.fn...)
+		default:
+			s.app.Fatal("api服务注入类型错误, 它必须能转为 api.RegisterApiRouterFunc 或 api.RegisterDualRouterFunc")
 		}
-
-		s.api.RegistryRouter(fn)
 	}
 }
 
@@ -15,6 +15,7 @@ import (
 
 	"github.com/kataras/iris/v12"
 	"github.com/zly-app/zapp/core"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/zly-app/service/api/config"
 )
@@ -63,22 +64,67 @@ func (c *contextUtil) MustGetConfFromIrisContext(ctx iris.Context) *config.Confi
 }
 
 // 试图解析并返回真实客户端的请求IP
+//
+// 如果配置了 TrustedProxies, 只有连接的对端在信任的代理ip段中时才会解析转发header,
+// 并从右向左跳过信任的代理, 返回第一个不受信任的ip
 func (c *contextUtil) GetRemoteIP(ctx iris.Context) string {
+	peerIP := c.GetPeerIP(ctx)
+
+	var trusted IPNets
+	if conf, ok := ctx.Values().Get(ConfContextFieldKey).(*config.Config); ok {
+		trusted = IP.CachedIPNets(conf.TrustedProxies)
+	}
+	if len(trusted) > 0 && !trusted.Contains(net.ParseIP(peerIP)) {
+		return peerIP
+	}
+
 	remoteHeaders := ctx.Application().ConfigurationReadOnly().GetRemoteAddrHeaders()
 	for _, headerName := range remoteHeaders {
 		ipAddresses := strings.Split(ctx.GetHeader(headerName), ",")
-		for _, addr := range ipAddresses {
-			if net.ParseIP(addr) != nil {
+		if len(trusted) == 0 {
+			for _, addr := range ipAddresses {
+				addr = strings.TrimSpace(addr)
+				if net.ParseIP(addr) != nil {
+					return addr
+				}
+			}
+			continue
+		}
+
+		first := ""
+		for i := len(ipAddresses) - 1; i >= 0; i-- {
+			addr := strings.TrimSpace(ipAddresses[i])
+			ip := net.ParseIP(addr)
+			if ip == nil {
+				continue
+			}
+			if !trusted.Contains(ip) {
 				return addr
 			}
+			first = addr
+		}
+		if first != "" { // 全部都是信任的代理
+			return first
 		}
 	}
 
-	addr := strings.TrimSpace(ctx.Request().RemoteAddr)
-	if addr != "" {
-		if ip, _, err := net.SplitHostPort(addr); err == nil {
-			return ip
-		}
+	return peerIP
+}
+
+// 返回连接的对端ip, 不解析任何转发header
+func (c *contextUtil) GetPeerIP(ctx iris.Context) string {
+	peerIP := strings.TrimSpace(ctx.Request().RemoteAddr)
+	if ip, _, err := net.SplitHostPort(peerIP); err == nil {
+		peerIP = ip
+	}
+	return peerIP
+}
+
+// 从context中获取traceID, 如果没有有效的链路数据返回空字符串
+func (c *contextUtil) GetTraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
 	}
-	return addr
+	return sc.TraceID().String()
 }
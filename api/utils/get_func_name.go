@@ -3,6 +3,7 @@ package utils
 import (
 	"reflect"
 	"runtime"
+	"unsafe"
 )
 
 // 获取函数名
@@ -11,3 +12,8 @@ func GetFuncName(f interface{}) string {
 	name := runtime.FuncForPC(pc).Name()
 	return name
 }
+
+// 获取函数实例的标识, 同一个函数字面量创建的不同闭包会得到不同的标识
+func GetFuncInstanceID(f interface{}) uintptr {
+	return (*[2]uintptr)(unsafe.Pointer(&f))[1]
+}
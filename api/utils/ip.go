@@ -0,0 +1,88 @@
+/*
+-------------------------------------------------
+   Author :       zlyuancn
+   date：         2026/10/19
+   Description :
+-------------------------------------------------
+*/
+
+package utils
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+var IP = new(ipUtil)
+
+type ipUtil struct {
+	cache sync.Map // 解析结果缓存, 配置文本 -> IPNets
+}
+
+// ip段列表
+type IPNets []*net.IPNet
+
+// 检查ip是否在ip段列表中
+func (n IPNets) Contains(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range n {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// 解析ip段列表, 支持CIDR和单个ip, 例如 10.0.0.0/8, 127.0.0.1, ::1
+func (u *ipUtil) ParseIPNets(list []string) (IPNets, error) {
+	nets := make(IPNets, 0, len(list))
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid ip %q", s)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cidr %q: %v", s, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// 解析ip段列表并缓存结果, 无效的ip段会被忽略, 应该在加载配置时通过 ParseIPNets 检查
+func (u *ipUtil) CachedIPNets(list []string) IPNets {
+	if len(list) == 0 {
+		return nil
+	}
+	key := strings.Join(list, ",")
+	if v, ok := u.cache.Load(key); ok {
+		return v.(IPNets)
+	}
+
+	nets := make(IPNets, 0, len(list))
+	for _, s := range list {
+		n, err := u.ParseIPNets([]string{s})
+		if err == nil {
+			nets = append(nets, n...)
+		}
+	}
+	u.cache.Store(key, nets)
+	return nets
+}
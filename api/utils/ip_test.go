@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kataras/iris/v12"
+
+	"github.com/zly-app/service/api/config"
+)
+
+func TestParseIPNets(t *testing.T) {
+	tests := []struct {
+		list     []string
+		contains []string
+		excludes []string
+		hasErr   bool
+	}{
+		{list: []string{"10.0.0.0/8"}, contains: []string{"10.1.2.3"}, excludes: []string{"11.0.0.1"}},
+		{list: []string{" 127.0.0.1 ", ""}, contains: []string{"127.0.0.1"}, excludes: []string{"127.0.0.2"}},
+		{list: []string{"::1", "fd00::/8"}, contains: []string{"::1", "fd12::1"}, excludes: []string{"::2", "127.0.0.1"}},
+		{list: []string{"1.2.3.4"}, contains: []string{"::ffff:1.2.3.4"}},
+		{list: []string{"1.2.3"}, hasErr: true},
+		{list: []string{"1.2.3.4/33"}, hasErr: true},
+	}
+	for _, test := range tests {
+		nets, err := IP.ParseIPNets(test.list)
+		if (err != nil) != test.hasErr {
+			t.Fatal("解析结果和预期不符", test.list, err)
+		}
+		for _, ip := range test.contains {
+			if !nets.Contains(net.ParseIP(ip)) {
+				t.Fatal("ip应该在ip段中", test.list, ip)
+			}
+		}
+		for _, ip := range test.excludes {
+			if nets.Contains(net.ParseIP(ip)) {
+				t.Fatal("ip不应该在ip段中", test.list, ip)
+			}
+		}
+	}
+
+	if (IPNets{}).Contains(nil) {
+		t.Fatal("无效的ip不应该在ip段中")
+	}
+}
+
+func TestCachedIPNets(t *testing.T) {
+	nets := IP.CachedIPNets([]string{"10.0.0.0/8", "bad", "127.0.0.1"})
+	if len(nets) != 2 {
+		t.Fatal("无效的ip段应该被忽略", nets)
+	}
+	if again := IP.CachedIPNets([]string{"10.0.0.0/8", "bad", "127.0.0.1"}); len(again) != 2 || again[0] != nets[0] {
+		t.Fatal("应该返回缓存的结果", again)
+	}
+	if IP.CachedIPNets(nil) != nil {
+		t.Fatal("空列表应该返回nil")
+	}
+}
+
+func TestGetRemoteIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		trusted []string
+		peer    string
+		xff     string
+		expect  string
+	}{
+		{"no_header", nil, "1.1.1.1:1234", "", "1.1.1.1"},
+		{"untrusted_config_first_header_ip", nil, "1.1.1.1:1234", "2.2.2.2, 3.3.3.3", "2.2.2.2"},
+		{"untrusted_config_skip_invalid", nil, "1.1.1.1:1234", "bad, 3.3.3.3", "3.3.3.3"},
+		{"peer_not_trusted", []string{"10.0.0.0/8"}, "1.1.1.1:1234", "2.2.2.2", "1.1.1.1"},
+		{"skip_trusted_from_right", []string{"10.0.0.0/8"}, "10.0.0.1:1234", "2.2.2.2, 3.3.3.3, 10.0.0.2", "3.3.3.3"},
+		{"all_trusted", []string{"10.0.0.0/8"}, "10.0.0.1:1234", "10.0.0.3, 10.0.0.2", "10.0.0.3"},
+		{"trusted_peer_without_header", []string{"10.0.0.0/8"}, "10.0.0.1:1234", "", "10.0.0.1"},
+		{"ipv6_peer", []string{"::1"}, "[::1]:1234", "2.2.2.2", "2.2.2.2"},
+	}
+	for _, test := range tests {
+		conf := &config.Config{TrustedProxies: test.trusted}
+		var ip string
+		app := iris.New()
+		app.Configure(iris.WithRemoteAddrHeader("X-Forwarded-For"))
+		app.Get("/", func(ctx iris.Context) {
+			Context.SaveConfToIrisContext(ctx, conf)
+			ip = Context.GetRemoteIP(ctx)
+		})
+		if err := app.Build(); err != nil {
+			t.Fatal(err)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = test.peer
+		if test.xff != "" {
+			req.Header.Set("X-Forwarded-For", test.xff)
+		}
+		app.ServeHTTP(httptest.NewRecorder(), req)
+		if ip != test.expect {
+			t.Fatal("解析的客户端ip和预期不符", test.name, ip)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+/*
+-------------------------------------------------
+   Author :       zlyuancn
+   date：         2026/10/19
+   Description :
+-------------------------------------------------
+*/
+
+package validator
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	// 手机号, 允许带上 86 或 +86 前缀
+	mobileRegex = regexp.MustCompile(`^(?:\+?86)?1[3-9]\d{9}$`)
+	// 18位身份证号
+	idCardRegex = regexp.MustCompile(`^\d{17}[\dXx]$`)
+	// 车牌号, 包含普通车牌和8位的新能源车牌
+	plateRegex = regexp.MustCompile(`^[京津沪渝冀豫云辽黑湘皖鲁新苏浙赣鄂桂甘晋蒙陕吉闽贵粤青藏川宁琼使领][A-HJ-NP-Z][A-HJ-NP-Z0-9]{4,5}[A-HJ-NP-Z0-9挂学警港澳]$`)
+)
+
+// 身份证号校验码计算的加权因子
+var idCardWeights = [17]int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+
+// 身份证号校验码
+const idCardCheckCodes = "10X98765432"
+
+// 注册内置校验规则
+func (v *Validator) registerBuiltinRules() {
+	_ = v.validate.RegisterValidation("required_if_top", validateRequiredIfTop, true)
+	_ = v.RegisterTranslation("required_if_top", "{0}为必填字段")
+	_ = v.RegisterTranslation("required_if", "{0}为必填字段")
+	_ = v.RegisterTranslation("unique", "{0}不能包含重复的值")
+
+	_ = v.RegisterValidationRule("mobile", validateMobile, "{0}必须是有效的手机号")
+	_ = v.RegisterValidationRule("idcard", validateIDCard, "{0}必须是有效的身份证号")
+	_ = v.RegisterValidationRule("plate", validatePlate, "{0}必须是有效的车牌号")
+}
+
+// 当顶层结构体中指定路径的字段等于指定值时, 当前字段为必填
+//
+// 和 required_if 不同的是字段路径从顶层结构体开始查找, 用于嵌套结构体中的字段依赖外层字段的场景.
+// 示例: bind:"required_if_top=Order.Type 1 Order.Status 2", 所有条件都满足时当前字段必填
+func validateRequiredIfTop(fl validator.FieldLevel) bool {
+	params := strings.Fields(fl.Param())
+	if len(params)%2 != 0 {
+		panic(fmt.Sprintf("Bad param number for required_if_top %s", fl.FieldName()))
+	}
+	for i := 0; i < len(params); i += 2 {
+		field, kind, _, found := fl.GetStructFieldOKAdvanced2(fl.Top(), params[i])
+		if !found || !fieldEquals(field, kind, params[i+1]) {
+			return true
+		}
+	}
+	return hasValue(fl.Field())
+}
+
+// 检查字段是否等于指定值, 切片, map, 数组比较的是长度
+func fieldEquals(field reflect.Value, kind reflect.Kind, value string) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 0, 64)
+		return err == nil && field.Int() == i
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(value, 0, 64)
+		return err == nil && field.Uint() == u
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		return err == nil && field.Float() == f
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		return err == nil && field.Bool() == b
+	case reflect.Slice, reflect.Map, reflect.Array:
+		i, err := strconv.ParseInt(value, 0, 64)
+		return err == nil && int64(field.Len()) == i
+	}
+	return field.String() == value
+}
+
+// 检查字段是否有值
+func hasValue(field reflect.Value) bool {
+	switch field.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Ptr, reflect.Interface, reflect.Chan, reflect.Func:
+		return !field.IsNil()
+	}
+	return field.IsValid() && !field.IsZero()
+}
+
+// 手机号
+func validateMobile(fl validator.FieldLevel) bool {
+	return fl.Field().Kind() == reflect.String && mobileRegex.MatchString(fl.Field().String())
+}
+
+// 18位身份证号, 会检查出生日期和校验码
+func validateIDCard(fl validator.FieldLevel) bool {
+	if fl.Field().Kind() != reflect.String {
+		return false
+	}
+	id := fl.Field().String()
+	if !idCardRegex.MatchString(id) {
+		return false
+	}
+
+	birthday, err := time.ParseInLocation("20060102", id[6:14], time.Local)
+	if err != nil || birthday.After(time.Now()) {
+		return false
+	}
+
+	sum := 0
+	for i, w := range idCardWeights {
+		sum += int(id[i]-'0') * w
+	}
+	return strings.ToUpper(id[17:]) == string(idCardCheckCodes[sum%11])
+}
+
+// 车牌号
+func validatePlate(fl validator.FieldLevel) bool {
+	return fl.Field().Kind() == reflect.String && plateRegex.MatchString(fl.Field().String())
+}
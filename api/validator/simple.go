@@ -18,9 +18,19 @@ func init() {
 	defaultValidator = NewValidator()
 }
 
-// 注册校验规则
-func RegisterValidationRule(tag string, fn validator.Func) error {
-	return defaultValidator.RegisterValidationRule(tag, fn)
+// 注册校验规则, translation 为校验失败时的错误描述模板, {0}为字段名, {1}为规则参数
+func RegisterValidationRule(tag string, fn validator.Func, translation ...string) error {
+	return defaultValidator.RegisterValidationRule(tag, fn, translation...)
+}
+
+// 注册校验失败时的错误描述模板
+func RegisterTranslation(tag string, translation string) error {
+	return defaultValidator.RegisterTranslation(tag, translation)
+}
+
+// 注册结构体校验器
+func RegisterStructRule(fn validator.StructLevelFunc, types ...interface{}) {
+	defaultValidator.RegisterStructRule(fn, types...)
 }
 
 // 校验struct
@@ -23,7 +23,15 @@ import (
 // 校验器
 type IValidator interface {
 	// 注册校验规则
-	RegisterValidationRule(tag string, fn validator.Func) error
+	//
+	// translation 为校验失败时的错误描述模板, {0}为字段名, {1}为规则参数, 例如 {0}必须是有效的手机号
+	RegisterValidationRule(tag string, fn validator.Func, translation ...string) error
+	// 注册校验失败时的错误描述模板, 可以覆盖已有规则的描述, 或者为结构体校验器上报的tag设置描述
+	RegisterTranslation(tag string, translation string) error
+	// 注册结构体校验器, 用于校验跨字段的业务约束, types 为需要校验的结构体实例
+	//
+	// 在 fn 中通过 sl.ReportError 上报错误, 上报的tag需要通过 RegisterTranslation 设置描述
+	RegisterStructRule(fn validator.StructLevelFunc, types ...interface{})
 	// 校验一个结构体
 	Valid(a interface{}) error
 	// 校验一个字段
@@ -43,13 +51,15 @@ func NewValidator() IValidator {
 	validate.SetTagName("bind")
 	_ = zh_translations.RegisterDefaultTranslations(validate, vt)
 
-	_ = validate.RegisterValidation("regex", validateRegex)
-	_ = validate.RegisterValidation("time", validateTime)
-	_ = validate.RegisterValidation("date", validateDate)
-	return &Validator{
+	v := &Validator{
 		validateTrans: vt,
 		validate:      validate,
 	}
+	_ = v.RegisterValidationRule("regex", validateRegex, "{0}格式不正确")
+	_ = v.RegisterValidationRule("time", validateTime, "{0}必须是有效的时间")
+	_ = v.RegisterValidationRule("date", validateDate, "{0}必须是有效的日期")
+	v.registerBuiltinRules()
+	return v
 }
 
 // 正则匹配
@@ -84,8 +94,34 @@ func validateDate(f validator.FieldLevel) bool {
 }
 
 // 注册校验规则
-func (v *Validator) RegisterValidationRule(tag string, fn validator.Func) error {
-	return v.validate.RegisterValidation(tag, fn)
+func (v *Validator) RegisterValidationRule(tag string, fn validator.Func, translation ...string) error {
+	if err := v.validate.RegisterValidation(tag, fn); err != nil {
+		return err
+	}
+	if len(translation) > 0 {
+		return v.RegisterTranslation(tag, translation[0])
+	}
+	return nil
+}
+
+// 注册校验失败时的错误描述模板
+func (v *Validator) RegisterTranslation(tag string, translation string) error {
+	registerFn := func(trans ut.Translator) error {
+		return trans.Add(tag, translation, true)
+	}
+	translationFn := func(trans ut.Translator, fe validator.FieldError) string {
+		t, err := trans.T(fe.Tag(), fe.Field(), fe.Param())
+		if err != nil {
+			return fe.Error()
+		}
+		return t
+	}
+	return v.validate.RegisterTranslation(tag, v.validateTrans, registerFn, translationFn)
+}
+
+// 注册结构体校验器
+func (v *Validator) RegisterStructRule(fn validator.StructLevelFunc, types ...interface{}) {
+	v.validate.RegisterStructValidation(fn, types...)
 }
 
 // 校验struct
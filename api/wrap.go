@@ -11,7 +11,9 @@ package api
 import (
 	"fmt"
 	"reflect"
+	"sync"
 
+	jsoniter "github.com/json-iterator/go"
 	"github.com/zly-app/zapp/logger"
 	"go.uber.org/zap"
 
@@ -20,6 +22,7 @@ import (
 
 	app_config "github.com/zly-app/zapp/config"
 
+	"github.com/zly-app/service/api/config"
 	"github.com/zly-app/service/api/utils"
 )
 
@@ -46,11 +49,11 @@ var defaultWriteResponseFunc WriteResponseFunc = func(ctx *Context, code int, me
 	case []byte: // 直接写入
 		_, _ = ctx.Write(v)
 	default:
-		_, _ = ctx.JSON(Response{
-			ErrCode: code,
-			ErrMsg:  message,
-			Data:    data,
-		})
+		var traceID string
+		if code != OK.Code {
+			traceID = utils.Context.GetTraceID(ctx.Context())
+		}
+		_, _ = ctx.JSON(makeResponse(ctx.conf, code, message, data, traceID))
 	}
 }
 
@@ -58,6 +61,48 @@ type Response struct {
 	ErrCode int         `json:"err_code"`
 	ErrMsg  string      `json:"err_msg"`
 	Data    interface{} `json:"data,omitempty"`
+	TraceID string      `json:"trace_id,omitempty"` // 链路id, 只有出现错误时才会返回
+}
+
+// 自定义字段名的响应结构
+type responseEnvelope struct {
+	conf *config.Config
+	Response
+}
+
+func (r responseEnvelope) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, 4)
+	m[r.conf.ResponseErrCodeField] = r.ErrCode
+	m[r.conf.ResponseErrMsgField] = r.ErrMsg
+	if r.Data != nil {
+		m[r.conf.ResponseDataField] = r.Data
+	}
+	if r.TraceID != "" {
+		m[r.conf.ResponseTraceIDField] = r.TraceID
+	}
+	return jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(m)
+}
+
+// 根据配置的字段名生成响应结构
+func makeResponse(conf *config.Config, code int, message string, data interface{}, traceID string) interface{} {
+	rsp := Response{
+		ErrCode: code,
+		ErrMsg:  message,
+		Data:    data,
+		TraceID: traceID,
+	}
+	if conf == nil || conf.IsDefaultResponseFields() {
+		return rsp
+	}
+	return responseEnvelope{conf: conf, Response: rsp}
+}
+
+// 写入错误响应, 会同时设置grpc调用的结果
+func writeErrorResponse(ctx *Context, code int, message string) {
+	if call := getGrpcCall(ctx); call != nil {
+		call.setResult(code, message, nil)
+	}
+	defaultWriteResponseFunc(ctx, code, message, nil)
 }
 
 var typeOfContext = reflect.TypeOf((*Context)(nil))
@@ -65,14 +110,19 @@ var typeOfError = reflect.TypeOf((*error)(nil)).Elem()
 
 // 包装处理程序
 func wrap(handler interface{}, isMiddleware bool) iris.Handler {
+	return wrapWithInjector(defaultInjector, handler, isMiddleware)
+}
+
+// 包装处理程序, 依赖从 injector 中解析
+func wrapWithInjector(injector *Injector, handler interface{}, isMiddleware bool) iris.Handler {
 	if handler == nil {
 		logger.Log.Fatal("handler为nil", zap.String("handler", fmt.Sprintf("%T", handler)))
 	}
 
-	h := newHandler(handler)
+	h := newHandler(handler, injector)
 	fn := h.MakeHandler()
 
-	return func(irisCtx *iris_context.Context) {
+	irisHandler := func(irisCtx *iris_context.Context) {
 		ctx := makeContext(irisCtx) // 构建上下文
 		result := fn(ctx)           // 处理
 
@@ -85,6 +135,19 @@ func wrap(handler interface{}, isMiddleware bool) iris.Handler {
 		WriteToCtx(ctx, result) // 写入结果
 		ctx.StopExecution()     // 停止调用链
 	}
+	wrappedHandlerNames.Store(utils.GetFuncInstanceID(irisHandler), h.name)
+	return irisHandler
+}
+
+// 包装后的处理程序与原始handler名的映射
+var wrappedHandlerNames sync.Map
+
+// 获取处理程序名, 如果是经过包装的处理程序会返回原始handler名
+func getHandlerName(h interface{}) string {
+	if name, ok := wrappedHandlerNames.Load(utils.GetFuncInstanceID(h)); ok {
+		return name.(string)
+	}
+	return iris_context.HandlerName(h)
 }
 
 // 写入数据到ctx
@@ -100,11 +163,14 @@ func WriteToCtx(ctx *Context, result interface{}) {
 			message = err.Error()
 		}
 		ctx.Values().Set("error", err)
-		defaultWriteResponseFunc(ctx, code, message, nil)
+		writeErrorResponse(ctx, code, message)
 		return
 	}
 
 	ctx.Values().Set("result", result)
+	if call := getGrpcCall(ctx); call != nil {
+		call.setResult(OK.Code, OK.Message, result)
+	}
 	switch v := result.(type) {
 	case []byte:
 		ctx.ContentType(iris_context.ContentBinaryHeaderValue)
@@ -122,6 +188,7 @@ func WriteToCtx(ctx *Context, result interface{}) {
 //
 // handler 是一个 func
 //      入参: 第一个入参必须是 *api.Context 类型, 如果有第二个入参必须是 struct, 第二个入参可以是指针, 第二个入参会自动bind
+//            之后的入参是通过 api.Provide 等注册的依赖, 在包装时解析, 第二个入参也可以是依赖
 //      出参: 第一个出参可以是任何类型, 如果有第二个出参必须是error类型
 //      示例:
 //          func (ctx *api.Context) interface{}
@@ -130,6 +197,7 @@ func WriteToCtx(ctx *Context, result interface{}) {
 //          func (ctx *api.Context, req *AnyReqStruct) error
 //          func (ctx *api.Context, req *AnyReqStruct) (interface{}, error)
 //          func (ctx *api.Context, req *AnyReqStruct) (*AnyOutStruct, error)
+//          func (ctx *api.Context, req *AnyReqStruct, db *sql.DB, repo IUserRepo) (*AnyOutStruct, error)
 func Wrap(handler interface{}) iris.Handler {
 	return wrap(handler, false)
 }
@@ -0,0 +1,306 @@
+package cron
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zly-app/zapp/core"
+	"go.uber.org/zap"
+)
+
+// 管理接口认证token的header
+const AdminTokenHeader = "X-Admin-Token"
+
+const (
+	// 查询记录时默认返回的数量
+	defaultAdminQueryN = 10
+	// 查询记录时最多返回的数量
+	maxAdminQueryN = 1000
+)
+
+// 任务信息
+type TaskInfo struct {
+	Name            string           `json:"name"`
+	Enable          bool             `json:"enable"`
+	TriggerType     string           `json:"trigger_type"`
+	Expression      string           `json:"expression"`
+	NextTriggerTime *time.Time       `json:"next_trigger_time"` // 下次触发时间, 为null表示不会再触发
+	LastTriggerTime *time.Time       `json:"last_trigger_time"` // 上次触发时间, 为null表示没有触发过
+	LastRun         *ExecutionRecord `json:"last_run"`          // 最近一次执行记录
+	Running         int              `json:"running"`           // 正在执行的数量
+}
+
+// 服务状态
+type CronStatus struct {
+	State string     `json:"state"`
+	Tasks []TaskInfo `json:"tasks"`
+}
+
+// 获取任务信息
+func GetTaskInfo(c ICron, task ITask) TaskInfo {
+	trigger := task.getTrigger()
+	info := TaskInfo{
+		Name:        task.Name(),
+		Enable:      task.IsEnable(),
+		TriggerType: trigger.TriggerType().String(),
+		Expression:  trigger.Expression(),
+	}
+	if t, ok := c.NextTriggerTime(task.Name()); ok {
+		info.NextTriggerTime = &t
+	}
+	if t, ok := c.LastTriggerTime(task.Name()); ok {
+		info.LastTriggerTime = &t
+	}
+	if records, _ := c.LastRuns(task.Name(), 1); len(records) > 0 {
+		info.LastRun = records[0]
+	}
+	for _, e := range c.RunningExecutions() {
+		if e.TaskName == task.Name() {
+			info.Running++
+		}
+	}
+	return info
+}
+
+// 管理接口的请求
+type adminRequest struct {
+	Name string          `json:"name"`
+	Meta json.RawMessage `json:"meta"` // 手动触发时的元数据, 在handler中通过 ctx.Meta() 获取, 类型为 json.RawMessage
+}
+
+/*
+创建管理接口, token 为空时不认证, 否则请求时需要带上header X-Admin-Token
+
+	GET  /tasks              任务列表, 包含状态, 表达式, 下次和上次触发时间
+	GET  /history?name=&n=   任务最近的执行记录, n默认为10, 最大为1000
+	GET  /running            正在执行的任务
+	GET  /dag_runs?name=&n=  DAG任务最近的运行状态, 包含每个节点的状态, n默认为10, 最大为1000
+	POST /trigger            立即触发任务, body: {"name": "", "meta": {}}
+	POST /enable             启用任务, body: {"name": ""}
+	POST /disable            禁用任务, body: {"name": ""}
+	POST /pause              暂停所有任务
+	POST /resume             恢复所有任务
+
+挂载在其它服务下时可以使用 http.StripPrefix 去掉路径前缀
+*/
+func NewAdminHandler(c ICron, token string) http.Handler {
+	mux := http.NewServeMux()
+	get := func(path string, fn func(r *http.Request) (interface{}, error)) {
+		mux.HandleFunc(path, adminHandlerFunc(http.MethodGet, token, fn))
+	}
+	post := func(path string, fn func(req *adminRequest) (interface{}, error)) {
+		mux.HandleFunc(path, adminHandlerFunc(http.MethodPost, token, func(r *http.Request) (interface{}, error) {
+			req := new(adminRequest)
+			if r.ContentLength != 0 {
+				if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+					return nil, adminError{http.StatusBadRequest, "invalid body: " + err.Error()}
+				}
+			}
+			return fn(req)
+		}))
+	}
+	getTask := func(name string) (ITask, error) {
+		task := c.GetTask(name)
+		if task == nil {
+			return nil, adminError{http.StatusNotFound, ErrTaskNotFound.Error()}
+		}
+		return task, nil
+	}
+
+	get("/tasks", func(r *http.Request) (interface{}, error) {
+		status := CronStatus{State: c.RunState().String()}
+		for _, task := range c.Tasks() {
+			status.Tasks = append(status.Tasks, GetTaskInfo(c, task))
+		}
+		return status, nil
+	})
+	get("/history", func(r *http.Request) (interface{}, error) {
+		name := r.URL.Query().Get("name")
+		if _, err := getTask(name); err != nil {
+			return nil, err
+		}
+		n, err := queryN(r)
+		if err != nil {
+			return nil, err
+		}
+		return c.LastRuns(name, n)
+	})
+	get("/running", func(r *http.Request) (interface{}, error) {
+		return c.RunningExecutions(), nil
+	})
+	get("/dag_runs", func(r *http.Request) (interface{}, error) {
+		n, err := queryN(r)
+		if err != nil {
+			return nil, err
+		}
+		runs, err := c.DAGRuns(r.URL.Query().Get("name"), n)
+		switch err {
+		case ErrTaskNotFound:
+			return nil, adminError{http.StatusNotFound, err.Error()}
+		case ErrNotDAGTask:
+			return nil, adminError{http.StatusBadRequest, err.Error()}
+		}
+		return runs, err
+	})
+
+	post("/trigger", func(req *adminRequest) (interface{}, error) {
+		if _, err := getTask(req.Name); err != nil {
+			return nil, err
+		}
+		var meta interface{}
+		if len(req.Meta) > 0 {
+			meta = req.Meta
+		}
+		return nil, c.TriggerTask(req.Name, meta)
+	})
+	post("/enable", func(req *adminRequest) (interface{}, error) {
+		task, err := getTask(req.Name)
+		if err != nil {
+			return nil, err
+		}
+		c.EnableTask(task, true)
+		return GetTaskInfo(c, task), nil
+	})
+	post("/disable", func(req *adminRequest) (interface{}, error) {
+		task, err := getTask(req.Name)
+		if err != nil {
+			return nil, err
+		}
+		c.EnableTask(task, false)
+		return GetTaskInfo(c, task), nil
+	})
+	post("/pause", func(req *adminRequest) (interface{}, error) {
+		c.Pause()
+		return map[string]string{"state": c.RunState().String()}, nil
+	})
+	post("/resume", func(req *adminRequest) (interface{}, error) {
+		c.Resume()
+		return map[string]string{"state": c.RunState().String()}, nil
+	})
+	return mux
+}
+
+// 获取查询参数中的数量, 默认为10
+func queryN(r *http.Request) (int, error) {
+	s := r.URL.Query().Get("n")
+	if s == "" {
+		return defaultAdminQueryN, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, adminError{http.StatusBadRequest, "invalid n"}
+	}
+	if n > maxAdminQueryN {
+		n = maxAdminQueryN
+	}
+	return n, nil
+}
+
+// 管理接口的错误
+type adminError struct {
+	status  int
+	message string
+}
+
+func (e adminError) Error() string {
+	return e.message
+}
+
+// 包装管理接口, 检查请求方法和token, 以json格式返回结果
+func adminHandlerFunc(method, token string, fn func(r *http.Request) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var result interface{}
+		var err error
+		switch {
+		case r.Method != method:
+			err = adminError{http.StatusMethodNotAllowed, "method not allowed"}
+		case token != "" && r.Header.Get(AdminTokenHeader) == "":
+			err = adminError{http.StatusUnauthorized, "authorization required"}
+		case token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get(AdminTokenHeader)), []byte(token)) != 1:
+			err = adminError{http.StatusForbidden, "authorization error"}
+		default:
+			result, err = fn(r)
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if err != nil {
+			status := http.StatusInternalServerError
+			if e, ok := err.(adminError); ok {
+				status = e.status
+			}
+			w.WriteHeader(status)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		if result == nil {
+			result = map[string]bool{"ok": true}
+		}
+		_ = json.NewEncoder(w).Encode(result)
+	}
+}
+
+// 启动独立bind的管理接口
+func (c *CronService) startAdmin() error {
+	if c.conf.AdminBind == "" {
+		return nil
+	}
+
+	if err := checkAdminBind(c.conf.AdminBind, c.conf.AdminToken); err != nil {
+		c.app.Fatal("cron服务的管理接口配置错误", zap.Error(err))
+	}
+
+	prefix := strings.TrimSuffix(c.conf.AdminPath, "/")
+	handler := http.Handler(NewAdminHandler(c, c.conf.AdminToken))
+	if prefix != "" {
+		handler = http.StripPrefix(prefix, handler)
+	}
+
+	c.app.Info("正在启动cron服务的管理接口", zap.String("bind", c.conf.AdminBind), zap.String("path", prefix))
+	lis, err := net.Listen("tcp", c.conf.AdminBind)
+	if err != nil {
+		return fmt.Errorf("cron服务的管理接口监听失败: %v", err)
+	}
+	c.adminServer = &http.Server{Addr: c.conf.AdminBind, Handler: handler}
+	go func(server *http.Server, app core.IApp) {
+		if err := server.Serve(lis); err != nil && err != http.ErrServerClosed {
+			app.Error("cron服务的管理接口运行失败", zap.Error(err))
+		}
+	}(c.adminServer, c.app)
+	return nil
+}
+
+// 检查管理接口的bind地址, 没有设置token时只允许绑定在回环地址上
+func checkAdminBind(bind, token string) error {
+	if token != "" {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(bind)
+	if err != nil {
+		return fmt.Errorf("invalid admin bind %q: %v", bind, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("admin token is required when admin bind %q is not a loopback address", bind)
+}
+
+// 关闭管理接口
+func (c *CronService) closeAdmin() {
+	if c.adminServer == nil {
+		return
+	}
+	if err := c.adminServer.Shutdown(context.Background()); err != nil {
+		c.app.Error("cron服务的管理接口关闭失败", zap.Error(err))
+	}
+	c.adminServer = nil
+}
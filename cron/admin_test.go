@@ -0,0 +1,134 @@
+package cron
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zly-app/zapp"
+)
+
+var testAppOnce sync.Once
+
+// 获取测试使用的cron服务, 一个进程中只能创建一次app
+func getTestCronService(t *testing.T) *CronService {
+	testAppOnce.Do(func() {
+		zapp.NewApp("test", WithService())
+	})
+	c, ok := GetService().(*CronService)
+	if !ok {
+		t.Fatal("获取cron服务失败")
+	}
+	return c
+}
+
+func TestAdminHandler(t *testing.T) {
+	c := getTestCronService(t)
+
+	metaChan := make(chan interface{}, 1)
+	c.AddTask(NewTask("admin_test", "0 0 1 1 *", true, func(ctx IContext) (err error) {
+		metaChan <- ctx.Meta()
+		return nil
+	}))
+
+	handler := NewAdminHandler(c, "tk")
+	do := func(method, path, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set(AdminTokenHeader, token)
+		}
+		rsp := httptest.NewRecorder()
+		handler.ServeHTTP(rsp, req)
+		return rsp
+	}
+
+	if rsp := do(http.MethodGet, "/tasks", "", ""); rsp.Code != http.StatusUnauthorized {
+		t.Fatal("没有token时应该返回401", rsp.Code)
+	}
+	if rsp := do(http.MethodGet, "/tasks", "", "x"); rsp.Code != http.StatusForbidden {
+		t.Fatal("token错误时应该返回403", rsp.Code)
+	}
+
+	rsp := do(http.MethodGet, "/tasks", "", "tk")
+	var status CronStatus
+	if err := json.Unmarshal(rsp.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Tasks) != 1 || status.Tasks[0].Name != "admin_test" || status.Tasks[0].Expression != "0 0 1 1 *" {
+		t.Fatal("任务列表和预期不符", rsp.Body.String())
+	}
+
+	if rsp = do(http.MethodPost, "/trigger", `{"name":"admin_test","meta":{"a":1}}`, "tk"); rsp.Code != http.StatusOK {
+		t.Fatal("触发任务失败", rsp.Body.String())
+	}
+	select {
+	case meta := <-metaChan:
+		if string(meta.(json.RawMessage)) != `{"a":1}` {
+			t.Fatal("元数据和预期不符", meta)
+		}
+	case <-time.After(time.Second * 3):
+		t.Fatal("任务没有被触发")
+	}
+
+	if rsp = do(http.MethodPost, "/trigger", `{"name":"unknown"}`, "tk"); rsp.Code != http.StatusNotFound {
+		t.Fatal("任务不存在时应该返回404", rsp.Code)
+	}
+	if rsp = do(http.MethodPost, "/disable", `{"name":"admin_test"}`, "tk"); rsp.Code != http.StatusOK || c.GetTask("admin_test").IsEnable() {
+		t.Fatal("禁用任务失败", rsp.Body.String())
+	}
+	if rsp = do(http.MethodGet, "/disable", "", "tk"); rsp.Code != http.StatusMethodNotAllowed {
+		t.Fatal("请求方法错误时应该返回405", rsp.Code)
+	}
+	for _, n := range []string{"0", "-1", "x"} {
+		if rsp = do(http.MethodGet, "/history?name=admin_test&n="+n, "", "tk"); rsp.Code != http.StatusBadRequest {
+			t.Fatal("n无效时应该返回400", n, rsp.Code)
+		}
+	}
+	if rsp = do(http.MethodGet, "/history?name=admin_test&n=100000", "", "tk"); rsp.Code != http.StatusOK {
+		t.Fatal("n超出最大值时应该被限制", rsp.Code)
+	}
+}
+
+func TestCheckAdminBind(t *testing.T) {
+	tests := []struct {
+		bind   string
+		token  string
+		hasErr bool
+	}{
+		{":8090", "tk", false},
+		{":8090", "", true},
+		{"0.0.0.0:8090", "", true},
+		{"10.0.0.1:8090", "", true},
+		{"127.0.0.1:8090", "", false},
+		{"localhost:8090", "", false},
+		{"[::1]:8090", "", false},
+		{"8090", "", true},
+	}
+	for _, test := range tests {
+		if err := checkAdminBind(test.bind, test.token); (err != nil) != test.hasErr {
+			t.Fatal("bind地址检查结果和预期不符", test.bind, test.token, err)
+		}
+	}
+}
+
+func TestStartAdminListenError(t *testing.T) {
+	c := getTestCronService(t)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+
+	bind := c.conf.AdminBind
+	c.conf.AdminBind = lis.Addr().String()
+	defer func() { c.conf.AdminBind = bind }()
+	if err := c.startAdmin(); err == nil {
+		t.Fatal("端口被占用时应该返回错误")
+	}
+}
@@ -9,7 +9,11 @@
 package cron
 
 import (
+	"fmt"
 	"runtime"
+	"time"
+
+	"github.com/zlyuancn/zutils"
 )
 
 const (
@@ -17,18 +21,46 @@ const (
 	defaultThreadCount = -1
 	// 默认最大任务队列大小
 	defaultMaxTaskQueueSize = 10000
+	// 默认锁租约时间
+	defaultLockLeaseMs = 60000
+	// 默认历史记录存储
+	defaultHistoryStore = MemoryHistoryStore
+	// 默认每个任务保存的历史记录数
+	defaultHistorySize = 100
+	// 默认管理接口路径前缀
+	defaultAdminPath = "/cron"
+	// 默认关闭服务时等待任务结束的宽限时间
+	defaultShutdownGracePeriodMs = 10000
+	// 默认取消任务的ctx后等待任务结束的时间
+	defaultShutdownWaitTimeoutMs = 5000
 )
 
 // 任务配置
 type TaskFileConfig struct {
-	Name                      string // 任务名
-	Expression                string // cron表达式, https://en.wikipedia.org/wiki/Cron
-	IsOnceTrigger             bool   // 是否为一次性触发, 如果设为true, 则 Expression 的格式为 YYYY-MM-dd hh:mm:ss
-	Disable                   bool   // 是否禁用
-	RetryCount                int64  // 任务失败重试次数, 0表示不重试
-	RetrySleepMs              int64  // 失败重试等待时间, 单位秒, 0表示不等待
-	MaxConcurrentExecuteCount int64  // 最大并发执行任务数, 如果为-1则不限制. 表示在执行过程中又被调度器触发执行时, 能同时运行同一个任务的数量. 默认1
-	TimeoutMs                 int64  // 超时时间, 单位秒, 0表示永不超时
+	Name                      string   `yaml:"Name"`                      // 任务名
+	TriggerType               string   `yaml:"TriggerType"`               // 触发器类型, 可选 cron, once, fixed_delay, fixed_rate, union, intersection, 默认为 cron
+	Expression                string   `yaml:"Expression"`                // cron表达式, https://en.wikipedia.org/wiki/Cron. 触发器类型为 fixed_delay, fixed_rate 时为间隔时间, 例如 30s
+	Expressions               []string `yaml:"Expressions"`               // 触发器类型为 union, intersection 时组合的cron表达式列表
+	ExpressionFormat          string   `yaml:"ExpressionFormat"`          // cron表达式格式, 可选 standard, seconds, 默认自动识别, 5个字段为标准格式, 6个字段时第一个字段为秒
+	IsOnceTrigger             bool     `yaml:"IsOnceTrigger"`             // 是否为一次性触发, 如果设为true, 则 Expression 的格式为 YYYY-MM-dd hh:mm:ss, 等同于触发器类型为 once
+	StartTime                 string   `yaml:"StartTime"`                 // 触发器类型为 fixed_rate 时的开始时间, 格式为 YYYY-MM-dd hh:mm:ss, 默认以unix时间0为起点
+	EndTime                   string   `yaml:"EndTime"`                   // 触发器类型为 fixed_rate 时的结束时间, 格式为 YYYY-MM-dd hh:mm:ss, 默认不结束
+	ExcludeDates              []string `yaml:"ExcludeDates"`              // 排除的日期列表, 格式为 YYYY-MM-dd, 这些日期不会触发
+	ExcludeDatesFile          string   `yaml:"ExcludeDatesFile"`          // 排除的日期文件, 每行一个日期, 格式为 YYYY-MM-dd, 忽略空行和#开头的注释
+	Timezone                  string   `yaml:"Timezone"`                  // 时区, 例如 Asia/Shanghai, 默认为本地时区. cron表达式中有 CRON_TZ= 前缀时以表达式为准
+	Disable                   bool     `yaml:"Disable"`                   // 是否禁用
+	RetryCount                int64    `yaml:"RetryCount"`                // 任务失败重试次数, 0表示不重试, -1表示不限制, 此时必须设置 RetryMaxElapsedMs
+	RetrySleepMs              int64    `yaml:"RetrySleepMs"`              // 第一次重试前的等待时间, 单位毫秒, 0表示不等待
+	RetryMultiplier           float64  `yaml:"RetryMultiplier"`           // 每次重试后等待时间的倍数, 大于1时为指数退避, 默认为固定间隔
+	RetryMaxSleepMs           int64    `yaml:"RetryMaxSleepMs"`           // 最大重试等待时间, 单位毫秒, 0表示不限制
+	RetryJitter               float64  `yaml:"RetryJitter"`               // 重试等待时间的随机抖动比例, 取值范围为 [0, 1], 0表示不抖动
+	RetryMaxElapsedMs         int64    `yaml:"RetryMaxElapsedMs"`         // 从第一次执行开始的最大总时间, 单位毫秒, 等待后会超过这个时间时不再重试, 0表示不限制
+	MaxConcurrentExecuteCount int64    `yaml:"MaxConcurrentExecuteCount"` // 最大并发执行任务数, 如果为-1则不限制. 表示在执行过程中又被调度器触发执行时, 能同时运行同一个任务的数量. 默认1
+	TimeoutMs                 int64    `yaml:"TimeoutMs"`                 // 每次执行的超时时间, 单位秒, 0表示永不超时
+	MisfirePolicy             string   `yaml:"MisfirePolicy"`             // 错过触发的处理策略, 可选 skip, fire_once, fire_all, 默认为 skip
+	MisfireThresholdMs        int64    `yaml:"MisfireThresholdMs"`        // 延迟多久才算错过触发, 单位毫秒, 延迟不超过这个时间的触发会正常补偿触发, 默认为0
+	MisfireMaxCount           int      `yaml:"MisfireMaxCount"`           // 策略为 fire_all 时最多补偿的次数, 0表示不限制
+	FailurePolicy             string   `yaml:"FailurePolicy"`             // DAG任务节点失败时的处理策略, 可选 skip_downstream, fail_fast, continue, 默认为 skip_downstream
 }
 
 // CronService配置
@@ -46,14 +78,65 @@ type Config struct {
 		  启动时创建一个指定大小的任务队列, 触发产生的任务会放入这个队列, 队列已满时新触发的任务会被抛弃
 	*/
 	MaxTaskQueueSize int
+	/*
+		分布式锁提供者, 默认为空表示不使用锁
+		  多副本部署时用于保证同一个任务的同一个触发时间只有一个副本执行, 手动触发不会加锁
+		  memory: 内存锁, 只在进程内生效, 一般用于测试
+		  file: 文件锁, 所有副本需要能访问 LockDir
+		  通过 SetLockProvider 设置了自定义锁提供者时忽略这个配置
+	*/
+	LockProvider string
+	// 文件锁目录, 默认为系统临时目录下的 zapp_cron_lock
+	LockDir string
+	// 锁租约时间, 单位毫秒, 默认60000. 执行期间会自动续约, 执行完毕后锁在租约到期后失效
+	LockLeaseMs int64
+	// 锁持有者标识, 默认为 hostname:pid
+	LockHolder string
+	/*
+		执行历史记录存储, 默认为 memory
+		  memory: 内存历史记录, 重启后丢失
+		  file: 文件历史记录, 每个任务的记录保存在 HistoryDir 下的一个jsonl文件中
+		  none: 不保存历史记录
+		  通过 SetHistoryStore 设置了自定义历史记录存储时忽略这个配置
+	*/
+	HistoryStore string
+	// 历史记录目录, 默认为系统临时目录下的 zapp_cron_history
+	HistoryDir string
+	// 每个任务保存的历史记录数, 默认100
+	HistorySize int
+	// 管理接口独立的bind地址, 例如 :8090, 为空时不启动. 也可以通过 NewAdminHandler 挂载在其它http服务下
+	//
+	// 没有设置 AdminToken 时只能绑定在回环地址上, 例如 127.0.0.1:8090
+	AdminBind string
+	// 管理接口路径前缀, 默认为 /cron
+	AdminPath string
+	// 管理接口认证token, 请求时需要带上header X-Admin-Token, 为空时不认证, 此时 AdminBind 只能是回环地址
+	AdminToken string
+	/*
+		关闭服务时等待任务结束的宽限时间, 单位毫秒, 默认10000
+		  关闭服务时不再触发新的任务, 正在等待重试的任务会停止等待
+		  超过宽限时间后会取消正在执行的任务的ctx
+	*/
+	ShutdownGracePeriodMs int64
+	// 取消任务的ctx后继续等待任务结束的时间, 单位毫秒, 默认5000. 超时后仍未结束的任务会输出任务名和已执行时间
+	ShutdownWaitTimeoutMs int64
 	// 任务列表
 	Tasks []TaskFileConfig
+	// 从配置中心观察任务配置, 配置内容为任务列表的yaml, 变更后立即生效, 会完整替换 Tasks
+	TasksWatchGroup string
+	TasksWatchKey   string
 }
 
 func newConfig() *Config {
 	return &Config{
-		ThreadCount:      defaultThreadCount,
-		MaxTaskQueueSize: defaultMaxTaskQueueSize,
+		ThreadCount:           defaultThreadCount,
+		MaxTaskQueueSize:      defaultMaxTaskQueueSize,
+		LockLeaseMs:           defaultLockLeaseMs,
+		HistoryStore:          defaultHistoryStore,
+		HistorySize:           defaultHistorySize,
+		AdminPath:             defaultAdminPath,
+		ShutdownGracePeriodMs: defaultShutdownGracePeriodMs,
+		ShutdownWaitTimeoutMs: defaultShutdownWaitTimeoutMs,
 	}
 }
 
@@ -64,4 +147,119 @@ func (c *Config) check() {
 	if c.MaxTaskQueueSize <= 0 {
 		c.MaxTaskQueueSize = defaultMaxTaskQueueSize
 	}
+	if c.LockLeaseMs <= 0 {
+		c.LockLeaseMs = defaultLockLeaseMs
+	}
+	if c.HistoryStore == "" {
+		c.HistoryStore = defaultHistoryStore
+	}
+	if c.HistorySize <= 0 {
+		c.HistorySize = defaultHistorySize
+	}
+	if c.AdminPath == "" {
+		c.AdminPath = defaultAdminPath
+	}
+	if c.ShutdownGracePeriodMs < 0 {
+		c.ShutdownGracePeriodMs = defaultShutdownGracePeriodMs
+	}
+	if c.ShutdownWaitTimeoutMs < 0 {
+		c.ShutdownWaitTimeoutMs = defaultShutdownWaitTimeoutMs
+	}
+	if c.LockHolder == "" {
+		c.LockHolder = defaultLockHolder()
+	}
+}
+
+// 根据配置创建触发器
+func (t *TaskFileConfig) makeTrigger() (ITrigger, error) {
+	loc := time.Local
+	if t.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(t.Timezone); err != nil {
+			return nil, fmt.Errorf("invalid timezone: %v", err)
+		}
+	}
+
+	triggerType := CronTriggerType
+	if t.IsOnceTrigger {
+		triggerType = OnceTriggerType
+	}
+	if t.TriggerType != "" {
+		var err error
+		if triggerType, err = ParseTriggerType(t.TriggerType); err != nil {
+			return nil, err
+		}
+	}
+
+	trigger, err := t.makeBaseTrigger(triggerType, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(t.ExcludeDates) == 0 && t.ExcludeDatesFile == "" {
+		return trigger, nil
+	}
+	dates := t.ExcludeDates
+	if t.ExcludeDatesFile != "" {
+		fileDates, err := readDateFile(t.ExcludeDatesFile)
+		if err != nil {
+			return nil, fmt.Errorf("load exclude dates file failed: %v", err)
+		}
+		dates = append(append([]string{}, dates...), fileDates...)
+	}
+	calendar, err := NewDateCalendar(dates, loc)
+	if err != nil {
+		return nil, err
+	}
+	return NewExcludeTrigger(trigger, calendar)
+}
+
+func (t *TaskFileConfig) makeBaseTrigger(triggerType TriggerType, loc *time.Location) (ITrigger, error) {
+	switch triggerType {
+	case CronTriggerType:
+		return NewCronTrigger(t.Expression, WithExpressionFormat(t.ExpressionFormat), WithTimezone(loc))
+	case OnceTriggerType:
+		tt, err := zutils.Time(loc).TextToTimeOfLayout(t.Expression, zutils.T.Layout)
+		if err != nil {
+			return nil, err
+		}
+		return NewOnceTrigger(tt), nil
+	case FixedDelayTriggerType:
+		delay, err := time.ParseDuration(t.Expression)
+		if err != nil {
+			return nil, err
+		}
+		return NewFixedDelayTrigger(delay)
+	case FixedRateTriggerType:
+		interval, err := time.ParseDuration(t.Expression)
+		if err != nil {
+			return nil, err
+		}
+		var start, end time.Time
+		if t.StartTime != "" {
+			if start, err = zutils.Time(loc).TextToTimeOfLayout(t.StartTime, zutils.T.Layout); err != nil {
+				return nil, fmt.Errorf("invalid start time: %v", err)
+			}
+		}
+		if t.EndTime != "" {
+			if end, err = zutils.Time(loc).TextToTimeOfLayout(t.EndTime, zutils.T.Layout); err != nil {
+				return nil, fmt.Errorf("invalid end time: %v", err)
+			}
+		}
+		return NewFixedRateTrigger(interval, start, end)
+	case UnionTriggerType, IntersectionTriggerType:
+		triggers := make([]ITrigger, len(t.Expressions))
+		for i, expression := range t.Expressions {
+			trigger, err := NewCronTrigger(expression, WithExpressionFormat(t.ExpressionFormat), WithTimezone(loc))
+			if err != nil {
+				return nil, fmt.Errorf("Expressions[%d]: %v", i, err)
+			}
+			triggers[i] = trigger
+		}
+		if triggerType == UnionTriggerType {
+			return NewUnionTrigger(triggers...)
+		}
+		return NewIntersectionTrigger(triggers...)
+	}
+	return nil, fmt.Errorf("%s trigger can not be created from config", triggerType)
 }
@@ -30,12 +30,22 @@ type Context struct {
 	context.Context
 }
 
+type metaKey struct{}
+
+// 设置执行上下文的初始元数据
+func withMeta(ctx context.Context, meta interface{}) context.Context {
+	if meta == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, metaKey{}, meta)
+}
+
 func newContext(ctx context.Context, task ITask) IContext {
 	log := logger.Log.NewTraceLogger(ctx, zap.String("task_name", task.Name()))
 	return &Context{
 		task:    task,
 		handler: task.Handler(),
-		meta:    nil,
+		meta:    ctx.Value(metaKey{}),
 		ILogger: log,
 		Context: ctx,
 	}
@@ -11,6 +11,7 @@ package cron
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"sort"
 	"sync"
 	"sync/atomic"
@@ -20,7 +21,6 @@ import (
 	"github.com/zly-app/zapp/core"
 	"github.com/zly-app/zapp/logger"
 	"github.com/zly-app/zapp/pkg/utils"
-	"github.com/zlyuancn/zutils"
 	"go.uber.org/zap"
 )
 
@@ -31,9 +31,9 @@ type ICron interface {
 	String() string
 
 	// 启动
-	Start()
+	Start() error
 	// 结束
-	Stop()
+	Close() error
 	// 暂停所有任务
 	Pause()
 	// 恢复所有任务
@@ -51,8 +51,25 @@ type ICron interface {
 	Tasks() []ITask
 	// 获取任务, 如果不存在返回nil
 	GetTask(name string) ITask
+
+	// 获取任务最近的 n 条执行记录, 按开始时间倒序, n<=0 时返回所有保存的记录. 不保存历史记录时返回nil
+	LastRuns(name string, n int) ([]*ExecutionRecord, error)
+	// 获取任务的下次触发时间, 任务不存在, 未启用或没有下一次时返回 false
+	NextTriggerTime(name string) (time.Time, bool)
+	// 获取任务的上次触发时间, 没有触发过时返回 false
+	LastTriggerTime(name string) (time.Time, bool)
+	// 立即触发任务, 不会等待执行结束. meta 会设置为执行上下文的元数据
+	TriggerTask(name string, meta interface{}) error
+	// 获取正在执行的任务列表, 按开始时间排序
+	RunningExecutions() []RunningExecution
+	// 获取DAG任务最近的 n 次运行状态, 按开始时间倒序, n<=0 时返回所有保存的运行状态
+	DAGRuns(name string, n int) ([]DAGRun, error)
+	// 重新加载任务配置, 新的配置会完整替换原来的配置, 正在执行的任务不受影响
+	ReloadTaskFileConfigs(tasks []TaskFileConfig)
 }
 
+var _ ICron = (*CronService)(nil)
+
 // 运行状态
 type RunState int32
 
@@ -94,17 +111,33 @@ func (r RunState) String() string {
 const heapsCount = 64 // 任务堆数量
 
 type CronService struct {
-	app core.IApp
+	app  core.IApp
+	conf *Config
 
 	tasks           map[string]ITask // 任务
+	originTasks     map[string]ITask // 代码中添加的原始任务, 配置变更时根据原始任务重建
 	taskFileConfigs map[string]*TaskFileConfig
-	heaps           []ITaskHeap // 任务堆列表, 根据触发时间取模将任务分配到不同的任务堆
+	lastFireTimes   map[string]time.Time // 任务的上次触发时间, 用于补偿错过的触发
+	heaps           []ITaskHeap          // 任务堆列表, 根据触发时间取模将任务分配到不同的任务堆
 
 	runState  RunState
 	closeChan chan struct{}
 
 	gpool core.IGPool // 协程池
 
+	running    map[*RunningExecution]struct{} // 正在执行的任务
+	dispatched int                            // 已分发还未结束的任务数
+	idleCond   *sync.Cond                     // 已分发的任务全部结束时通知
+	runCtx     context.Context                // 任务执行的基础ctx, 关闭服务超过宽限时间后取消
+	runCancel  context.CancelFunc
+	stopChan   chan struct{} // 服务关闭时关闭, 用于停止触发新的任务和停止等待重试
+	runningMx  sync.Mutex    // 锁 running, dispatched, runCtx, runCancel, stopChan
+
+	lockProvider ILockProvider // 分布式锁提供者, 为nil表示不使用锁
+	historyStore IHistoryStore // 历史记录存储, 为nil表示不保存历史记录
+
+	adminServer *http.Server // 独立bind的管理接口
+
 	mx sync.Mutex // 锁 tasks, heaps
 }
 
@@ -120,11 +153,17 @@ func NewCronService(app core.IApp) core.IService {
 	conf.check()
 
 	c := &CronService{
-		app:       app,
-		tasks:     make(map[string]ITask),
-		runState:  StoppedState,
-		closeChan: make(chan struct{}),
+		app:           app,
+		conf:          conf,
+		tasks:         make(map[string]ITask),
+		originTasks:   make(map[string]ITask),
+		lastFireTimes: make(map[string]time.Time),
+		running:       make(map[*RunningExecution]struct{}),
+		runState:      StoppedState,
+		closeChan:     make(chan struct{}),
 	}
+	c.idleCond = sync.NewCond(&c.runningMx)
+	c.resetRunContext()
 	c.remakeHeaps()
 	if conf.ThreadCount > 0 {
 		c.gpool = gpool.NewGPool(&gpool.GPoolConfig{
@@ -133,10 +172,20 @@ func NewCronService(app core.IApp) core.IService {
 		})
 	}
 
-	c.taskFileConfigs = make(map[string]*TaskFileConfig, len(conf.Tasks))
-	for _, t := range conf.Tasks {
-		c.taskFileConfigs[t.Name] = &t
+	lockProvider, err := newLockProvider(conf)
+	if err != nil {
+		app.Fatal("创建cron分布式锁提供者失败", zap.Error(err))
 	}
+	c.lockProvider = lockProvider
+
+	historyStore, err := newHistoryStore(conf)
+	if err != nil {
+		app.Fatal("创建cron历史记录存储失败", zap.Error(err))
+	}
+	c.historyStore = historyStore
+
+	c.taskFileConfigs = makeTaskFileConfigs(conf.Tasks)
+	c.watchTaskFileConfigs()
 	return c
 }
 
@@ -159,6 +208,19 @@ func (c *CronService) Start() error {
 	}
 	c.app.Debug("cron服务正在启动")
 
+	if customLockProvider != nil {
+		c.lockProvider = customLockProvider
+	}
+	if customHistoryStore != nil {
+		c.historyStore = customHistoryStore
+	}
+	if err := c.startAdmin(); err != nil {
+		atomic.StoreInt32((*int32)(&c.runState), int32(StoppedState))
+		return err
+	}
+	c.loadLastFireTimes()
+	c.resetRunContext()
+
 	c.resetClock()
 	go c.start()
 
@@ -180,12 +242,21 @@ func (c *CronService) Close() error {
 	c.app.Debug("cron服务正在关闭")
 	c.closeChan <- struct{}{}
 	<-c.closeChan
+	c.shutdown()
+	c.closeAdmin()
 
 	atomic.StoreInt32((*int32)(&c.runState), int32(StoppedState))
 	c.app.Warn("cron服务已关闭")
 	return nil
 }
 
+func (c *CronService) String() string {
+	c.mx.Lock()
+	count := len(c.tasks)
+	c.mx.Unlock()
+	return fmt.Sprintf("cron(state=%s, tasks=%d)", c.RunState(), count)
+}
+
 func (c *CronService) RunState() RunState {
 	return RunState(atomic.LoadInt32((*int32)(&c.runState)))
 }
@@ -201,7 +272,7 @@ func (c *CronService) Pause() {
 }
 
 func (c *CronService) Resume() {
-	if c.RunState() != StartedState {
+	if c.RunState() != PausedState {
 		return
 	}
 
@@ -217,13 +288,14 @@ func (c *CronService) Resume() {
 }
 
 func (c *CronService) AddTask(task ITask) bool {
-	task = c.rebuildTask(task)
 	c.mx.Lock()
 	if _, ok := c.tasks[task.Name()]; ok { // 已存在
 		c.mx.Unlock()
 		return false
 	}
 
+	c.originTasks[task.Name()] = task
+	task = c.rebuildTask(task)
 	c.tasks[task.Name()] = task
 
 	if task.IsEnable() && c.RunState() == StartedState {
@@ -247,6 +319,8 @@ func (c *CronService) RemoveTask(name string) {
 	}
 
 	delete(c.tasks, name)
+	delete(c.originTasks, name)
+	delete(c.lastFireTimes, name)
 
 	heap := c.getHeapOfTime(task.TriggerTime().Unix())
 	heap.Remove(task)
@@ -257,11 +331,14 @@ func (c *CronService) RemoveTask(name string) {
 func (c *CronService) EnableTask(task ITask, enable bool) {
 	c.mx.Lock()
 	rawTask, ok := c.tasks[task.Name()]
-	if !ok || rawTask != task {
-		c.mx.Unlock()
-		return
+	if ok && rawTask == task {
+		c.enableTask(task, enable)
 	}
+	c.mx.Unlock()
+}
 
+// 启用或禁用任务, 调用者需要持有锁
+func (c *CronService) enableTask(task ITask, enable bool) {
 	heap := c.getHeapOfTime(task.TriggerTime().Unix())
 	heap.Remove(task)
 
@@ -273,7 +350,6 @@ func (c *CronService) EnableTask(task ITask, enable bool) {
 			c.pushTaskToHeap(task)
 		}
 	}
-	c.mx.Unlock()
 }
 
 func (c *CronService) TaskNames() []string {
@@ -313,6 +389,48 @@ func (c *CronService) GetTask(name string) ITask {
 	return task
 }
 
+func (c *CronService) LastRuns(name string, n int) ([]*ExecutionRecord, error) {
+	if c.historyStore == nil {
+		return nil, nil
+	}
+	return c.historyStore.LastRuns(name, n)
+}
+
+func (c *CronService) NextTriggerTime(name string) (time.Time, bool) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	task, ok := c.tasks[name]
+	if !ok || !task.IsEnable() {
+		return time.Time{}, false
+	}
+
+	// 只有在任务堆中的任务才会被触发
+	if !c.inHeap(task) {
+		return time.Time{}, false
+	}
+	return task.TriggerTime(), true
+}
+
+func (c *CronService) DAGRuns(name string, n int) ([]DAGRun, error) {
+	task := c.GetTask(name)
+	if task == nil {
+		return nil, ErrTaskNotFound
+	}
+	dag, ok := task.(IDAGTask)
+	if !ok {
+		return nil, ErrNotDAGTask
+	}
+	return dag.LastDAGRuns(n), nil
+}
+
+func (c *CronService) LastTriggerTime(name string) (time.Time, bool) {
+	c.mx.Lock()
+	t, ok := c.lastFireTimes[name]
+	c.mx.Unlock()
+	return t, ok
+}
+
 // 开始
 func (c *CronService) start() {
 	timer := time.NewTicker(time.Second)
@@ -350,6 +468,13 @@ func (c *CronService) getHeapOfTime(sec int64) ITaskHeap {
 	return c.heaps[bucket]
 }
 
+// 任务是否在任务堆中, 调用者需要持有锁
+func (c *CronService) inHeap(task ITask) bool {
+	tasks := c.getHeapOfTime(task.TriggerTime().Unix()).Tasks()
+	index := task.getHeapIndex()
+	return index >= 0 && index < len(tasks) && tasks[index] == task
+}
+
 // 将任务放入任务堆中
 func (c *CronService) pushTaskToHeap(task ITask) {
 	heap := c.getHeapOfTime(task.TriggerTime().Unix())
@@ -374,7 +499,12 @@ func (c *CronService) heartBeat(t time.Time) {
 		}
 
 		task = heap.Pop()
-		c.triggerTask(task) // 触发
+		if !task.isScanCheckpoint() { // 到达继续查找的检查时间时不执行任务
+			fireTime := task.TriggerTime()
+			c.lastFireTimes[task.Name()] = fireTime
+			c.triggerTask(task, fireTime)                // 触发
+			c.catchUpMisfire(task, task.getTrigger(), t) // 心跳延迟时补偿错过的触发
+		}
 
 		// 获取下一次触发时间
 		_, ok := task.MakeNextTriggerTime(t)
@@ -384,45 +514,112 @@ func (c *CronService) heartBeat(t time.Time) {
 	}
 }
 
-// 触发一个任务
-func (c *CronService) triggerTask(t ITask) {
+// 触发一个任务, fireTime 为计划的触发时间
+func (c *CronService) triggerTask(t ITask, fireTime time.Time) {
+	ok := c.dispatch(t.Name(), func() {
+		c.execute(t, fireTime)
+	})
+	if !ok {
+		go c.executeFinished(t)
+	}
+}
+
+// 在协程池中运行 fn, 没有协程池时开启一个新的goroutine, 任务队列已满时返回 false
+func (c *CronService) dispatch(taskName string, fn func()) bool {
+	c.addDispatched(1)
+	run := func() {
+		defer c.addDispatched(-1)
+		fn()
+	}
+
 	if c.gpool == nil {
-		go c.execute(t)
-		return
+		go run()
+		return true
 	}
 
 	ok := c.gpool.TryGo(func() error {
-		c.execute(t)
+		run()
 		return nil
 	}, nil)
 	if !ok {
-		c.app.Warn("cron.error", zap.String("task_name", t.Name()), zap.String("err", "tasks queue is full"))
+		c.addDispatched(-1)
+		c.app.Warn("cron.error", zap.String("task_name", taskName), zap.String("err", "tasks queue is full"))
 	}
+	return ok
 }
 
 // 执行一个任务
-func (c *CronService) execute(task ITask) {
-	if !task.IsEnable() {
+func (c *CronService) execute(task ITask, fireTime time.Time) {
+	defer c.executeFinished(task)
+
+	// 服务关闭时不再执行, 也不能加锁, 否则其它副本无法执行
+	if !task.IsEnable() || c.isStopping() {
 		return
 	}
 
-	baseCtx, span := utils.Otel.StartSpan(context.Background(), task.Name()+" trigger")
+	baseCtx, span := utils.Otel.StartSpan(c.runContext(), task.Name()+" trigger")
 	defer utils.Otel.EndSpan(span)
 
 	log := c.app.NewTraceLogger(baseCtx, zap.String("task_name", task.Name()))
 
-	log.Debug("cron.start")
-	err := task.Trigger(baseCtx)
+	stopRenew, ok := c.acquireLock(baseCtx, log, task, fireTime)
+	if !ok {
+		return
+	}
+	defer stopRenew()
+
+	c.run(baseCtx, task, fireTime, false)
+}
+
+// 执行结束后通知需要执行结果反馈的触发器, 然后重新计算下次触发时间
+func (c *CronService) executeFinished(task ITask) {
+	trigger, ok := task.getTrigger().(IFeedbackTrigger)
+	if !ok {
+		return
+	}
+	trigger.ExecuteFinished(time.Now())
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	// 暂停后恢复时会重置所有任务的定时
+	if c.tasks[task.Name()] != task || !task.IsEnable() || !c.isStarted() || c.inHeap(task) {
+		return
+	}
+	if _, ok := task.MakeNextTriggerTime(time.Now()); ok {
+		c.pushTaskToHeap(task)
+	}
+}
+
+// 保存执行记录
+func (c *CronService) saveHistory(log core.ILogger, task ITask, e *RunningExecution, attempts int, err error) {
+	if c.historyStore == nil {
+		return
+	}
+
+	endTime := time.Now()
+	record := &ExecutionRecord{
+		TaskName:  task.Name(),
+		FireTime:  e.FireTime,
+		StartTime: e.StartTime,
+		EndTime:   endTime,
+		Duration:  endTime.Sub(e.StartTime),
+		Attempts:  attempts,
+		Outcome:   ExecutionSuccess,
+		Manual:    e.Manual,
+	}
 	if err != nil {
-		log.Error("cron.error!\n" + utils.Recover.GetRecoverErrorDetail(err))
-	} else {
-		log.Debug("cron.success")
+		record.Outcome = ExecutionFailure
+		record.Error = utils.Recover.GetRecoverErrorDetail(err)
+	}
+	if err := c.historyStore.Save(record); err != nil {
+		log.Error("cron.save history error", zap.Error(err))
 	}
 }
 
 // 重置定时器
 //
-// 会重新创建任务堆列表并重新将所有任务加入堆中.
+// 会重新创建任务堆列表并重新将所有任务加入堆中, 并按任务的配置补偿错过的触发.
 // 这里不要做任何耗时操作, 否则可能会错过下一秒的时间导致任务会延迟64秒后执行
 func (c *CronService) resetClock() {
 	c.mx.Lock()
@@ -434,6 +631,7 @@ func (c *CronService) resetClock() {
 			continue
 		}
 
+		c.catchUpMisfire(task, task.getTrigger(), now)
 		task.resetClock()
 		_, ok := task.MakeNextTriggerTime(now)
 		if ok {
@@ -443,34 +641,62 @@ func (c *CronService) resetClock() {
 	c.mx.Unlock()
 }
 
-// 根据配置文件重建task
+// 根据配置文件重建task, 调用者需要持有锁
 func (c *CronService) rebuildTask(task ITask) ITask {
 	conf, ok := c.taskFileConfigs[task.Name()]
 	if !ok {
 		return task
 	}
 
-	var trigger ITrigger
-	if conf.IsOnceTrigger {
-		t, err := zutils.Time(time.Local).TextToTimeOfLayout(conf.Expression, zutils.T.Layout)
-		if err != nil {
-			logger.Log.Fatal("解析一次性任务失败",
-				zap.String("task", task.Name()),
-				zap.String("expression", conf.Expression),
-				zap.Error(err),
-			)
-		}
-		trigger = NewOnceTrigger(t)
-	} else {
-		trigger = NewCronTrigger(conf.Expression)
+	newTask, err := buildTask(task, conf)
+	if err != nil {
+		logger.Log.Fatal("根据配置重建任务失败",
+			zap.String("task", task.Name()),
+			zap.String("expression", conf.Expression),
+			zap.Error(err),
+		)
+	}
+	return newTask
+}
+
+// 使用配置创建新的task, 只保留原task的handler, DAG任务会保留节点和运行状态
+func buildTask(task ITask, conf *TaskFileConfig) (ITask, error) {
+	trigger, err := conf.makeTrigger()
+	if err != nil {
+		return nil, fmt.Errorf("create trigger failed: %v", err)
+	}
+
+	misfire := Misfire{
+		Policy:    conf.MisfirePolicy,
+		Threshold: time.Duration(conf.MisfireThresholdMs) * time.Millisecond,
+		MaxCount:  conf.MisfireMaxCount,
+	}
+	if err := misfire.check(); err != nil {
+		return nil, err
 	}
 
-	executor := NewExecutor(conf.RetryCount, time.Duration(conf.RetrySleepMs)*time.Second, conf.MaxConcurrentExecuteCount)
-	return NewTaskOfConfig(task.Name(), TaskConfig{
+	policy := RetryPolicy{
+		MaxRetryCount:  conf.RetryCount,
+		Interval:       time.Duration(conf.RetrySleepMs) * time.Millisecond,
+		Multiplier:     conf.RetryMultiplier,
+		MaxInterval:    time.Duration(conf.RetryMaxSleepMs) * time.Millisecond,
+		Jitter:         conf.RetryJitter,
+		MaxElapsedTime: time.Duration(conf.RetryMaxElapsedMs) * time.Millisecond,
+	}
+	if err := policy.check(); err != nil {
+		return nil, err
+	}
+
+	taskConf := TaskConfig{
 		Trigger:  trigger,
-		Executor: executor,
+		Executor: NewExecutorOfPolicy(policy, conf.MaxConcurrentExecuteCount),
 		Handler:  task.Handler(),
 		TimeOut:  time.Duration(conf.TimeoutMs) * time.Second,
 		Enable:   !conf.Disable,
-	})
+		Misfire:  misfire,
+	}
+	if dag, ok := task.(*DAGTask); ok {
+		return dag.rebuild(taskConf, conf.FailurePolicy)
+	}
+	return NewTaskOfConfig(task.Name(), taskConf), nil
 }
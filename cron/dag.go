@@ -0,0 +1,461 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zly-app/zapp/logger"
+	"github.com/zly-app/zapp/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// DAG节点失败时的处理策略
+const (
+	// 跳过失败节点的所有下游节点, 其它分支继续执行, 默认
+	DAGSkipDownstream = "skip_downstream"
+	// 任意节点失败后不再启动新的节点, 正在执行的节点会执行完毕
+	DAGFailFast = "fail_fast"
+	// 忽略失败, 依赖只表示执行顺序, 上游节点结束后下游节点就会执行
+	DAGContinue = "continue"
+)
+
+// DAG运行和节点的状态
+const (
+	DAGStatePending = "pending"        // 等待执行
+	DAGStateRunning = "running"        // 正在执行
+	DAGStateSuccess = ExecutionSuccess // 成功
+	DAGStateFailure = ExecutionFailure // 失败
+	DAGStateSkipped = "skipped"        // 被跳过
+)
+
+// 默认保存的DAG运行状态数
+const defaultDAGRunHistorySize = 20
+
+var ErrNotDAGTask = errors.New("任务不是DAG任务")
+
+// DAG节点
+type DAGNode struct {
+	Name      string
+	DependsOn []string // 依赖的节点, 所有依赖的节点都成功后才会执行
+	Handler   Handler
+	Executor  IExecutor     // 执行器, 节点失败时按执行器的配置重试, 为nil时不重试
+	TimeOut   time.Duration // 节点执行超时时间, 0表示永不超时
+}
+
+// DAG任务配置
+type DAGConfig struct {
+	Trigger        ITrigger
+	Executor       IExecutor     // 整个DAG的执行器, 为nil时不重试且同时只有一个运行
+	TimeOut        time.Duration // 整个DAG的超时时间, 超时后未开始的节点会被跳过
+	Enable         bool
+	Misfire        Misfire // 错过触发的处理配置
+	FailurePolicy  string  // 节点失败时的处理策略, 参考 DAGSkipDownstream 等, 默认为 DAGSkipDownstream
+	RunHistorySize int     // 保存最近的运行状态数, 默认为20
+	Nodes          []DAGNode
+}
+
+// DAG节点的运行状态
+type DAGNodeRun struct {
+	Name      string    `json:"name"`
+	DependsOn []string  `json:"depends_on,omitempty"`
+	State     string    `json:"state"` // 参考 DAGStatePending 等
+	StartTime time.Time `json:"start_time,omitempty"`
+	EndTime   time.Time `json:"end_time,omitempty"`
+	Attempts  int       `json:"attempts"` // 执行次数, 包含重试
+	Error     string    `json:"error,omitempty"`
+}
+
+// DAG的一次运行状态
+type DAGRun struct {
+	ID        int64        `json:"id"` // 运行id, 从1开始递增, 重启后重置
+	DAGName   string       `json:"dag_name"`
+	State     string       `json:"state"` // 参考 DAGStateRunning 等
+	StartTime time.Time    `json:"start_time"`
+	EndTime   time.Time    `json:"end_time,omitempty"`
+	Nodes     []DAGNodeRun `json:"nodes"` // 按节点声明的顺序
+}
+
+func (r *DAGRun) clone() DAGRun {
+	run := *r
+	run.Nodes = append([]DAGNodeRun(nil), r.Nodes...)
+	return run
+}
+
+type IDAGTask interface {
+	ITask
+	// 获取最近的 n 次运行状态, 按开始时间倒序, n<=0 时返回所有保存的运行状态
+	LastDAGRuns(n int) []DAGRun
+	// 获取指定id的运行状态
+	GetDAGRun(id int64) (DAGRun, bool)
+}
+
+// DAG任务, 整个DAG作为一个任务被调度, 加锁, 补偿和保存执行记录
+type DAGTask struct {
+	*Task
+	*dagRunner
+	policy string // 节点失败时的处理策略
+}
+
+// 创建一个DAG任务, cron表达式错误或DAG配置错误时会退出程序
+func NewDAGTask(name string, expression string, enable bool, nodes ...DAGNode) IDAGTask {
+	return NewDAGTaskOfConfig(name, DAGConfig{
+		Trigger: mustCronTrigger(name, expression),
+		Enable:  enable,
+		Nodes:   nodes,
+	})
+}
+
+// 根据配置创建一个DAG任务, DAG配置错误时会退出程序
+func NewDAGTaskOfConfig(name string, config DAGConfig) IDAGTask {
+	d, err := newDAGTask(name, config)
+	if err != nil {
+		logger.Log.Fatal("创建DAG任务失败", zap.String("task", name), zap.Error(err))
+	}
+	return d
+}
+
+func newDAGTask(name string, config DAGConfig) (*DAGTask, error) {
+	r, err := newDAGRunner(name, config)
+	if err != nil {
+		return nil, err
+	}
+
+	executor := config.Executor
+	if executor == nil {
+		executor = NewExecutor(0, 0, 1)
+	}
+	policy := config.FailurePolicy
+	if policy == "" {
+		policy = DAGSkipDownstream
+	}
+	return r.newTask(name, TaskConfig{
+		Trigger:  config.Trigger,
+		Executor: executor,
+		TimeOut:  config.TimeOut,
+		Enable:   config.Enable,
+		Misfire:  config.Misfire,
+	}, policy), nil
+}
+
+// 使用新的任务配置重建DAG任务, failurePolicy 为空时使用原来的策略, 节点和运行状态保持不变
+func (d *DAGTask) rebuild(config TaskConfig, failurePolicy string) (ITask, error) {
+	policy := d.policy
+	if failurePolicy != "" {
+		if err := checkDAGFailurePolicy(failurePolicy); err != nil {
+			return nil, err
+		}
+		policy = failurePolicy
+	}
+	return d.dagRunner.newTask(d.Name(), config, policy), nil
+}
+
+// 等待DAG和所有节点的执行器中的任务执行完毕
+func (d *DAGTask) wait() {
+	d.Task.wait()
+	for _, n := range d.nodes {
+		n.task.wait()
+	}
+}
+
+func checkDAGFailurePolicy(policy string) error {
+	switch policy {
+	case "", DAGSkipDownstream, DAGFailFast, DAGContinue:
+		return nil
+	}
+	return fmt.Errorf("unsupported dag failure policy %q", policy)
+}
+
+// ---------------- DAG执行 ----------------
+
+type dagNode struct {
+	name      string
+	dependsOn []string
+	task      ITask
+	children  []int // 下游节点的索引
+}
+
+type dagRunner struct {
+	name   string
+	nodes  []*dagNode
+	size   int       // 保存的运行状态数
+	runs   []*DAGRun // 最近的运行状态, 按开始时间正序
+	lastID int64
+	mx     sync.Mutex // 锁 runs, lastID 和运行状态的修改
+}
+
+func newDAGRunner(name string, config DAGConfig) (*dagRunner, error) {
+	if len(config.Nodes) == 0 {
+		return nil, fmt.Errorf("dag requires at least one node")
+	}
+	if err := checkDAGFailurePolicy(config.FailurePolicy); err != nil {
+		return nil, err
+	}
+
+	r := &dagRunner{
+		name:  name,
+		nodes: make([]*dagNode, len(config.Nodes)),
+		size:  config.RunHistorySize,
+	}
+	if r.size <= 0 {
+		r.size = defaultDAGRunHistorySize
+	}
+
+	indexes := make(map[string]int, len(config.Nodes))
+	for i, n := range config.Nodes {
+		if n.Name == "" {
+			return nil, fmt.Errorf("nodes[%d] name is empty", i)
+		}
+		if _, ok := indexes[n.Name]; ok {
+			return nil, fmt.Errorf("node %q is duplicated", n.Name)
+		}
+		if n.Handler == nil {
+			return nil, fmt.Errorf("node %q handler is nil", n.Name)
+		}
+		indexes[n.Name] = i
+
+		executor := n.Executor
+		if executor == nil {
+			executor = NewExecutor(0, 0, 1)
+		}
+		r.nodes[i] = &dagNode{
+			name:      n.Name,
+			dependsOn: n.DependsOn,
+			task: NewTaskOfConfig(n.Name, TaskConfig{
+				Executor: executor,
+				Handler:  n.Handler,
+				TimeOut:  n.TimeOut,
+				Enable:   true,
+			}),
+		}
+	}
+
+	inDegree := make([]int, len(r.nodes))
+	for i, n := range r.nodes {
+		for _, dep := range n.dependsOn {
+			j, ok := indexes[dep]
+			if !ok {
+				return nil, fmt.Errorf("node %q depends on unknown node %q", n.name, dep)
+			}
+			if j == i {
+				return nil, fmt.Errorf("node %q depends on itself", n.name)
+			}
+			r.nodes[j].children = append(r.nodes[j].children, i)
+			inDegree[i]++
+		}
+	}
+
+	// 拓扑排序检查环
+	queue := make([]int, 0, len(r.nodes))
+	for i, d := range inDegree {
+		if d == 0 {
+			queue = append(queue, i)
+		}
+	}
+	for k := 0; k < len(queue); k++ {
+		for _, child := range r.nodes[queue[k]].children {
+			inDegree[child]--
+			if inDegree[child] == 0 {
+				queue = append(queue, child)
+			}
+		}
+	}
+	if len(queue) != len(r.nodes) {
+		var cycle []string
+		for i, d := range inDegree {
+			if d > 0 {
+				cycle = append(cycle, r.nodes[i].name)
+			}
+		}
+		return nil, fmt.Errorf("dag has a cycle among nodes: %s", strings.Join(cycle, ", "))
+	}
+	return r, nil
+}
+
+// 创建运行这个DAG的任务
+func (r *dagRunner) newTask(name string, config TaskConfig, policy string) *DAGTask {
+	config.Handler = func(ctx IContext) error {
+		return r.run(ctx, policy)
+	}
+	return &DAGTask{
+		Task:      NewTaskOfConfig(name, config).(*Task),
+		dagRunner: r,
+		policy:    policy,
+	}
+}
+
+func (r *dagRunner) LastDAGRuns(n int) []DAGRun {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if n <= 0 || n > len(r.runs) {
+		n = len(r.runs)
+	}
+	runs := make([]DAGRun, n)
+	for i := 0; i < n; i++ {
+		runs[i] = r.runs[len(r.runs)-1-i].clone()
+	}
+	return runs
+}
+
+func (r *dagRunner) GetDAGRun(id int64) (DAGRun, bool) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	for _, run := range r.runs {
+		if run.ID == id {
+			return run.clone(), true
+		}
+	}
+	return DAGRun{}, false
+}
+
+// 创建一个运行状态并保存
+func (r *dagRunner) newRun() *DAGRun {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	r.lastID++
+	run := &DAGRun{
+		ID:        r.lastID,
+		DAGName:   r.name,
+		State:     DAGStateRunning,
+		StartTime: time.Now(),
+		Nodes:     make([]DAGNodeRun, len(r.nodes)),
+	}
+	for i, n := range r.nodes {
+		run.Nodes[i] = DAGNodeRun{Name: n.name, DependsOn: n.dependsOn, State: DAGStatePending}
+	}
+
+	r.runs = append(r.runs, run)
+	if len(r.runs) > r.size {
+		r.runs = append(r.runs[:0], r.runs[len(r.runs)-r.size:]...)
+	}
+	return run
+}
+
+// 修改节点的运行状态
+func (r *dagRunner) setNodeState(run *DAGRun, index int, fn func(n *DAGNodeRun)) {
+	r.mx.Lock()
+	fn(&run.Nodes[index])
+	r.mx.Unlock()
+}
+
+// 节点的执行结果
+type dagNodeResult struct {
+	index    int
+	attempts int
+	err      error
+}
+
+// 运行整个DAG, 阻塞等待所有节点结束
+func (r *dagRunner) run(ctx IContext, policy string) error {
+	run := r.newRun()
+	ctx.Info("dag.start", zap.Int64("run_id", run.ID), zap.String("failure_policy", policy))
+
+	remaining := make([]int, len(r.nodes))       // 未结束的上游节点数
+	upstreamFailed := make([]bool, len(r.nodes)) // 是否有上游节点失败或被跳过
+	for i, n := range r.nodes {
+		remaining[i] = len(n.dependsOn)
+	}
+
+	results := make(chan dagNodeResult, len(r.nodes))
+	var active int
+	var failed []string // 失败的节点和错误
+	var skipped bool
+
+	start := func(i int) {
+		active++
+		r.setNodeState(run, i, func(n *DAGNodeRun) {
+			n.State = DAGStateRunning
+			n.StartTime = time.Now()
+		})
+		go func(i int) {
+			attempts, err := r.nodes[i].task.execute(ctx)
+			results <- dagNodeResult{index: i, attempts: attempts, err: err}
+		}(i)
+	}
+
+	// 节点结束后检查下游节点是否可以执行
+	var finish func(i int, ok bool)
+	skip := func(i int, reason string) {
+		skipped = true
+		r.setNodeState(run, i, func(n *DAGNodeRun) {
+			n.State = DAGStateSkipped
+			n.Error = reason
+		})
+		ctx.Warn("dag.node skipped", zap.String("node", r.nodes[i].name), zap.String("reason", reason))
+		finish(i, false)
+	}
+	ready := func(i int) {
+		switch {
+		case upstreamFailed[i] && policy != DAGContinue:
+			skip(i, "upstream node failed or skipped")
+		case len(failed) > 0 && policy == DAGFailFast:
+			skip(i, "dag failed fast")
+		case ctx.Err() != nil:
+			skip(i, ctx.Err().Error())
+		default:
+			start(i)
+		}
+	}
+	finish = func(i int, ok bool) {
+		for _, child := range r.nodes[i].children {
+			if !ok {
+				upstreamFailed[child] = true
+			}
+			remaining[child]--
+			if remaining[child] == 0 {
+				ready(child)
+			}
+		}
+	}
+
+	for i := range r.nodes {
+		if remaining[i] == 0 {
+			ready(i)
+		}
+	}
+	for active > 0 {
+		result := <-results
+		active--
+
+		node := r.nodes[result.index]
+		r.setNodeState(run, result.index, func(n *DAGNodeRun) {
+			n.EndTime = time.Now()
+			n.Attempts = result.attempts
+			n.State = DAGStateSuccess
+			if result.err != nil {
+				n.State = DAGStateFailure
+				n.Error = utils.Recover.GetRecoverErrorDetail(result.err)
+			}
+		})
+		if result.err != nil {
+			failed = append(failed, node.name+": "+result.err.Error())
+			ctx.Error("dag.node error", zap.String("node", node.name), zap.Int("attempts", result.attempts),
+				zap.String("err", utils.Recover.GetRecoverErrorDetail(result.err)))
+		} else {
+			ctx.Debug("dag.node success", zap.String("node", node.name), zap.Int("attempts", result.attempts))
+		}
+		finish(result.index, result.err == nil)
+	}
+
+	var err error
+	switch {
+	case len(failed) > 0:
+		err = fmt.Errorf("dag run %d has %d failed node(s): %s", run.ID, len(failed), strings.Join(failed, "; "))
+	case skipped && ctx.Err() != nil:
+		err = fmt.Errorf("dag run %d is interrupted: %v", run.ID, ctx.Err())
+	}
+
+	r.mx.Lock()
+	run.EndTime = time.Now()
+	run.State = DAGStateSuccess
+	if err != nil {
+		run.State = DAGStateFailure
+	}
+	r.mx.Unlock()
+	return err
+}
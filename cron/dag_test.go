@@ -0,0 +1,176 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 记录节点的执行顺序
+type dagRecorder struct {
+	order []string
+	mx    sync.Mutex
+}
+
+func (r *dagRecorder) handler(name string, err error) Handler {
+	return func(ctx IContext) error {
+		r.mx.Lock()
+		r.order = append(r.order, name)
+		r.mx.Unlock()
+		return err
+	}
+}
+
+func (r *dagRecorder) index(name string) int {
+	for i, n := range r.order {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func testDAGNodes(r *dagRecorder, transformErr error) []DAGNode {
+	return []DAGNode{
+		{Name: "export", Handler: r.handler("export", nil)},
+		{Name: "transform_a", DependsOn: []string{"export"}, Handler: r.handler("transform_a", transformErr)},
+		{Name: "transform_b", DependsOn: []string{"export"}, Handler: r.handler("transform_b", nil)},
+		{Name: "load", DependsOn: []string{"transform_a", "transform_b"}, Handler: r.handler("load", nil)},
+		{Name: "audit", DependsOn: []string{"transform_b"}, Handler: r.handler("audit", nil)},
+	}
+}
+
+func testDAGNodeStates(run DAGRun) map[string]string {
+	states := make(map[string]string, len(run.Nodes))
+	for _, n := range run.Nodes {
+		states[n.Name] = n.State
+	}
+	return states
+}
+
+func TestDAGTask(t *testing.T) {
+	r := new(dagRecorder)
+	dag, err := newDAGTask("test", DAGConfig{Trigger: NewOnceTrigger(time.Now()), Enable: true, Nodes: testDAGNodes(r, nil)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = dag.Trigger(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.order) != 5 || r.index("export") != 0 {
+		t.Fatal("执行顺序和预期不符", r.order)
+	}
+	if r.index("load") < r.index("transform_a") || r.index("load") < r.index("transform_b") || r.index("audit") < r.index("transform_b") {
+		t.Fatal("执行顺序和预期不符", r.order)
+	}
+
+	runs := dag.LastDAGRuns(0)
+	if len(runs) != 1 || runs[0].ID != 1 || runs[0].State != DAGStateSuccess {
+		t.Fatal("运行状态和预期不符", runs)
+	}
+	for _, n := range runs[0].Nodes {
+		if n.State != DAGStateSuccess || n.Attempts != 1 {
+			t.Fatal("节点状态和预期不符", n)
+		}
+	}
+	if _, ok := dag.GetDAGRun(1); !ok {
+		t.Fatal("没有找到运行状态")
+	}
+}
+
+func TestDAGFailurePolicy(t *testing.T) {
+	tests := []struct {
+		policy string
+		states map[string]string
+	}{
+		{DAGSkipDownstream, map[string]string{"export": DAGStateSuccess, "transform_a": DAGStateFailure, "transform_b": DAGStateSuccess, "load": DAGStateSkipped, "audit": DAGStateSuccess}},
+		{DAGContinue, map[string]string{"export": DAGStateSuccess, "transform_a": DAGStateFailure, "transform_b": DAGStateSuccess, "load": DAGStateSuccess, "audit": DAGStateSuccess}},
+	}
+	for _, test := range tests {
+		r := new(dagRecorder)
+		dag, err := newDAGTask("test", DAGConfig{Enable: true, FailurePolicy: test.policy, Nodes: testDAGNodes(r, errors.New("err"))})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = dag.Trigger(context.Background()); err == nil {
+			t.Fatal("节点失败时应该返回错误", test.policy)
+		}
+
+		run, _ := dag.GetDAGRun(1)
+		if run.State != DAGStateFailure {
+			t.Fatal("运行状态和预期不符", test.policy, run.State)
+		}
+		states := testDAGNodeStates(run)
+		for name, state := range test.states {
+			if states[name] != state {
+				t.Fatal("节点状态和预期不符", test.policy, name, states[name])
+			}
+		}
+	}
+}
+
+func TestDAGFailFast(t *testing.T) {
+	r := new(dagRecorder)
+	dag, err := newDAGTask("test", DAGConfig{Enable: true, FailurePolicy: DAGFailFast, Nodes: []DAGNode{
+		{Name: "a", Handler: r.handler("a", errors.New("err"))},
+		{Name: "b", Handler: func(ctx IContext) error {
+			time.Sleep(time.Millisecond * 100)
+			return nil
+		}},
+		{Name: "c", DependsOn: []string{"b"}, Handler: r.handler("c", nil)},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = dag.Trigger(context.Background())
+
+	run, _ := dag.GetDAGRun(1)
+	states := testDAGNodeStates(run)
+	if states["a"] != DAGStateFailure || states["b"] != DAGStateSuccess || states["c"] != DAGStateSkipped {
+		t.Fatal("节点状态和预期不符", states)
+	}
+}
+
+func TestDAGNodeRetry(t *testing.T) {
+	var count int
+	dag, err := newDAGTask("test", DAGConfig{Enable: true, Nodes: []DAGNode{
+		{Name: "a", Executor: NewExecutor(2, 0, 1), Handler: func(ctx IContext) error {
+			count++
+			if count < 3 {
+				return errors.New("err")
+			}
+			return nil
+		}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = dag.Trigger(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	run, _ := dag.GetDAGRun(1)
+	if run.Nodes[0].Attempts != 3 || run.Nodes[0].State != DAGStateSuccess {
+		t.Fatal("节点重试和预期不符", run.Nodes[0])
+	}
+}
+
+func TestDAGConfigError(t *testing.T) {
+	handler := func(ctx IContext) error { return nil }
+	tests := [][]DAGNode{
+		nil,
+		{{Name: "a", Handler: handler}, {Name: "a", Handler: handler}},
+		{{Name: "a", DependsOn: []string{"b"}, Handler: handler}},
+		{{Name: "a", DependsOn: []string{"a"}, Handler: handler}},
+		{{Name: "a", DependsOn: []string{"c"}, Handler: handler}, {Name: "b", DependsOn: []string{"a"}, Handler: handler}, {Name: "c", DependsOn: []string{"b"}, Handler: handler}},
+		{{Name: "a"}},
+	}
+	for i, nodes := range tests {
+		if _, err := newDAGTask("test", DAGConfig{Nodes: nodes}); err == nil {
+			t.Fatal("DAG配置错误时应该返回错误", i)
+		}
+	}
+}
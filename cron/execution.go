@@ -0,0 +1,94 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/zly-app/zapp/pkg/utils"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrTaskNotFound  = errors.New("任务不存在")
+	ErrTaskQueueFull = errors.New("任务队列已满")
+)
+
+// 正在执行的任务
+type RunningExecution struct {
+	TaskName  string    `json:"task_name"`
+	FireTime  time.Time `json:"fire_time"`  // 计划触发时间, 手动触发时为触发的时间
+	StartTime time.Time `json:"start_time"` // 开始时间
+	Manual    bool      `json:"manual"`     // 是否为手动触发
+}
+
+// 获取正在执行的任务列表, 按开始时间排序
+func (c *CronService) RunningExecutions() []RunningExecution {
+	c.runningMx.Lock()
+	list := make([]RunningExecution, 0, len(c.running))
+	for e := range c.running {
+		list = append(list, *e)
+	}
+	c.runningMx.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].StartTime.Before(list[j].StartTime)
+	})
+	return list
+}
+
+func (c *CronService) addRunning(e *RunningExecution) {
+	c.runningMx.Lock()
+	c.running[e] = struct{}{}
+	c.runningMx.Unlock()
+}
+
+func (c *CronService) removeRunning(e *RunningExecution) {
+	c.runningMx.Lock()
+	delete(c.running, e)
+	c.runningMx.Unlock()
+}
+
+// 立即触发任务, 不会等待执行结束. meta 会设置为执行上下文的元数据
+//
+// 手动触发不会加锁, 即使任务未启用也会执行, 执行记录会保存到历史记录中
+func (c *CronService) TriggerTask(name string, meta interface{}) error {
+	task := c.GetTask(name)
+	if task == nil {
+		return ErrTaskNotFound
+	}
+
+	fireTime := time.Now()
+	ok := c.dispatch(name, func() {
+		baseCtx, span := utils.Otel.StartSpan(c.runContext(), task.Name()+" manual trigger")
+		defer utils.Otel.EndSpan(span)
+		c.run(withMeta(baseCtx, meta), task, fireTime, true)
+	})
+	if !ok {
+		return ErrTaskQueueFull
+	}
+	return nil
+}
+
+// 执行任务, 记录正在执行的任务和执行记录
+func (c *CronService) run(ctx context.Context, task ITask, fireTime time.Time, manual bool) {
+	log := c.app.NewTraceLogger(ctx, zap.String("task_name", task.Name()))
+	if c.isStopping() {
+		log.Warn("cron.skip, the service is stopping")
+		return
+	}
+
+	e := &RunningExecution{TaskName: task.Name(), FireTime: fireTime, StartTime: time.Now(), Manual: manual}
+	c.addRunning(e)
+	defer c.removeRunning(e)
+
+	log.Debug("cron.start", zap.Bool("manual", manual))
+	attempts, err := task.execute(ctx)
+	if err != nil {
+		log.Error("cron.error!\n" + utils.Recover.GetRecoverErrorDetail(err))
+	} else {
+		log.Debug("cron.success")
+	}
+	c.saveHistory(log, task, e, attempts, err)
+}
@@ -1,7 +1,11 @@
 package cron
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"math"
+	"math/rand"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -15,41 +19,177 @@ var OutOfMaxConcurrentExecuteCount = errors.New("超出最大并发执行数")
 type ErrCallback func(ctx IContext, err error)
 
 type IExecutor interface {
-	// 执行
-	Do(onDo func(retryNums int) (IContext, error), errCallback ErrCallback) error
+	// 执行, ctx 结束或服务停止时不再等待重试
+	Do(ctx context.Context, onDo func(retryNums int) (IContext, error), errCallback ErrCallback) error
 	// 等待任务执行完毕
 	Wait()
 	// 返回是否正在执行任务
 	IsRunning() bool
 }
 
+// 重试策略
+type RetryPolicy struct {
+	MaxRetryCount  int64         // 最大重试次数, 0表示不重试, -1表示不限制, 此时必须设置 MaxElapsedTime
+	Interval       time.Duration // 第一次重试前的等待时间
+	Multiplier     float64       // 每次重试后等待时间的倍数, 小于等于1时为固定间隔
+	MaxInterval    time.Duration // 最大等待时间, 0表示不限制
+	Jitter         float64       // 随机抖动比例, 取值范围为 [0, 1], 实际等待时间在 interval*(1-Jitter) 到 interval*(1+Jitter) 之间
+	MaxElapsedTime time.Duration // 从第一次执行开始的最大总时间, 等待后会超过这个时间时不再重试, 0表示不限制
+}
+
+func (p RetryPolicy) check() error {
+	if p.MaxRetryCount < -1 {
+		return fmt.Errorf("max retry count must not be less than -1")
+	}
+	if p.MaxRetryCount == -1 && p.MaxElapsedTime <= 0 {
+		return fmt.Errorf("max elapsed time must be set when max retry count is -1")
+	}
+	if p.Interval < 0 || p.MaxInterval < 0 || p.MaxElapsedTime < 0 {
+		return fmt.Errorf("retry interval, max interval and max elapsed time must not be negative")
+	}
+	if p.Multiplier < 0 {
+		return fmt.Errorf("retry multiplier must not be negative")
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return fmt.Errorf("retry jitter must be in [0, 1]")
+	}
+	return nil
+}
+
+var (
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	jitterMx   sync.Mutex
+)
+
+// 第 retryNum 次重试前的等待时间, retryNum 从1开始
+func (p RetryPolicy) backoff(retryNum int) time.Duration {
+	interval := float64(p.Interval)
+	if p.Multiplier > 1 {
+		for i := 1; i < retryNum; i++ {
+			interval *= p.Multiplier
+			if (p.MaxInterval > 0 && interval >= float64(p.MaxInterval)) || interval >= math.MaxInt64 {
+				break
+			}
+		}
+	}
+	if p.Jitter > 0 {
+		jitterMx.Lock()
+		interval *= 1 + p.Jitter*(jitterRand.Float64()*2-1)
+		jitterMx.Unlock()
+	}
+	if p.MaxInterval > 0 && interval > float64(p.MaxInterval) { // 抖动后也不能超过最大等待时间
+		interval = float64(p.MaxInterval)
+	}
+	if interval >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(interval)
+}
+
+// 不可重试的错误
+type nonRetryableError struct {
+	err error
+}
+
+func (e *nonRetryableError) Error() string { return e.err.Error() }
+func (e *nonRetryableError) Unwrap() error { return e.err }
+
+// 将错误标记为不可重试, handler返回这个错误时不会重试. err 为nil时返回nil
+func NonRetryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &nonRetryableError{err: err}
+}
+
+// 是否为不可重试的错误
+func IsNonRetryable(err error) bool {
+	var e *nonRetryableError
+	return errors.As(err, &e)
+}
+
+// 指定重试等待时间的错误
+type retryAfterError struct {
+	err   error
+	delay time.Duration
+}
+
+func (e *retryAfterError) Error() string { return e.err.Error() }
+func (e *retryAfterError) Unwrap() error { return e.err }
+
+// 指定下次重试前的等待时间, handler返回这个错误时会忽略重试策略的等待时间. err 为nil时返回nil
+//
+// 重试次数和最大总时间仍然按照重试策略限制
+func RetryAfter(err error, delay time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &retryAfterError{err: err, delay: delay}
+}
+
+// 获取错误指定的重试等待时间
+func retryAfterDelay(err error) (time.Duration, bool) {
+	var e *retryAfterError
+	if errors.As(err, &e) {
+		return e.delay, true
+	}
+	return 0, false
+}
+
+type stopSignalKey struct{}
+
+// 设置停止信号, 信号关闭时不再等待重试
+func withStopSignal(ctx context.Context, stop <-chan struct{}) context.Context {
+	return context.WithValue(ctx, stopSignalKey{}, stop)
+}
+
+// 等待重试, ctx 结束或收到停止信号时返回 false
+func waitRetry(ctx context.Context, d time.Duration) bool {
+	stop, _ := ctx.Value(stopSignalKey{}).(<-chan struct{})
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-stop:
+		return false
+	}
+}
+
 type Executor struct {
-	maxConcurrentExecuteCount int64         // 最大并发执行数, -1表示不限制
-	concurrentExecuteCount    int64         // 当前并发执行数
-	maxRetryCount             int64         // 重试次数
-	retryInterval             time.Duration // 重试间隔
+	maxConcurrentExecuteCount int64       // 最大并发执行数, -1表示不限制
+	concurrentExecuteCount    int64       // 当前并发执行数
+	policy                    RetryPolicy // 重试策略
 	wg                        sync.WaitGroup
 }
 
-// 创建一个执行器, 任务失败会重试
+// 创建一个执行器, 任务失败会按固定间隔重试
 //
 // retryCount: 任务失败重试次数
 // retryInterval: 失败重试间隔时间
 // maxConcurrentExecuteCount: 最大并发执行任务数, 如果为-1则不限制, 默认为1
 func NewExecutor(retryCount int64, retryInterval time.Duration, maxConcurrentExecuteCount int64) IExecutor {
+	return NewExecutorOfPolicy(RetryPolicy{MaxRetryCount: retryCount, Interval: retryInterval}, maxConcurrentExecuteCount)
+}
+
+// 根据重试策略创建一个执行器
+//
+// maxConcurrentExecuteCount: 最大并发执行任务数, 如果为-1则不限制, 默认为1
+func NewExecutorOfPolicy(policy RetryPolicy, maxConcurrentExecuteCount int64) IExecutor {
 	if maxConcurrentExecuteCount == 0 {
 		maxConcurrentExecuteCount = 1
 	}
 	return &Executor{
 		maxConcurrentExecuteCount: maxConcurrentExecuteCount,
 		concurrentExecuteCount:    0,
-		maxRetryCount:             retryCount,
-		retryInterval:             retryInterval,
+		policy:                    policy,
 	}
 }
 
 // 执行, 如果已经达到最大并发执行任务数则会返回错误
-func (w *Executor) Do(onDo func(retryNums int) (IContext, error), errCallback ErrCallback) error {
+func (w *Executor) Do(ctx context.Context, onDo func(retryNums int) (IContext, error), errCallback ErrCallback) error {
 	if w.maxConcurrentExecuteCount > 0 && atomic.AddInt64(&w.concurrentExecuteCount, 1) > w.maxConcurrentExecuteCount {
 		atomic.AddInt64(&w.concurrentExecuteCount, -1) // 增加了要退回去
 		return OutOfMaxConcurrentExecuteCount
@@ -57,7 +197,7 @@ func (w *Executor) Do(onDo func(retryNums int) (IContext, error), errCallback Er
 
 	w.wg.Add(1)
 
-	err := w.doRetry(w.retryInterval, w.maxRetryCount, onDo, errCallback)
+	err := w.doRetry(ctx, onDo, errCallback)
 
 	if w.maxConcurrentExecuteCount > 0 {
 		atomic.AddInt64(&w.concurrentExecuteCount, -1) // 执行完毕也要退回去
@@ -76,28 +216,71 @@ func (w *Executor) IsRunning() bool {
 	return atomic.LoadInt64(&w.concurrentExecuteCount) > 0
 }
 
-// 执行一个函数
-func (w *Executor) doRetry(interval time.Duration, retryCount int64,
-	onDo func(retryNums int) (IContext, error), errCallback ErrCallback) (err error) {
+// 替换执行器时使用, 旧执行器中的任务执行完毕前新的执行不会开始, 保证最大并发执行数不会被突破
+type drainExecutor struct {
+	IExecutor
+	prev IExecutor
+}
+
+// 创建一个等待 prev 执行完毕的执行器, next 已经是这种执行器时会使用它的原始执行器
+func newDrainExecutor(prev, next IExecutor) IExecutor {
+	if d, ok := next.(*drainExecutor); ok {
+		next = d.IExecutor
+	}
+	return &drainExecutor{IExecutor: next, prev: prev}
+}
+
+// 旧执行器中还有任务在执行时返回 OutOfMaxConcurrentExecuteCount
+func (d *drainExecutor) Do(ctx context.Context, onDo func(retryNums int) (IContext, error), errCallback ErrCallback) error {
+	if d.prev.IsRunning() {
+		return OutOfMaxConcurrentExecuteCount
+	}
+	return d.IExecutor.Do(ctx, onDo, errCallback)
+}
+
+// 等待新旧执行器中的任务执行完毕
+func (d *drainExecutor) Wait() {
+	d.prev.Wait()
+	d.IExecutor.Wait()
+}
+
+func (d *drainExecutor) IsRunning() bool {
+	return d.prev.IsRunning() || d.IExecutor.IsRunning()
+}
+
+// 执行一个函数, 失败时按重试策略重试
+func (w *Executor) doRetry(baseCtx context.Context, onDo func(retryNums int) (IContext, error), errCallback ErrCallback) (err error) {
+	start := time.Now()
+	retryCount := w.policy.MaxRetryCount
 	var ctx IContext
-	for {
+	for retryNum := 1; ; retryNum++ {
 		err = utils.Recover.WrapCall(func() error {
 			ctx, err = onDo(int(retryCount))
 			return err
 		})
-		if err == nil || retryCount == 0 {
-			// 这里不需要错误回调, 如果有err交给调用者处理
+		// 这里不需要错误回调, 如果有err交给调用者处理
+		if err == nil || retryCount == 0 || IsNonRetryable(err) {
+			return
+		}
+
+		interval, ok := retryAfterDelay(err)
+		if !ok {
+			interval = w.policy.backoff(retryNum)
+		}
+		if w.policy.MaxElapsedTime > 0 && time.Since(start)+interval > w.policy.MaxElapsedTime {
 			return
 		}
 
-		retryCount--
+		if retryCount > 0 {
+			retryCount--
+		}
 
 		if errCallback != nil {
 			errCallback(ctx, err)
 		}
 
-		if interval > 0 {
-			time.Sleep(interval)
+		if !waitRetry(baseCtx, interval) {
+			return
 		}
 	}
 }
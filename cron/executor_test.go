@@ -0,0 +1,152 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{Interval: time.Second, Multiplier: 2, MaxInterval: time.Second * 5}
+	expects := []time.Duration{time.Second, time.Second * 2, time.Second * 4, time.Second * 5, time.Second * 5}
+	for i, expect := range expects {
+		if d := policy.backoff(i + 1); d != expect {
+			t.Fatal("等待时间和预期不符", i+1, d)
+		}
+	}
+
+	policy = RetryPolicy{Interval: time.Second, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		if d := policy.backoff(1); d < time.Millisecond*500 || d > time.Millisecond*1500 {
+			t.Fatal("抖动后的等待时间超出范围", d)
+		}
+	}
+
+	policy = RetryPolicy{Interval: time.Second * 4, Multiplier: 2, MaxInterval: time.Second * 5, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		if d := policy.backoff(3); d < time.Millisecond*2500 || d > time.Second*5 {
+			t.Fatal("抖动后的等待时间超过了最大等待时间", d)
+		}
+	}
+
+	if d := (RetryPolicy{Interval: time.Hour, Multiplier: 10}).backoff(100); d <= 0 {
+		t.Fatal("等待时间溢出", d)
+	}
+}
+
+func TestRetryPolicyCheck(t *testing.T) {
+	tests := []struct {
+		policy RetryPolicy
+		hasErr bool
+	}{
+		{RetryPolicy{MaxRetryCount: 3}, false},
+		{RetryPolicy{MaxRetryCount: -1, MaxElapsedTime: time.Minute}, false},
+		{RetryPolicy{MaxRetryCount: -1}, true},
+		{RetryPolicy{MaxRetryCount: -2, MaxElapsedTime: time.Minute}, true},
+		{RetryPolicy{Interval: -1}, true},
+		{RetryPolicy{Multiplier: -1}, true},
+		{RetryPolicy{Jitter: 1.5}, true},
+	}
+	for _, test := range tests {
+		if err := test.policy.check(); (err != nil) != test.hasErr {
+			t.Fatal("重试策略检查结果和预期不符", test.policy, err)
+		}
+	}
+}
+
+// 执行直到成功或不再重试, 返回执行次数
+func testExecutorDo(ctx context.Context, executor IExecutor, handler func(n int) error) (int, error) {
+	var n int
+	err := executor.Do(ctx, func(retryNums int) (IContext, error) {
+		n++
+		return nil, handler(n)
+	}, nil)
+	return n, err
+}
+
+func TestExecutorRetry(t *testing.T) {
+	executor := NewExecutorOfPolicy(RetryPolicy{MaxRetryCount: 3, Interval: time.Millisecond, Multiplier: 2}, 1)
+	n, err := testExecutorDo(context.Background(), executor, func(n int) error {
+		if n < 3 {
+			return errors.New("err")
+		}
+		return nil
+	})
+	if err != nil || n != 3 {
+		t.Fatal("重试和预期不符", n, err)
+	}
+
+	n, err = testExecutorDo(context.Background(), executor, func(n int) error {
+		return NonRetryable(errors.New("err"))
+	})
+	if err == nil || n != 1 || !IsNonRetryable(err) {
+		t.Fatal("不可重试的错误不应该重试", n, err)
+	}
+}
+
+func TestExecutorRetryAfter(t *testing.T) {
+	executor := NewExecutorOfPolicy(RetryPolicy{MaxRetryCount: 1, Interval: time.Hour}, 1)
+	start := time.Now()
+	n, err := testExecutorDo(context.Background(), executor, func(n int) error {
+		if n == 1 {
+			return RetryAfter(errors.New("err"), time.Millisecond*10)
+		}
+		return nil
+	})
+	if err != nil || n != 2 || time.Since(start) > time.Second {
+		t.Fatal("指定的等待时间没有生效", n, err)
+	}
+}
+
+func TestExecutorMaxElapsedTime(t *testing.T) {
+	executor := NewExecutorOfPolicy(RetryPolicy{MaxRetryCount: -1, Interval: time.Millisecond * 40, MaxElapsedTime: time.Millisecond * 100}, 1)
+	n, err := testExecutorDo(context.Background(), executor, func(n int) error {
+		return errors.New("err")
+	})
+	if err == nil || n != 3 {
+		t.Fatal("最大总时间没有生效", n, err)
+	}
+}
+
+func TestExecutorStopWaiting(t *testing.T) {
+	executor := NewExecutorOfPolicy(RetryPolicy{MaxRetryCount: 1, Interval: time.Hour}, -1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	n, err := testExecutorDo(ctx, executor, func(n int) error {
+		return errors.New("err")
+	})
+	if err == nil || n != 1 {
+		t.Fatal("ctx结束后应该停止等待重试", n, err)
+	}
+
+	stop := make(chan struct{})
+	time.AfterFunc(time.Millisecond*10, func() { close(stop) })
+	n, err = testExecutorDo(withStopSignal(context.Background(), stop), executor, func(n int) error {
+		return errors.New("err")
+	})
+	if err == nil || n != 1 {
+		t.Fatal("收到停止信号后应该停止等待重试", n, err)
+	}
+}
+
+func TestTaskTimeoutRetry(t *testing.T) {
+	task := NewTaskOfConfig("test", TaskConfig{
+		Executor: NewExecutor(1, 0, 1),
+		Handler: func(ctx IContext) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Millisecond * 50):
+				return nil
+			}
+		},
+		TimeOut: time.Millisecond * 10,
+		Enable:  true,
+	})
+	attempts, err := task.execute(context.Background())
+	if err == nil || attempts != 2 {
+		t.Fatal("每次执行都应该单独计算超时", attempts, err)
+	}
+}
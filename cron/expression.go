@@ -0,0 +1,337 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// cron表达式格式
+const (
+	// 自动识别, 5个字段为标准格式, 6个字段时第一个字段为秒, 默认
+	ExpressionAuto = ""
+	// 标准格式, 5个字段: 分 时 日 月 周
+	ExpressionStandard = "standard"
+	// 秒级格式, 6个字段: 秒 分 时 日 月 周
+	ExpressionSeconds = "seconds"
+)
+
+// 计算下一次触发时间时最多检查的天数
+const maxExpressionScanDays = 366 * 5
+
+var secondsParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+var dowNames = map[string]int{"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6}
+
+/*
+解析cron表达式
+
+	支持 CRON_TZ=<时区> 或 TZ=<时区> 前缀设置时区, 未设置时使用 loc
+	支持 @every 1h, @daily 等描述符
+	日字段支持 L(最后一天), L-n(最后一天的前n天), nW(离n号最近的工作日), LW(最后一个工作日)
+	周字段支持 nL(最后一个周n), n#k(第k个周n), 周日为0或7
+*/
+func parseCronExpression(expression, format string, loc *time.Location) (cron.Schedule, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	switch format {
+	case ExpressionAuto, ExpressionStandard, ExpressionSeconds:
+	default:
+		return nil, fmt.Errorf("unsupported expression format %q", format)
+	}
+
+	spec := strings.TrimSpace(expression)
+	if strings.HasPrefix(spec, "TZ=") || strings.HasPrefix(spec, "CRON_TZ=") {
+		i := strings.IndexAny(spec, " \t")
+		if i == -1 {
+			return nil, fmt.Errorf("expression is empty")
+		}
+		var err error
+		loc, err = time.LoadLocation(spec[strings.Index(spec, "=")+1 : i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone: %v", err)
+		}
+		spec = strings.TrimSpace(spec[i:])
+	}
+	tz := "CRON_TZ=" + loc.String() + " "
+
+	if strings.HasPrefix(spec, "@") {
+		return secondsParser.Parse(tz + spec)
+	}
+
+	fields := strings.Fields(spec)
+	switch {
+	case len(fields) == 5 && format != ExpressionSeconds:
+		fields = append([]string{"0"}, fields...)
+	case len(fields) == 6 && format != ExpressionStandard:
+	default:
+		return nil, fmt.Errorf("expected %s fields, found %d: %s", expectedFieldCount(format), len(fields), spec)
+	}
+
+	dom, dow := strings.ToUpper(fields[3]), strings.ToUpper(fields[5])
+	if !strings.ContainsAny(dom, "LW") && !strings.ContainsAny(dow, "L#") {
+		return secondsParser.Parse(tz + strings.Join(fields, " "))
+	}
+
+	// 日期由扩展语法匹配, 其它字段由标准解析器计算
+	base, err := secondsParser.Parse(tz + strings.Join([]string{fields[0], fields[1], fields[2], "*", fields[4], "*"}, " "))
+	if err != nil {
+		return nil, err
+	}
+	s := &extendedSchedule{base: base, loc: loc}
+	if s.dom, err = parseDomField(dom); err != nil {
+		return nil, fmt.Errorf("invalid day of month field %q: %v", fields[3], err)
+	}
+	if s.dow, err = parseDowField(dow); err != nil {
+		return nil, fmt.Errorf("invalid day of week field %q: %v", fields[5], err)
+	}
+	return s, nil
+}
+
+func expectedFieldCount(format string) string {
+	switch format {
+	case ExpressionStandard:
+		return "5"
+	case ExpressionSeconds:
+		return "6"
+	}
+	return "5 or 6"
+}
+
+// 日期匹配函数, 为nil表示匹配所有日期
+type dayMatcher func(t time.Time) bool
+
+// 支持扩展日期语法的计划
+type extendedSchedule struct {
+	base cron.Schedule // 不限制日期的计划
+	loc  *time.Location
+	dom  dayMatcher
+	dow  dayMatcher
+}
+
+func (s *extendedSchedule) Next(t time.Time) time.Time {
+	t = t.In(s.loc)
+	for i := 0; i < maxExpressionScanDays; i++ {
+		next := s.base.Next(t)
+		if next.IsZero() || s.match(next) {
+			return next
+		}
+		// 跳到这一天的最后
+		y, m, d := next.Date()
+		t = time.Date(y, m, d+1, 0, 0, 0, 0, s.loc).Add(-time.Nanosecond)
+	}
+	return time.Time{}
+}
+
+// 和标准cron相同, 日和周都有限制时满足其一即可
+func (s *extendedSchedule) match(t time.Time) bool {
+	switch {
+	case s.dom != nil && s.dow != nil:
+		return s.dom(t) || s.dow(t)
+	case s.dom != nil:
+		return s.dom(t)
+	case s.dow != nil:
+		return s.dow(t)
+	}
+	return true
+}
+
+// 当月的最后一天
+func lastDayOfMonth(t time.Time) int {
+	y, m, _ := t.Date()
+	return time.Date(y, m+1, 0, 0, 0, 0, 0, t.Location()).Day()
+}
+
+// 离 day 号最近的工作日, 不会跨月
+func nearestWeekday(t time.Time, day int) int {
+	last := lastDayOfMonth(t)
+	if day > last {
+		day = last
+	}
+	y, m, _ := t.Date()
+	switch time.Date(y, m, day, 0, 0, 0, 0, t.Location()).Weekday() {
+	case time.Saturday:
+		if day == 1 {
+			return day + 2
+		}
+		return day - 1
+	case time.Sunday:
+		if day == last {
+			return day - 2
+		}
+		return day + 1
+	}
+	return day
+}
+
+// 解析日字段
+func parseDomField(field string) (dayMatcher, error) {
+	if field == "*" || field == "?" {
+		return nil, nil
+	}
+
+	var matchers []dayMatcher
+	for _, item := range strings.Split(field, ",") {
+		switch {
+		case item == "L":
+			matchers = append(matchers, func(t time.Time) bool { return t.Day() == lastDayOfMonth(t) })
+		case item == "LW":
+			matchers = append(matchers, func(t time.Time) bool {
+				last := lastDayOfMonth(t)
+				return t.Day() == nearestWeekday(t, last)
+			})
+		case strings.HasPrefix(item, "L-"):
+			n, err := parseNumber(item[2:], 0, 30)
+			if err != nil {
+				return nil, err
+			}
+			matchers = append(matchers, func(t time.Time) bool { return t.Day() == lastDayOfMonth(t)-n })
+		case strings.HasSuffix(item, "W"):
+			n, err := parseNumber(item[:len(item)-1], 1, 31)
+			if err != nil {
+				return nil, err
+			}
+			matchers = append(matchers, func(t time.Time) bool { return t.Day() == nearestWeekday(t, n) })
+		default:
+			set, err := parseRange(item, 1, 31, nil)
+			if err != nil {
+				return nil, err
+			}
+			matchers = append(matchers, func(t time.Time) bool { return set[t.Day()] })
+		}
+	}
+	return anyMatch(matchers), nil
+}
+
+// 解析周字段
+func parseDowField(field string) (dayMatcher, error) {
+	if field == "*" || field == "?" {
+		return nil, nil
+	}
+
+	var matchers []dayMatcher
+	for _, item := range strings.Split(field, ",") {
+		switch {
+		case strings.HasSuffix(item, "L"):
+			wd, err := parseWeekday(item[:len(item)-1])
+			if err != nil {
+				return nil, err
+			}
+			matchers = append(matchers, func(t time.Time) bool {
+				return t.Weekday() == wd && t.Day()+7 > lastDayOfMonth(t)
+			})
+		case strings.Contains(item, "#"):
+			i := strings.Index(item, "#")
+			wd, err := parseWeekday(item[:i])
+			if err != nil {
+				return nil, err
+			}
+			k, err := parseNumber(item[i+1:], 1, 5)
+			if err != nil {
+				return nil, err
+			}
+			matchers = append(matchers, func(t time.Time) bool {
+				return t.Weekday() == wd && (t.Day()-1)/7+1 == k
+			})
+		default:
+			set, err := parseRange(item, 0, 7, dowNames)
+			if err != nil {
+				return nil, err
+			}
+			if set[7] {
+				set[0] = true
+			}
+			matchers = append(matchers, func(t time.Time) bool { return set[int(t.Weekday())] })
+		}
+	}
+	return anyMatch(matchers), nil
+}
+
+func anyMatch(matchers []dayMatcher) dayMatcher {
+	return func(t time.Time) bool {
+		for _, m := range matchers {
+			if m(t) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// 解析星期, 周日为0或7
+func parseWeekday(s string) (time.Weekday, error) {
+	if n, ok := dowNames[s]; ok {
+		return time.Weekday(n), nil
+	}
+	n, err := parseNumber(s, 0, 7)
+	if err != nil {
+		return 0, err
+	}
+	return time.Weekday(n % 7), nil
+}
+
+func parseNumber(s string, min, max int) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	if n < min || n > max {
+		return 0, fmt.Errorf("number %d out of range [%d, %d]", n, min, max)
+	}
+	return n, nil
+}
+
+// 解析范围, 格式为 *, a, a-b, */step, a/step, a-b/step
+func parseRange(item string, min, max int, names map[string]int) (map[int]bool, error) {
+	step := 1
+	if i := strings.Index(item, "/"); i != -1 {
+		var err error
+		if step, err = parseNumber(item[i+1:], 1, max); err != nil {
+			return nil, err
+		}
+		item = item[:i]
+	}
+
+	parse := func(s string) (int, error) {
+		if n, ok := names[s]; ok {
+			return n, nil
+		}
+		return parseNumber(s, min, max)
+	}
+
+	start, end := min, max
+	switch {
+	case item == "*" || item == "?":
+	case strings.Contains(item, "-"):
+		i := strings.Index(item, "-")
+		var err error
+		if start, err = parse(item[:i]); err != nil {
+			return nil, err
+		}
+		if end, err = parse(item[i+1:]); err != nil {
+			return nil, err
+		}
+		if start > end {
+			return nil, fmt.Errorf("invalid range %q", item)
+		}
+	default:
+		n, err := parse(item)
+		if err != nil {
+			return nil, err
+		}
+		start = n
+		if step == 1 {
+			end = n
+		}
+	}
+
+	set := make(map[int]bool)
+	for n := start; n <= end; n += step {
+		set[n] = true
+	}
+	return set, nil
+}
@@ -0,0 +1,81 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronExpression(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expression string
+		format     string
+		loc        *time.Location
+		from       time.Time
+		expect     time.Time
+	}{
+		{"*/10 * * * * *", ExpressionAuto, time.UTC, time.Date(2024, 2, 10, 10, 0, 3, 0, time.UTC), time.Date(2024, 2, 10, 10, 0, 10, 0, time.UTC)},
+		{"0 0 * * *", ExpressionStandard, time.UTC, time.Date(2024, 2, 10, 10, 0, 3, 0, time.UTC), time.Date(2024, 2, 11, 0, 0, 0, 0, time.UTC)},
+		{"0 0 L * *", ExpressionAuto, time.UTC, time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 L-2 * *", ExpressionAuto, time.UTC, time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 27, 0, 0, 0, 0, time.UTC)},
+		{"0 0 15W * *", ExpressionAuto, time.UTC, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 14, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1W * *", ExpressionAuto, time.UTC, time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)},
+		{"0 0 LW * *", ExpressionAuto, time.UTC, time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 8, 30, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 5L", ExpressionAuto, time.UTC, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 23, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * MON#2", ExpressionAuto, time.UTC, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 12, 0, 0, 0, 0, time.UTC)},
+		{"30 0 0 ? * 1#2", ExpressionSeconds, time.UTC, time.Date(2024, 2, 12, 0, 1, 0, 0, time.UTC), time.Date(2024, 3, 11, 0, 0, 30, 0, time.UTC)},
+		{"CRON_TZ=Asia/Shanghai 0 8 * * *", ExpressionAuto, time.UTC, time.Date(2024, 2, 10, 1, 0, 0, 0, time.UTC), time.Date(2024, 2, 11, 0, 0, 0, 0, time.UTC)},
+		{"0 8 * * *", ExpressionAuto, shanghai, time.Date(2024, 2, 10, 1, 0, 0, 0, time.UTC), time.Date(2024, 2, 11, 0, 0, 0, 0, time.UTC)},
+		{"0 8 L * *", ExpressionAuto, shanghai, time.Date(2024, 2, 10, 1, 0, 0, 0, time.UTC), time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		schedule, err := parseCronExpression(test.expression, test.format, test.loc)
+		if err != nil {
+			t.Fatal(test.expression, err)
+		}
+		next := schedule.Next(test.from)
+		if !next.Equal(test.expect) {
+			t.Fatal(test.expression, "触发时间和预期不符", next)
+		}
+	}
+}
+
+func TestParseCronExpressionError(t *testing.T) {
+	tests := []struct {
+		expression string
+		format     string
+	}{
+		{"* * * * *", ExpressionSeconds},
+		{"* * * * * *", ExpressionStandard},
+		{"* * * *", ExpressionAuto},
+		{"0 0 32W * *", ExpressionAuto},
+		{"0 0 L-31 * *", ExpressionAuto},
+		{"0 0 * * 8L", ExpressionAuto},
+		{"0 0 * * 1#6", ExpressionAuto},
+		{"CRON_TZ=Unknown/Zone 0 0 * * *", ExpressionAuto},
+		{"0 0 * * *", "unknown"},
+	}
+	for _, test := range tests {
+		if _, err := parseCronExpression(test.expression, test.format, time.UTC); err == nil {
+			t.Fatal(test.expression, "应该返回错误")
+		}
+	}
+}
+
+func TestCronTriggerNoNext(t *testing.T) {
+	trigger, err := NewCronTrigger("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := trigger.MakeNextTriggerTime(time.Now()); ok {
+		t.Fatal("不存在的日期不应该有下一次触发")
+	}
+
+	if _, err = NewCronTrigger("invalid"); err == nil {
+		t.Fatal("表达式错误时应该返回错误")
+	}
+}
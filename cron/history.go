@@ -0,0 +1,287 @@
+package cron
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// 内存历史记录, 每个任务保存最近的记录
+	MemoryHistoryStore = "memory"
+	// 文件历史记录, 每个任务的记录保存在历史记录目录下的一个jsonl文件中
+	FileHistoryStore = "file"
+	// 不保存历史记录
+	NoneHistoryStore = "none"
+)
+
+// 执行结果
+const (
+	ExecutionSuccess = "success" // 成功
+	ExecutionFailure = "failure" // 失败
+)
+
+// 执行记录
+type ExecutionRecord struct {
+	TaskName  string        `json:"task_name"`
+	FireTime  time.Time     `json:"fire_time"`  // 计划触发时间
+	StartTime time.Time     `json:"start_time"` // 开始时间
+	EndTime   time.Time     `json:"end_time"`   // 结束时间
+	Duration  time.Duration `json:"duration"`   // 耗时
+	Attempts  int           `json:"attempts"`   // 执行次数, 包含重试
+	Outcome   string        `json:"outcome"`    // 执行结果, 参考 ExecutionSuccess 等
+	Error     string        `json:"error,omitempty"`
+	Manual    bool          `json:"manual,omitempty"` // 是否为手动触发
+}
+
+// 执行历史记录存储, 可以通过 SetHistoryStore 接入数据库等实现
+type IHistoryStore interface {
+	// 保存一条执行记录
+	Save(record *ExecutionRecord) error
+	// 获取任务最近的 n 条执行记录, 按开始时间倒序, n 小于等于0时返回所有记录
+	LastRuns(taskName string, n int) ([]*ExecutionRecord, error)
+}
+
+// 自定义历史记录存储
+var customHistoryStore IHistoryStore
+
+// 设置自定义历史记录存储, 设置后忽略配置中的 HistoryStore, 这个函数应该在 app.Run 之前调用
+func SetHistoryStore(store IHistoryStore) {
+	customHistoryStore = store
+}
+
+// 根据配置创建历史记录存储, 不保存历史记录时返回nil
+func newHistoryStore(conf *Config) (IHistoryStore, error) {
+	switch conf.HistoryStore {
+	case NoneHistoryStore:
+		return nil, nil
+	case MemoryHistoryStore:
+		return NewMemoryHistoryStore(conf.HistorySize), nil
+	case FileHistoryStore:
+		return NewFileHistoryStore(conf.HistoryDir, conf.HistorySize)
+	}
+	return nil, fmt.Errorf("unsupported history store %q", conf.HistoryStore)
+}
+
+// ---------------- 内存历史记录 ----------------
+
+// 环形缓冲区
+type historyRing struct {
+	records []*ExecutionRecord
+	next    int // 下一条记录写入的位置
+	full    bool
+}
+
+func (r *historyRing) push(record *ExecutionRecord) {
+	r.records[r.next] = record
+	r.next = (r.next + 1) % len(r.records)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// 获取最近的 n 条记录, 按写入顺序倒序
+func (r *historyRing) last(n int) []*ExecutionRecord {
+	count := r.next
+	if r.full {
+		count = len(r.records)
+	}
+	if n <= 0 || n > count {
+		n = count
+	}
+
+	out := make([]*ExecutionRecord, n)
+	for i := 0; i < n; i++ {
+		index := (r.next - 1 - i + len(r.records)) % len(r.records)
+		out[i] = r.records[index]
+	}
+	return out
+}
+
+// 内存历史记录, 每个任务使用一个环形缓冲区保存最近的记录
+type MemoryHistory struct {
+	size  int
+	rings map[string]*historyRing
+	mx    sync.Mutex
+}
+
+// 创建内存历史记录存储, size 为每个任务保存的记录数
+func NewMemoryHistoryStore(size int) IHistoryStore {
+	if size <= 0 {
+		size = defaultHistorySize
+	}
+	return &MemoryHistory{size: size, rings: make(map[string]*historyRing)}
+}
+
+func (m *MemoryHistory) Save(record *ExecutionRecord) error {
+	m.mx.Lock()
+	ring, ok := m.rings[record.TaskName]
+	if !ok {
+		ring = &historyRing{records: make([]*ExecutionRecord, m.size)}
+		m.rings[record.TaskName] = ring
+	}
+	ring.push(record)
+	m.mx.Unlock()
+	return nil
+}
+
+func (m *MemoryHistory) LastRuns(taskName string, n int) ([]*ExecutionRecord, error) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	ring, ok := m.rings[taskName]
+	if !ok {
+		return nil, nil
+	}
+	return ring.last(n), nil
+}
+
+// ---------------- 文件历史记录 ----------------
+
+// 文件历史记录, 每个任务的记录追加写入一个jsonl文件
+//
+// 文件行数超过 size 的2倍时会重写文件只保留最近的 size 条记录
+type FileHistory struct {
+	dir   string
+	size  int
+	lines map[string]int // 任务名 -> 文件行数
+	mx    sync.Mutex
+}
+
+// 创建文件历史记录存储, dir 为历史记录目录, 不存在时会自动创建, size 为每个任务保存的记录数
+func NewFileHistoryStore(dir string, size int) (IHistoryStore, error) {
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "zapp_cron_history")
+	}
+	if size <= 0 {
+		size = defaultHistorySize
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create history dir failed: %v", err)
+	}
+	return &FileHistory{dir: dir, size: size, lines: make(map[string]int)}, nil
+}
+
+// 任务的历史记录文件
+func (f *FileHistory) file(taskName string) string {
+	name := strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(taskName)
+	return filepath.Join(f.dir, name+".jsonl")
+}
+
+func (f *FileHistory) Save(record *ExecutionRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	lines, ok := f.lines[record.TaskName]
+	if !ok {
+		records, err := f.read(record.TaskName)
+		if err != nil {
+			return err
+		}
+		lines = len(records)
+	}
+
+	file, err := os.OpenFile(f.file(record.TaskName), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(data, '\n'))
+	_ = file.Close()
+	if err != nil {
+		return err
+	}
+	lines++
+
+	if lines > f.size*2 {
+		if lines, err = f.compact(record.TaskName); err != nil {
+			return err
+		}
+	}
+	f.lines[record.TaskName] = lines
+	return nil
+}
+
+func (f *FileHistory) LastRuns(taskName string, n int) ([]*ExecutionRecord, error) {
+	f.mx.Lock()
+	records, err := f.read(taskName)
+	f.mx.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if n <= 0 || n > len(records) {
+		n = len(records)
+	}
+	out := make([]*ExecutionRecord, n)
+	for i := 0; i < n; i++ {
+		out[i] = records[len(records)-1-i]
+	}
+	return out, nil
+}
+
+// 读取任务的所有记录, 按写入顺序
+func (f *FileHistory) read(taskName string) ([]*ExecutionRecord, error) {
+	data, err := ioutil.ReadFile(f.file(taskName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*ExecutionRecord
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		record := new(ExecutionRecord)
+		if err = json.Unmarshal(line, record); err != nil { // 跳过写入不完整的行
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// 重写文件只保留最近的 size 条记录, 返回文件行数
+func (f *FileHistory) compact(taskName string) (int, error) {
+	records, err := f.read(taskName)
+	if err != nil {
+		return 0, err
+	}
+	if len(records) > f.size {
+		records = records[len(records)-f.size:]
+	}
+
+	var buf bytes.Buffer
+	for _, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return 0, err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	file := f.file(taskName)
+	tmp := file + ".tmp"
+	if err = ioutil.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return 0, err
+	}
+	return len(records), os.Rename(tmp, file)
+}
@@ -0,0 +1,72 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func testHistoryStore(t *testing.T, store IHistoryStore, size int) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local)
+	for i := 0; i < size*3; i++ {
+		record := &ExecutionRecord{
+			TaskName:  "test",
+			FireTime:  start.Add(time.Duration(i) * time.Minute),
+			StartTime: start.Add(time.Duration(i) * time.Minute),
+			Attempts:  i,
+			Outcome:   ExecutionSuccess,
+		}
+		if err := store.Save(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := store.LastRuns("test", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Attempts != size*3-1 || records[1].Attempts != size*3-2 {
+		t.Fatal("记录和预期不符", records)
+	}
+	if !records[0].FireTime.Equal(start.Add(time.Duration(size*3-1) * time.Minute)) {
+		t.Fatal("触发时间和预期不符", records[0].FireTime)
+	}
+
+	records, err = store.LastRuns("test", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) < size || records[size-1].Attempts != size*2 {
+		t.Fatal("保存的记录数和预期不符", len(records))
+	}
+
+	records, err = store.LastRuns("unknown", 10)
+	if err != nil || len(records) != 0 {
+		t.Fatal("不存在的任务应该没有记录", records, err)
+	}
+}
+
+func TestMemoryHistoryStore(t *testing.T) {
+	testHistoryStore(t, NewMemoryHistoryStore(5), 5)
+}
+
+func TestFileHistoryStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileHistoryStore(dir, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testHistoryStore(t, store, 5)
+
+	// 重新打开后可以读取之前的记录
+	store, err = NewFileHistoryStore(dir, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := store.LastRuns("test", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Attempts != 14 {
+		t.Fatal("记录和预期不符", records)
+	}
+}
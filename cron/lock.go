@@ -0,0 +1,171 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/zly-app/zapp/core"
+	"go.uber.org/zap"
+)
+
+const (
+	// 内存锁, 只能保证同一个进程内单次执行, 一般用于测试
+	MemoryLockProvider = "memory"
+	// 文件锁, 所有副本需要能访问同一个锁目录
+	FileLockProvider = "file"
+)
+
+/*
+分布式锁提供者, 用于多副本部署时保证同一个任务的同一个触发时间只有一个副本执行
+
+	key 由任务名和触发时间组成, holder 为副本的持有者标识
+	锁在租约到期后自动失效, 执行期间会定时续约
+
+可以通过 SetLockProvider 接入基于 redis, etcd 等实现的锁
+*/
+type ILockProvider interface {
+	// 尝试获取锁, 获取失败时返回当前的持有者
+	TryLock(ctx context.Context, key, holder string, lease time.Duration) (ok bool, currentHolder string, err error)
+	// 续约, 锁已经不属于 holder 时返回错误
+	Renew(ctx context.Context, key, holder string, lease time.Duration) error
+}
+
+// 自定义锁提供者
+var customLockProvider ILockProvider
+
+// 设置自定义锁提供者, 设置后忽略配置中的 LockProvider, 这个函数应该在 app.Run 之前调用
+func SetLockProvider(provider ILockProvider) {
+	customLockProvider = provider
+}
+
+// 根据配置创建锁提供者, 未配置时返回nil
+func newLockProvider(conf *Config) (ILockProvider, error) {
+	switch conf.LockProvider {
+	case "":
+		return nil, nil
+	case MemoryLockProvider:
+		return NewMemoryLockProvider(), nil
+	case FileLockProvider:
+		return NewFileLockProvider(conf.LockDir)
+	}
+	return nil, fmt.Errorf("unsupported lock provider %q", conf.LockProvider)
+}
+
+// 锁租约时间
+func (c *CronService) lockLease() time.Duration {
+	return time.Duration(c.conf.LockLeaseMs) * time.Millisecond
+}
+
+// 生成锁key
+func makeLockKey(taskName string, fireTime time.Time) string {
+	return taskName + "@" + strconv.FormatInt(fireTime.Unix(), 10)
+}
+
+// 默认持有者标识
+func defaultLockHolder() string {
+	hostname, _ := os.Hostname()
+	return hostname + ":" + strconv.Itoa(os.Getpid())
+}
+
+// 获取任务在触发时间的锁, 没有设置锁提供者时总是成功
+//
+// 获取成功时返回停止续约的函数. 锁在执行完毕后不会主动释放, 以防止时钟稍慢的副本在执行完毕后再次获取到锁
+func (c *CronService) acquireLock(ctx context.Context, log core.ILogger, task ITask, fireTime time.Time) (stopRenew func(), ok bool) {
+	if c.lockProvider == nil {
+		return func() {}, true
+	}
+
+	key := makeLockKey(task.Name(), fireTime)
+	ok, holder, err := c.lockProvider.TryLock(ctx, key, c.conf.LockHolder, c.lockLease())
+	if err != nil {
+		log.Error("cron.lock error, skip execution", zap.String("key", key), zap.Error(err))
+		return nil, false
+	}
+	if !ok {
+		log.Info("cron.skip, the lock is held by another holder", zap.String("key", key), zap.String("holder", holder))
+		return nil, false
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(c.lockLease() / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := c.lockProvider.Renew(ctx, key, c.conf.LockHolder, c.lockLease()); err != nil {
+					log.Warn("cron.lock renew error", zap.String("key", key), zap.Error(err))
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }, true
+}
+
+// ---------------- 内存锁 ----------------
+
+// 锁记录
+type lockEntry struct {
+	Holder string `json:"holder"`
+	Expire int64  `json:"expire"` // 过期时间, 单位纳秒
+}
+
+// 内存锁, 只能保证同一个进程内单次执行
+type MemoryLock struct {
+	entries map[string]lockEntry
+	mx      sync.Mutex
+}
+
+// 创建内存锁提供者
+func NewMemoryLockProvider() ILockProvider {
+	return &MemoryLock{entries: make(map[string]lockEntry)}
+}
+
+func (m *MemoryLock) TryLock(ctx context.Context, key, holder string, lease time.Duration) (bool, string, error) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	now := time.Now().UnixNano()
+	ok, currentHolder := tryLockEntries(m.entries, now, key, holder, lease)
+	return ok, currentHolder, nil
+}
+
+func (m *MemoryLock) Renew(ctx context.Context, key, holder string, lease time.Duration) error {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	return renewLockEntries(m.entries, time.Now().UnixNano(), key, holder, lease)
+}
+
+// 在锁记录中尝试获取锁, 会清理已过期的记录
+func tryLockEntries(entries map[string]lockEntry, now int64, key, holder string, lease time.Duration) (bool, string) {
+	for k, e := range entries {
+		if e.Expire <= now {
+			delete(entries, k)
+		}
+	}
+
+	if e, ok := entries[key]; ok && e.Holder != holder {
+		return false, e.Holder
+	}
+	entries[key] = lockEntry{Holder: holder, Expire: now + int64(lease)}
+	return true, holder
+}
+
+// 在锁记录中续约
+func renewLockEntries(entries map[string]lockEntry, now int64, key, holder string, lease time.Duration) error {
+	e, ok := entries[key]
+	if !ok || e.Expire <= now {
+		return fmt.Errorf("lock %q is expired", key)
+	}
+	if e.Holder != holder {
+		return fmt.Errorf("lock %q is held by %q", key, e.Holder)
+	}
+	entries[key] = lockEntry{Holder: holder, Expire: now + int64(lease)}
+	return nil
+}
@@ -0,0 +1,88 @@
+package cron
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 文件锁的记录文件名
+const fileLockName = "cron.lock"
+
+// 文件锁, 所有锁记录保存在锁目录的一个文件中, 读写记录时通过 flock 互斥
+//
+// 多台机器部署时锁目录需要在共享存储上, 并且共享存储需要支持 flock
+type FileLock struct {
+	file string
+}
+
+// 创建文件锁提供者, dir 为锁目录, 不存在时会自动创建. 不支持windows, 在windows上会返回错误
+func NewFileLockProvider(dir string) (ILockProvider, error) {
+	if err := flockSupported(); err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "zapp_cron_lock")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create lock dir failed: %v", err)
+	}
+	return &FileLock{file: filepath.Join(dir, fileLockName)}, nil
+}
+
+func (f *FileLock) TryLock(ctx context.Context, key, holder string, lease time.Duration) (ok bool, currentHolder string, err error) {
+	err = f.update(func(entries map[string]lockEntry, now int64) error {
+		ok, currentHolder = tryLockEntries(entries, now, key, holder, lease)
+		return nil
+	})
+	return ok, currentHolder, err
+}
+
+func (f *FileLock) Renew(ctx context.Context, key, holder string, lease time.Duration) error {
+	return f.update(func(entries map[string]lockEntry, now int64) error {
+		return renewLockEntries(entries, now, key, holder, lease)
+	})
+}
+
+// 在文件锁内读取锁记录, fn 执行成功后写回
+func (f *FileLock) update(fn func(entries map[string]lockEntry, now int64) error) error {
+	file, err := os.OpenFile(f.file, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err = flock(file); err != nil {
+		return fmt.Errorf("flock failed: %v", err)
+	}
+	defer funlock(file)
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	entries := make(map[string]lockEntry)
+	if len(data) > 0 {
+		if err = json.Unmarshal(data, &entries); err != nil {
+			return fmt.Errorf("parse lock file failed: %v", err)
+		}
+	}
+
+	if err = fn(entries, time.Now().UnixNano()); err != nil {
+		return err
+	}
+
+	data, err = json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	if err = file.Truncate(0); err != nil {
+		return err
+	}
+	_, err = file.WriteAt(data, 0)
+	return err
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+// +build !windows
+
+package cron
+
+import (
+	"os"
+	"syscall"
+)
+
+// 检查当前平台是否支持文件锁
+func flockSupported() error {
+	return nil
+}
+
+// 获取文件的排他锁, 会阻塞直到获取成功
+func flock(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+}
+
+// 释放文件锁
+func funlock(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
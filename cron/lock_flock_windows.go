@@ -0,0 +1,23 @@
+//go:build windows
+// +build windows
+
+package cron
+
+import (
+	"errors"
+	"os"
+)
+
+var errFlockNotSupported = errors.New("flock is not supported on windows")
+
+func flockSupported() error {
+	return errFlockNotSupported
+}
+
+func flock(file *os.File) error {
+	return errFlockNotSupported
+}
+
+func funlock(file *os.File) error {
+	return errFlockNotSupported
+}
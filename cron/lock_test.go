@@ -0,0 +1,67 @@
+package cron
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func testLockProvider(t *testing.T, p ILockProvider) {
+	ctx := context.Background()
+	key := makeLockKey("test", time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local))
+
+	ok, holder, err := p.TryLock(ctx, key, "a", time.Millisecond*200)
+	if err != nil || !ok || holder != "a" {
+		t.Fatal("获取锁失败", ok, holder, err)
+	}
+	ok, holder, err = p.TryLock(ctx, key, "b", time.Millisecond*200)
+	if err != nil || ok || holder != "a" {
+		t.Fatal("锁被重复获取", ok, holder, err)
+	}
+	ok, _, err = p.TryLock(ctx, makeLockKey("test", time.Date(2020, 1, 1, 0, 1, 0, 0, time.Local)), "b", time.Millisecond*200)
+	if err != nil || !ok {
+		t.Fatal("不同触发时间的锁应该互不影响", ok, err)
+	}
+
+	if err = p.Renew(ctx, key, "a", time.Millisecond*200); err != nil {
+		t.Fatal("续约失败", err)
+	}
+	if err = p.Renew(ctx, key, "b", time.Millisecond*200); err == nil {
+		t.Fatal("非持有者续约应该失败")
+	}
+
+	time.Sleep(time.Millisecond * 300)
+	ok, holder, err = p.TryLock(ctx, key, "b", time.Millisecond*200)
+	if err != nil || !ok || holder != "b" {
+		t.Fatal("租约到期后获取锁失败", ok, holder, err)
+	}
+	if err = p.Renew(ctx, key, "a", time.Millisecond*200); err == nil {
+		t.Fatal("锁过期后原持有者续约应该失败")
+	}
+}
+
+func TestMemoryLockProvider(t *testing.T) {
+	testLockProvider(t, NewMemoryLockProvider())
+}
+
+func TestFileLockProvider(t *testing.T) {
+	dir := t.TempDir()
+	p, err := NewFileLockProvider(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testLockProvider(t, p)
+
+	// 另一个实例使用同一个目录时共享锁记录
+	p2, err := NewFileLockProvider(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := makeLockKey("test", time.Now())
+	if ok, _, err := p.TryLock(context.Background(), key, "a", time.Second); err != nil || !ok {
+		t.Fatal("获取锁失败", ok, err)
+	}
+	if ok, holder, err := p2.TryLock(context.Background(), key, "b", time.Second); err != nil || ok || holder != "a" {
+		t.Fatal("锁被重复获取", ok, holder, err)
+	}
+}
@@ -0,0 +1,177 @@
+package cron
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 错过触发的处理策略
+const (
+	// 跳过错过的触发, 默认
+	MisfireSkip = "skip"
+	// 只补偿触发一次
+	MisfireFireOnce = "fire_once"
+	// 补偿触发所有错过的触发, 最多补偿 MaxCount 次
+	MisfireFireAll = "fire_all"
+)
+
+// 查找错过的触发时间时最多计算的次数, 防止触发间隔很小且停机很久时计算过多
+const maxMisfireScanCount = 100000
+
+// 错过触发的处理配置
+//
+// 停机, 暂停或心跳延迟都会导致错过触发, 重新计算触发时间时上次触发时间到当前时间之间的触发时间就是错过的触发.
+// 延迟不超过 Threshold 的触发不算错过, 会正常补偿触发, 超过 Threshold 的按 Policy 处理.
+// 补偿触发使用原本的触发时间, 会按时间顺序依次执行
+type Misfire struct {
+	Policy    string        // 处理策略, 参考 MisfireSkip 等, 默认为 MisfireSkip
+	Threshold time.Duration // 延迟多久才算错过, 默认为0
+	MaxCount  int           // 策略为 MisfireFireAll 时最多补偿的次数, 0表示不限制
+}
+
+// 是否需要处理错过的触发
+func (m Misfire) enabled() bool {
+	return (m.Policy != "" && m.Policy != MisfireSkip) || m.Threshold > 0
+}
+
+func (m Misfire) check() error {
+	switch m.Policy {
+	case "", MisfireSkip, MisfireFireOnce, MisfireFireAll:
+	default:
+		return fmt.Errorf("unsupported misfire policy %q", m.Policy)
+	}
+	if m.Threshold < 0 || m.MaxCount < 0 {
+		return fmt.Errorf("misfire threshold and max count must not be negative")
+	}
+	return nil
+}
+
+// 可以计算任意时间之后的触发时间的触发器, 只有实现了这个接口的触发器才能补偿错过的触发
+type IMisfireTrigger interface {
+	// 返回 t 之后的触发时间, 不会改变触发器的状态. 如果返回了 false 表示没有下一次了
+	NextTriggerTimeAfter(t time.Time) (time.Time, bool)
+}
+
+// 计算需要补偿的触发时间, 返回 (lastFireTime, now] 之间需要补偿的触发时间, 按时间顺序
+func misfireTimes(trigger ITrigger, misfire Misfire, lastFireTime, now time.Time) (fireTimes []time.Time, err error) {
+	mt, ok := trigger.(IMisfireTrigger)
+	if !ok {
+		return nil, nil
+	}
+
+	var late []time.Time   // 延迟不超过阈值的触发
+	var missed []time.Time // 错过的触发, 只保留需要补偿的部分
+	keep := 0              // 需要保留的错过的触发数, 为-1表示不限制
+	switch misfire.Policy {
+	case MisfireFireOnce:
+		keep = 1
+	case MisfireFireAll:
+		keep = misfire.MaxCount
+		if keep == 0 {
+			keep = -1
+		}
+	}
+
+	t := lastFireTime
+	for i := 0; ; i++ {
+		if i >= maxMisfireScanCount {
+			return nil, fmt.Errorf("too many misfired triggers since %s", lastFireTime.Format(OnceTriggerTimeLayout))
+		}
+
+		t, ok = mt.NextTriggerTimeAfter(t)
+		if !ok || t.Unix() > now.Unix() {
+			break
+		}
+
+		if now.Sub(t) <= misfire.Threshold {
+			late = append(late, t)
+			continue
+		}
+		if keep == 0 {
+			continue
+		}
+		missed = append(missed, t)
+		if keep > 0 && len(missed) > keep {
+			missed = missed[1:]
+		}
+	}
+	return append(missed, late...), nil
+}
+
+// 加载持久化的上次触发时间, 只有保存在历史记录中的触发时间才能在重启后恢复
+func (c *CronService) loadLastFireTimes() {
+	if c.historyStore == nil {
+		return
+	}
+
+	for _, task := range c.Tasks() {
+		if !task.Misfire().enabled() {
+			continue
+		}
+
+		c.mx.Lock()
+		_, ok := c.lastFireTimes[task.Name()]
+		c.mx.Unlock()
+		if ok {
+			continue
+		}
+
+		records, err := c.historyStore.LastRuns(task.Name(), 0)
+		if err != nil {
+			c.app.Error("cron.load last fire time error", zap.String("task_name", task.Name()), zap.Error(err))
+			continue
+		}
+		// 手动触发的记录不是计划触发时间. 记录按保存顺序也就是执行结束的顺序排列,
+		// 重叠执行或补偿触发时和触发时间的顺序不同, 所以使用非手动触发的记录中最大的触发时间
+		var lastFireTime time.Time
+		for _, record := range records {
+			if !record.Manual && record.FireTime.After(lastFireTime) {
+				lastFireTime = record.FireTime
+			}
+		}
+		if lastFireTime.IsZero() {
+			continue
+		}
+		c.mx.Lock()
+		if _, ok = c.lastFireTimes[task.Name()]; !ok {
+			c.lastFireTimes[task.Name()] = lastFireTime
+		}
+		c.mx.Unlock()
+	}
+}
+
+// 补偿触发任务在 (lastFireTime, now] 之间错过的触发, 调用者需要持有锁
+func (c *CronService) catchUpMisfire(task ITask, trigger ITrigger, now time.Time) {
+	misfire := task.Misfire()
+	if !misfire.enabled() {
+		return
+	}
+	lastFireTime, ok := c.lastFireTimes[task.Name()]
+	if !ok {
+		return
+	}
+
+	fireTimes, err := misfireTimes(trigger, misfire, lastFireTime, now)
+	if err != nil {
+		c.app.Warn("cron.misfire error, skip catch up", zap.String("task_name", task.Name()), zap.Error(err))
+		return
+	}
+	if len(fireTimes) == 0 {
+		return
+	}
+
+	c.lastFireTimes[task.Name()] = fireTimes[len(fireTimes)-1]
+	c.app.Warn("cron.misfire, catch up",
+		zap.String("task_name", task.Name()),
+		zap.String("policy", misfire.Policy),
+		zap.Time("lastFireTime", lastFireTime),
+		zap.Times("fireTimes", fireTimes),
+	)
+	c.dispatch(task.Name(), func() {
+		for _, fireTime := range fireTimes {
+			c.execute(task, fireTime)
+		}
+	})
+}
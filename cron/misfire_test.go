@@ -0,0 +1,97 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMisfireTimes(t *testing.T) {
+	trigger, _ := NewCronTrigger("* * * * *")
+	lastFireTime := time.Date(2020, 1, 1, 10, 0, 0, 0, time.Local)
+	now := time.Date(2020, 1, 1, 10, 10, 30, 0, time.Local)
+	minute := func(m int) time.Time {
+		return time.Date(2020, 1, 1, 10, m, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name    string
+		misfire Misfire
+		expect  []time.Time
+	}{
+		{"skip", Misfire{Policy: MisfireSkip}, nil},
+		{"fire_once", Misfire{Policy: MisfireFireOnce}, []time.Time{minute(10)}},
+		{"fire_all", Misfire{Policy: MisfireFireAll, MaxCount: 3}, []time.Time{minute(8), minute(9), minute(10)}},
+		{"fire_all_unlimited", Misfire{Policy: MisfireFireAll}, []time.Time{
+			minute(1), minute(2), minute(3), minute(4), minute(5), minute(6), minute(7), minute(8), minute(9), minute(10),
+		}},
+		{"skip_threshold", Misfire{Policy: MisfireSkip, Threshold: time.Second * 90}, []time.Time{minute(9), minute(10)}},
+		{"fire_once_threshold", Misfire{Policy: MisfireFireOnce, Threshold: time.Second * 90}, []time.Time{minute(8), minute(9), minute(10)}},
+	}
+	for _, test := range tests {
+		fireTimes, err := misfireTimes(trigger, test.misfire, lastFireTime, now)
+		if err != nil {
+			t.Fatal(test.name, err)
+		}
+		if len(fireTimes) != len(test.expect) {
+			t.Fatal(test.name, "补偿的触发时间和预期不符", fireTimes)
+		}
+		for i := range fireTimes {
+			if !fireTimes[i].Equal(test.expect[i]) {
+				t.Fatal(test.name, "补偿的触发时间和预期不符", fireTimes)
+			}
+		}
+	}
+}
+
+func TestMisfireTimesOnceTrigger(t *testing.T) {
+	executeTime := time.Date(2020, 1, 1, 10, 0, 0, 0, time.Local)
+	trigger := NewOnceTrigger(executeTime)
+	fireTimes, err := misfireTimes(trigger, Misfire{Policy: MisfireFireOnce}, executeTime.Add(-time.Hour), executeTime.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fireTimes) != 1 || !fireTimes[0].Equal(executeTime) {
+		t.Fatal("补偿的触发时间和预期不符", fireTimes)
+	}
+
+	fireTimes, err = misfireTimes(trigger, Misfire{Policy: MisfireFireOnce}, executeTime, executeTime.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fireTimes) != 0 {
+		t.Fatal("已经触发过的一次性任务不应该补偿", fireTimes)
+	}
+}
+
+func TestLoadLastFireTimes(t *testing.T) {
+	store := NewMemoryHistoryStore(10)
+	c := &CronService{
+		tasks:         make(map[string]ITask),
+		lastFireTimes: make(map[string]time.Time),
+		historyStore:  store,
+	}
+	task := NewTaskOfConfig("load_last_fire_time", TaskConfig{
+		Trigger: mustCronTrigger("load_last_fire_time", "* * * * *"),
+		Misfire: Misfire{Policy: MisfireFireOnce},
+		Enable:  true,
+	})
+	c.tasks[task.Name()] = task
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	// 按执行结束的顺序保存, 后触发的任务先执行完毕
+	records := []*ExecutionRecord{
+		{FireTime: base, StartTime: base},
+		{FireTime: base.Add(time.Minute * 2), StartTime: base.Add(time.Minute * 2)},
+		{FireTime: base.Add(time.Minute), StartTime: base.Add(time.Minute)},
+		{FireTime: base.Add(time.Minute * 90), StartTime: base.Add(time.Minute * 90), Manual: true},
+	}
+	for _, record := range records {
+		record.TaskName = task.Name()
+		_ = store.Save(record)
+	}
+
+	c.loadLastFireTimes()
+	if fireTime := c.lastFireTimes[task.Name()]; !fireTime.Equal(base.Add(time.Minute * 2)) {
+		t.Fatal("应该使用非手动触发的记录中最大的触发时间", fireTime)
+	}
+}
@@ -0,0 +1,194 @@
+package cron
+
+import (
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/zly-app/zapp"
+	"github.com/zly-app/zapp/core"
+	"go.uber.org/zap"
+)
+
+// 根据任务配置列表生成任务名到配置的映射, 任务名重复时后面的配置生效
+func makeTaskFileConfigs(tasks []TaskFileConfig) map[string]*TaskFileConfig {
+	confs := make(map[string]*TaskFileConfig, len(tasks))
+	for i := range tasks {
+		confs[tasks[i].Name] = &tasks[i]
+	}
+	return confs
+}
+
+// 从配置中心观察任务配置
+func (c *CronService) watchTaskFileConfigs() {
+	if c.conf.TasksWatchGroup == "" || c.conf.TasksWatchKey == "" {
+		return
+	}
+	// 配置观察需要在app初始化完成后才能使用
+	zapp.AddHandler(zapp.AfterInitializeHandler, func(app core.IApp, handlerType zapp.HandlerType) {
+		w := app.GetConfig().WatchKey(c.conf.TasksWatchGroup, c.conf.TasksWatchKey)
+		w.AddCallback(func(isInit bool, oldData, newData []byte) {
+			if len(newData) == 0 {
+				app.Warn("cron服务的任务配置为空, 忽略", zap.Bool("isInit", isInit))
+				return
+			}
+			var tasks []TaskFileConfig
+			if err := w.ParseYaml(&tasks); err != nil {
+				app.Error("解析cron服务的任务配置失败", zap.String("data", string(newData)), zap.Error(err))
+				return
+			}
+			c.ReloadTaskFileConfigs(tasks)
+			app.Info("cron服务的任务配置已更新", zap.Bool("isInit", isInit), zap.Int("count", len(tasks)))
+		})
+	})
+}
+
+/*
+重新加载任务配置, 新的配置会完整替换原来的配置
+
+	只修改了 Disable 的任务会启用或禁用, 没有修改 Disable 时保持当前的启用状态, 例如通过管理接口禁用的任务
+	其它配置有变化的任务会根据代码中添加的原始任务重建触发器和执行器, 删除了配置的任务会恢复为原始任务
+	执行器相关的配置没有变化时会继续使用原来的执行器
+	正在执行的任务不受影响, 会使用原来的执行器执行完毕, 在此之前新的执行器不会开始执行
+	配置中的任务不存在时会输出警告, 任务添加时会使用这个配置
+	配置错误的任务会输出错误并保持不变
+*/
+func (c *CronService) ReloadTaskFileConfigs(tasks []TaskFileConfig) {
+	newConfs := makeTaskFileConfigs(tasks)
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	oldConfs := c.taskFileConfigs
+	names := make([]string, 0, len(oldConfs)+len(newConfs))
+	for name := range oldConfs {
+		names = append(names, name)
+	}
+	for name := range newConfs {
+		if _, ok := oldConfs[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		oldConf, newConf := oldConfs[name], newConfs[name]
+		if reflect.DeepEqual(oldConf, newConf) {
+			continue
+		}
+
+		origin, ok := c.originTasks[name]
+		if !ok {
+			if newConf != nil {
+				c.app.Warn("cron任务不存在, 将在任务添加时使用这个配置", zap.String("task", name))
+			}
+			continue
+		}
+
+		if err := c.applyTaskFileConfig(origin, oldConf, newConf); err != nil {
+			c.app.Error("cron任务的配置错误, 保持原来的配置", zap.String("task", name), zap.Any("config", newConf), zap.Error(err))
+			newConfs[name] = oldConf // 保留旧配置, 下次变更时可以正确比较
+			if oldConf == nil {
+				delete(newConfs, name)
+			}
+		}
+	}
+	c.taskFileConfigs = newConfs
+}
+
+// 将配置变更应用到任务上, 调用者需要持有锁
+func (c *CronService) applyTaskFileConfig(origin ITask, oldConf, newConf *TaskFileConfig) error {
+	task := c.tasks[origin.Name()]
+	changed := changedTaskFileConfigFields(oldConf, newConf)
+
+	// 只修改了启用状态
+	if oldConf != nil && newConf != nil && len(changed) == 1 && changed[0] == "Disable" {
+		c.enableTask(task, !newConf.Disable)
+		c.app.Info("cron任务的启用状态已根据配置更新", zap.String("task", task.Name()), zap.Bool("enable", !newConf.Disable))
+		return nil
+	}
+
+	newTask := origin
+	if newConf != nil {
+		var err error
+		if newTask, err = buildTask(origin, newConf); err != nil {
+			return err
+		}
+	}
+
+	if !containsString(changed, "Disable") {
+		newTask.setEnable(task.IsEnable())
+	}
+	oldExecutor := task.getExecutor()
+	switch {
+	case oldConf != nil && newConf != nil && !hasExecutorConfigChanged(changed):
+		newTask.setExecutor(oldExecutor)
+	case oldExecutor.IsRunning():
+		newTask.setExecutor(newDrainExecutor(oldExecutor, newTask.getExecutor()))
+	}
+
+	c.getHeapOfTime(task.TriggerTime().Unix()).Remove(task)
+	c.tasks[task.Name()] = newTask
+	if newTask.IsEnable() && c.RunState() == StartedState {
+		newTask.resetClock()
+		if _, ok := newTask.MakeNextTriggerTime(time.Now()); ok {
+			c.pushTaskToHeap(newTask)
+		}
+	}
+	c.app.Info("cron任务已根据配置重建",
+		zap.String("task", task.Name()),
+		zap.Strings("changed", changed),
+		zap.Any("config", newConf),
+	)
+	return nil
+}
+
+// 执行器相关的配置字段
+var executorConfigFields = []string{
+	"RetryCount",
+	"RetrySleepMs",
+	"RetryMultiplier",
+	"RetryMaxSleepMs",
+	"RetryJitter",
+	"RetryMaxElapsedMs",
+	"MaxConcurrentExecuteCount",
+}
+
+// 执行器相关的配置是否有变化
+func hasExecutorConfigChanged(changed []string) bool {
+	for _, field := range executorConfigFields {
+		if containsString(changed, field) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// 获取有变化的配置字段名, 新增或删除配置时返回所有有值的字段
+func changedTaskFileConfigFields(oldConf, newConf *TaskFileConfig) []string {
+	var a, b TaskFileConfig
+	if oldConf != nil {
+		a = *oldConf
+	}
+	if newConf != nil {
+		b = *newConf
+	}
+
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	var fields []string
+	for i := 0; i < va.NumField(); i++ {
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			fields = append(fields, va.Type().Field(i).Name)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,98 @@
+package cron
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReloadTaskFileConfigs(t *testing.T) {
+	c := getTestCronService(t)
+
+	handler := func(ctx IContext) error { return nil }
+	c.AddTask(NewTask("reload_a", "0 0 1 1 *", true, handler))
+	c.AddTask(NewTask("reload_b", "0 0 1 1 *", true, handler))
+	expression := func(name string) string {
+		return c.GetTask(name).getTrigger().Expression()
+	}
+
+	// 修改表达式
+	c.ReloadTaskFileConfigs([]TaskFileConfig{{Name: "reload_a", Expression: "0 0 2 1 *"}, {Name: "unknown", Expression: "@every 1s"}})
+	if expression("reload_a") != "0 0 2 1 *" || expression("reload_b") != "0 0 1 1 *" {
+		t.Fatal("表达式没有更新", expression("reload_a"), expression("reload_b"))
+	}
+	if c.GetTask("unknown") != nil {
+		t.Fatal("不存在的任务不应该被添加")
+	}
+
+	// 只修改启用状态时不重建任务
+	task := c.GetTask("reload_a")
+	c.ReloadTaskFileConfigs([]TaskFileConfig{{Name: "reload_a", Expression: "0 0 2 1 *", Disable: true}})
+	if c.GetTask("reload_a") != task || task.IsEnable() {
+		t.Fatal("禁用任务失败")
+	}
+
+	// 配置错误时保持不变
+	c.ReloadTaskFileConfigs([]TaskFileConfig{{Name: "reload_a", Expression: "bad expression"}})
+	if c.GetTask("reload_a") != task {
+		t.Fatal("配置错误时任务不应该被修改")
+	}
+
+	// 删除配置后恢复为原始任务
+	c.ReloadTaskFileConfigs(nil)
+	if expression("reload_a") != "0 0 1 1 *" || !c.GetTask("reload_a").IsEnable() {
+		t.Fatal("删除配置后没有恢复为原始任务")
+	}
+
+	// 配置在任务添加时生效
+	c.ReloadTaskFileConfigs([]TaskFileConfig{{Name: "reload_c", Expression: "0 0 3 1 *"}})
+	c.AddTask(NewTask("reload_c", "0 0 1 1 *", true, handler))
+	if expression("reload_c") != "0 0 3 1 *" {
+		t.Fatal("任务添加时没有使用配置", expression("reload_c"))
+	}
+}
+
+func TestMakeTaskFileConfigs(t *testing.T) {
+	confs := makeTaskFileConfigs([]TaskFileConfig{{Name: "a", Expression: "1"}, {Name: "b", Expression: "2"}})
+	if confs["a"].Expression != "1" || confs["b"].Expression != "2" {
+		t.Fatal("配置和预期不符")
+	}
+}
+
+func TestReloadTaskFileConfigsKeepState(t *testing.T) {
+	c := getTestCronService(t)
+
+	c.AddTask(NewTask("reload_state", "0 0 1 1 *", true, func(ctx IContext) error { return nil }))
+	c.ReloadTaskFileConfigs([]TaskFileConfig{{Name: "reload_state", Expression: "0 0 2 1 *"}})
+
+	// 运行时禁用的任务在修改其它配置后保持禁用
+	c.EnableTask(c.GetTask("reload_state"), false)
+	executor := c.GetTask("reload_state").getExecutor()
+	c.ReloadTaskFileConfigs([]TaskFileConfig{{Name: "reload_state", Expression: "0 0 3 1 *"}})
+	task := c.GetTask("reload_state")
+	if task.IsEnable() {
+		t.Fatal("修改其它配置后任务不应该被启用")
+	}
+	if task.getExecutor() != executor {
+		t.Fatal("执行器配置没有变化时应该使用原来的执行器")
+	}
+
+	// 旧执行器正在执行时新执行器不会开始执行
+	started, done := make(chan struct{}), make(chan struct{})
+	go executor.Do(context.Background(), func(retryNums int) (IContext, error) {
+		close(started)
+		<-done
+		return nil, nil
+	}, nil)
+	<-started
+	c.ReloadTaskFileConfigs([]TaskFileConfig{{Name: "reload_state", Expression: "0 0 3 1 *", MaxConcurrentExecuteCount: 2}})
+	newExecutor := c.GetTask("reload_state").getExecutor()
+	onDo := func(retryNums int) (IContext, error) { return nil, nil }
+	if err := newExecutor.Do(context.Background(), onDo, nil); err != OutOfMaxConcurrentExecuteCount {
+		t.Fatal("旧执行器正在执行时新执行器不应该开始执行", err)
+	}
+	close(done)
+	executor.Wait()
+	if err := newExecutor.Do(context.Background(), onDo, nil); err != nil {
+		t.Fatal("旧执行器执行完毕后新执行器应该可以执行", err)
+	}
+}
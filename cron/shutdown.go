@@ -0,0 +1,133 @@
+package cron
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 重置任务执行的基础ctx和停止信号, 服务创建和启动时调用
+func (c *CronService) resetRunContext() {
+	c.runningMx.Lock()
+	if c.runCtx == nil || c.runCtx.Err() != nil {
+		c.runCtx, c.runCancel = context.WithCancel(context.Background())
+	}
+	if c.stopChan == nil {
+		c.stopChan = make(chan struct{})
+	}
+	select {
+	case <-c.stopChan:
+		c.stopChan = make(chan struct{})
+	default:
+	}
+	c.runningMx.Unlock()
+}
+
+// 获取任务执行的基础ctx, 带有停止信号
+func (c *CronService) runContext() context.Context {
+	c.runningMx.Lock()
+	ctx := withStopSignal(c.runCtx, c.stopChan)
+	c.runningMx.Unlock()
+	return ctx
+}
+
+// 是否正在关闭服务
+func (c *CronService) isStopping() bool {
+	c.runningMx.Lock()
+	defer c.runningMx.Unlock()
+	select {
+	case <-c.stopChan:
+		return true
+	default:
+		return false
+	}
+}
+
+// 发送停止信号, 不再执行新的任务, 正在等待重试的任务会停止等待
+func (c *CronService) sendStopSignal() {
+	c.runningMx.Lock()
+	select {
+	case <-c.stopChan:
+	default:
+		close(c.stopChan)
+	}
+	c.runningMx.Unlock()
+}
+
+// 取消正在执行的任务的ctx
+func (c *CronService) cancelRunContext() {
+	c.runningMx.Lock()
+	c.runCancel()
+	c.runningMx.Unlock()
+}
+
+// 修改已分发还未结束的任务数
+func (c *CronService) addDispatched(delta int) {
+	c.runningMx.Lock()
+	c.dispatched += delta
+	if c.dispatched == 0 {
+		c.idleCond.Broadcast()
+	}
+	c.runningMx.Unlock()
+}
+
+// 等待已分发的任务和所有执行器中的任务结束, 结束后关闭返回的chan
+func (c *CronService) waitIdle() <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		c.runningMx.Lock()
+		for c.dispatched > 0 {
+			c.idleCond.Wait()
+		}
+		c.runningMx.Unlock()
+
+		for _, task := range c.Tasks() {
+			task.wait()
+		}
+		close(done)
+	}()
+	return done
+}
+
+// 关闭服务时等待任务结束
+//
+// 先发送停止信号, 在宽限时间内等待任务结束, 超过宽限时间后取消任务的ctx再等待一段时间, 仍未结束的任务会输出任务名和已执行时间
+func (c *CronService) shutdown() {
+	c.sendStopSignal()
+	done := c.waitIdle()
+
+	grace := time.Duration(c.conf.ShutdownGracePeriodMs) * time.Millisecond
+	if running := c.RunningExecutions(); len(running) > 0 {
+		c.app.Info("cron服务正在等待任务结束", zap.Int("running", len(running)), zap.Duration("gracePeriod", grace))
+	}
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-done:
+		c.cancelRunContext()
+		return
+	case <-timer.C:
+	}
+
+	c.app.Warn("cron服务等待任务结束超过宽限时间, 取消正在执行的任务", zap.Int("running", len(c.RunningExecutions())))
+	c.cancelRunContext()
+
+	timer.Reset(time.Duration(c.conf.ShutdownWaitTimeoutMs) * time.Millisecond)
+	select {
+	case <-done:
+		return
+	case <-timer.C:
+	}
+
+	now := time.Now()
+	for _, e := range c.RunningExecutions() {
+		c.app.Error("cron服务关闭时任务仍未结束",
+			zap.String("task_name", e.TaskName),
+			zap.Duration("elapsed", now.Sub(e.StartTime)),
+			zap.Time("fireTime", e.FireTime),
+			zap.Bool("manual", e.Manual),
+		)
+	}
+}
@@ -0,0 +1,68 @@
+package cron
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGracefulShutdown(t *testing.T) {
+	c := getTestCronService(t)
+	grace, waitTimeout := c.conf.ShutdownGracePeriodMs, c.conf.ShutdownWaitTimeoutMs
+	c.conf.ShutdownGracePeriodMs, c.conf.ShutdownWaitTimeoutMs = 200, 200
+	defer func() {
+		c.conf.ShutdownGracePeriodMs, c.conf.ShutdownWaitTimeoutMs = grace, waitTimeout
+	}()
+
+	var cancelled, retried int32
+	c.AddTask(NewTask("shutdown_wait_ctx", "0 0 1 1 *", true, func(ctx IContext) error {
+		<-ctx.Done()
+		atomic.StoreInt32(&cancelled, 1)
+		return ctx.Err()
+	}))
+	c.AddTask(NewTaskOfConfig("shutdown_retry", TaskConfig{
+		Trigger:  mustCronTrigger("shutdown_retry", "0 0 1 1 *"),
+		Executor: NewExecutor(1, time.Hour, 1),
+		Handler: func(ctx IContext) error {
+			atomic.AddInt32(&retried, 1)
+			return errors.New("err")
+		},
+		Enable: true,
+	}))
+	c.AddTask(NewTask("shutdown_ignore_ctx", "0 0 1 1 *", true, func(ctx IContext) error {
+		time.Sleep(time.Second)
+		return nil
+	}))
+	defer func() {
+		time.Sleep(time.Second) // 等待 shutdown_ignore_ctx 结束
+		c.RemoveTask("shutdown_wait_ctx")
+		c.RemoveTask("shutdown_retry")
+		c.RemoveTask("shutdown_ignore_ctx")
+	}()
+
+	_ = c.Start()
+	for _, name := range []string{"shutdown_wait_ctx", "shutdown_retry", "shutdown_ignore_ctx"} {
+		if err := c.TriggerTask(name, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(time.Millisecond * 50)
+
+	start := time.Now()
+	_ = c.Close()
+	if d := time.Since(start); d < time.Millisecond*400 || d > time.Millisecond*800 {
+		t.Fatal("关闭服务的等待时间和预期不符", d)
+	}
+	if atomic.LoadInt32(&cancelled) != 1 {
+		t.Fatal("超过宽限时间后应该取消任务的ctx")
+	}
+	if atomic.LoadInt32(&retried) != 1 {
+		t.Fatal("关闭服务时应该停止等待重试", retried)
+	}
+
+	running := c.RunningExecutions()
+	if len(running) != 1 || running[0].TaskName != "shutdown_ignore_ctx" {
+		t.Fatal("仍在执行的任务和预期不符", running)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/zly-app/zapp/logger"
 	"github.com/zly-app/zapp/pkg/utils"
 	"go.uber.org/zap"
 )
@@ -19,14 +20,30 @@ type ITask interface {
 	IsEnable() bool
 	// 执行超时时间
 	Timeout() time.Duration
+	// 错过触发的处理配置
+	Misfire() Misfire
 
 	// 获取触发时间
 	TriggerTime() time.Time
 	// 生成下次触发时间, 如果返回了 false 表示没有下一次了, 返回的时间一定>t
+	//
+	// 触发器中止查找时也会返回 true, 这时触发时间为继续查找的检查时间, 参考 IScanLimitedTrigger
 	MakeNextTriggerTime(t time.Time) (time.Time, bool)
 	// 立即触发执行, 阻塞等待执行结束
 	Trigger(ctx context.Context) error
 
+	// 执行, 阻塞等待执行结束, 返回执行次数(包含重试)
+	execute(ctx context.Context) (attempts int, err error)
+	// 获取触发器
+	getTrigger() ITrigger
+	// 获取执行器
+	getExecutor() IExecutor
+	// 设置执行器, 重载配置时使用
+	setExecutor(executor IExecutor)
+	// 触发时间是否为继续查找的检查时间, 到达检查时间时不会执行任务
+	isScanCheckpoint() bool
+	// 等待执行器中的任务执行完毕
+	wait()
 	// 重置定时, 发生在被定时器添加任务时和重新设为启用时
 	resetClock()
 	// 设置启用
@@ -43,12 +60,14 @@ type Task struct {
 	handler Handler
 
 	triggerTime time.Time
+	checkpoint  bool // triggerTime 是否为继续查找的检查时间
 	trigger     ITrigger
 	executor    IExecutor
 	timeout     time.Duration // 执行超时时间
+	misfire     Misfire
 
 	enable int32
-	mx     sync.Mutex // 用于锁 triggerTime, trigger, executor
+	mx     sync.Mutex // 用于锁 triggerTime, checkpoint, trigger, executor
 
 	heapIndex int // 堆索引
 }
@@ -59,11 +78,12 @@ type TaskConfig struct {
 	Handler  Handler
 	TimeOut  time.Duration
 	Enable   bool
+	Misfire  Misfire // 错过触发的处理配置
 }
 
-// 创建一个任务
+// 创建一个任务, cron表达式错误时会退出程序
 func NewTask(name string, expression string, enable bool, handler Handler) ITask {
-	trigger := NewCronTrigger(expression)
+	trigger := mustCronTrigger(name, expression)
 	executor := NewExecutor(0, 0, 1)
 	return NewTaskOfConfig(name, TaskConfig{
 		Trigger:  trigger,
@@ -82,11 +102,25 @@ func NewTaskOfConfig(name string, config TaskConfig) ITask {
 		executor: config.Executor,
 		handler:  config.Handler,
 		timeout:  config.TimeOut,
+		misfire:  config.Misfire,
 	}
 	t.setEnable(config.Enable)
 	return t
 }
 
+// 创建cron触发器, 表达式错误时会退出程序
+func mustCronTrigger(name string, expression string, opts ...CronTriggerOption) ITrigger {
+	trigger, err := NewCronTrigger(expression, opts...)
+	if err != nil {
+		logger.Log.Fatal("创建cron触发器失败",
+			zap.String("task", name),
+			zap.String("expression", expression),
+			zap.Error(err),
+		)
+	}
+	return trigger
+}
+
 func (t *Task) Name() string {
 	return t.name
 }
@@ -99,6 +133,9 @@ func (t *Task) IsEnable() bool {
 func (t *Task) Timeout() time.Duration {
 	return t.timeout
 }
+func (t *Task) Misfire() Misfire {
+	return t.misfire
+}
 func (t *Task) TriggerTime() time.Time {
 	t.mx.Lock()
 	tt := t.triggerTime
@@ -111,17 +148,20 @@ func (t *Task) MakeNextTriggerTime(tt time.Time) (time.Time, bool) {
 	}
 
 	tt, ok := t.trigger.MakeNextTriggerTime(tt)
+	checkpoint := !ok && isScanExhausted(t.trigger)
 	t.mx.Lock()
 	t.triggerTime = tt
+	t.checkpoint = checkpoint
 	t.mx.Unlock()
-	return tt, ok
+	return tt, ok || checkpoint
 }
 func (t *Task) Trigger(ctx context.Context) error {
-	return t.execute(ctx)
+	_, err := t.execute(ctx)
+	return err
 }
 
-// 执行
-func (t *Task) execute(ctx context.Context) error {
+// 执行, 返回执行次数(包含重试)
+func (t *Task) execute(ctx context.Context) (attempts int, err error) {
 	t.mx.Lock()
 	executor := t.executor
 	t.mx.Unlock()
@@ -130,21 +170,54 @@ func (t *Task) execute(ctx context.Context) error {
 	defer utils.Otel.EndSpan(span)
 
 	onDo := func(retryNums int) (IContext, error) {
+		attempts++
+		// 每次执行单独计算超时
+		attemptCtx := doCtx
 		if t.timeout > 0 {
-			timeoutCtx, cancel := context.WithTimeout(doCtx, t.timeout)
+			var cancel context.CancelFunc
+			attemptCtx, cancel = context.WithTimeout(doCtx, t.timeout)
 			defer cancel()
-			doCtx = timeoutCtx
 		}
 
-		iCtx := newContext(doCtx, t)
+		iCtx := newContext(attemptCtx, t)
 		return iCtx, t.handler(iCtx)
 	}
-	return executor.Do(onDo, t.errCallback)
+	err = executor.Do(doCtx, onDo, t.errCallback)
+	return attempts, err
 }
 func (t *Task) errCallback(ctx IContext, err error) {
 	ctx.Warn(ctx, "cron.error! try retry", zap.String("err", utils.Recover.GetRecoverErrorDetail(err)))
 }
 
+func (t *Task) wait() {
+	t.mx.Lock()
+	executor := t.executor
+	t.mx.Unlock()
+	executor.Wait()
+}
+
+func (t *Task) getTrigger() ITrigger {
+	t.mx.Lock()
+	trigger := t.trigger
+	t.mx.Unlock()
+	return trigger
+}
+func (t *Task) getExecutor() IExecutor {
+	t.mx.Lock()
+	executor := t.executor
+	t.mx.Unlock()
+	return executor
+}
+func (t *Task) setExecutor(executor IExecutor) {
+	t.mx.Lock()
+	t.executor = executor
+	t.mx.Unlock()
+}
+func (t *Task) isScanCheckpoint() bool {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	return t.checkpoint
+}
 func (t *Task) resetClock() {
 	t.mx.Lock()
 	trigger := t.trigger
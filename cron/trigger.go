@@ -2,6 +2,7 @@ package cron
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,18 +17,45 @@ const (
 	CronTriggerType TriggerType = iota
 	// 一次性触发器
 	OnceTriggerType
+	// 固定延迟触发器
+	FixedDelayTriggerType
+	// 固定频率触发器
+	FixedRateTriggerType
+	// 日历排除触发器
+	ExcludeTriggerType
+	// 并集组合触发器
+	UnionTriggerType
+	// 交集组合触发器
+	IntersectionTriggerType
 )
 
+var triggerTypeNames = map[TriggerType]string{
+	CronTriggerType:         "cron",
+	OnceTriggerType:         "once",
+	FixedDelayTriggerType:   "fixed_delay",
+	FixedRateTriggerType:    "fixed_rate",
+	ExcludeTriggerType:      "exclude",
+	UnionTriggerType:        "union",
+	IntersectionTriggerType: "intersection",
+}
+
 func (t TriggerType) String() string {
-	switch t {
-	case CronTriggerType:
-		return "cron"
-	case OnceTriggerType:
-		return "once"
+	if name, ok := triggerTypeNames[t]; ok {
+		return name
 	}
 	return fmt.Sprintf("undefined trigger type: %d", t)
 }
 
+// 根据名称获取触发器类型
+func ParseTriggerType(name string) (TriggerType, error) {
+	for t, n := range triggerTypeNames {
+		if n == name {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported trigger type %q", name)
+}
+
 type ITrigger interface {
 	// 触发器类型
 	TriggerType() TriggerType
@@ -40,6 +68,21 @@ type ITrigger interface {
 	MakeNextTriggerTime(t time.Time) (time.Time, bool)
 }
 
+// 查找下次触发时间时会限制检查次数的触发器
+//
+// MakeNextTriggerTime 因为超出检查次数而返回 false 时, 返回的时间为最后检查的时间, 这时 ScanExhausted 返回 true,
+// 调度器会在这个时间从中止的地方继续查找, 不会执行任务
+type IScanLimitedTrigger interface {
+	// 上次生成触发时间时是否因为超出检查次数而中止查找
+	ScanExhausted() bool
+}
+
+// 触发器上次生成触发时间时是否中止了查找
+func isScanExhausted(trigger ITrigger) bool {
+	st, ok := trigger.(IScanLimitedTrigger)
+	return ok && st.ScanExhausted()
+}
+
 // -------------- cron触发器 --------------------
 
 // cron触发器
@@ -50,18 +93,51 @@ type CronTrigger struct {
 	mx              sync.Mutex // 用于锁 nextExecuteTime
 }
 
-// 创建一个cron触发器
-func NewCronTrigger(expression string) ITrigger {
-	schedule, err := cron.ParseStandard(expression)
+type cronTriggerOptions struct {
+	format string
+	loc    *time.Location
+}
+
+// cron触发器选项
+type CronTriggerOption func(opts *cronTriggerOptions)
+
+// 设置表达式格式, 参考 ExpressionAuto 等, 默认自动识别
+func WithExpressionFormat(format string) CronTriggerOption {
+	return func(opts *cronTriggerOptions) {
+		opts.format = format
+	}
+}
+
+// 设置时区, 表达式中有 CRON_TZ= 前缀时以表达式为准, 默认为本地时区
+func WithTimezone(loc *time.Location) CronTriggerOption {
+	return func(opts *cronTriggerOptions) {
+		opts.loc = loc
+	}
+}
+
+// 创建一个cron触发器, 表达式错误时返回错误
+//
+// 表达式支持秒字段, 时区和扩展语法, 参考 parseCronExpression
+func NewCronTrigger(expression string, opts ...CronTriggerOption) (ITrigger, error) {
+	o := cronTriggerOptions{loc: time.Local}
+	for _, fn := range opts {
+		fn(&o)
+	}
+
+	schedule, err := parseCronExpression(expression, o.format, o.loc)
 	if err != nil {
-		panic(fmt.Errorf("expression syntax error, %s", err))
+		return nil, fmt.Errorf("expression syntax error, %s", err)
 	}
 
+	// 设置了时区时保留在表达式中便于查看
+	if o.loc != time.Local && !strings.HasPrefix(expression, "TZ=") && !strings.HasPrefix(expression, "CRON_TZ=") {
+		expression = "CRON_TZ=" + o.loc.String() + " " + expression
+	}
 	return &CronTrigger{
 		expression:      expression,
 		schedule:        schedule,
 		nextExecuteTime: schedule.Next(time.Now()),
-	}
+	}, nil
 }
 
 func (c *CronTrigger) TriggerType() TriggerType {
@@ -78,12 +154,18 @@ func (c *CronTrigger) ResetClock() {
 }
 func (c *CronTrigger) MakeNextTriggerTime(t time.Time) (time.Time, bool) {
 	c.mx.Lock()
-	for t.Unix() >= c.nextExecuteTime.Unix() {
+	defer c.mx.Unlock()
+	for !c.nextExecuteTime.IsZero() && t.Unix() >= c.nextExecuteTime.Unix() {
 		c.nextExecuteTime = c.schedule.Next(c.nextExecuteTime)
 	}
-	t = c.nextExecuteTime
-	c.mx.Unlock()
-	return t, true
+	if c.nextExecuteTime.IsZero() { // 没有下一次了
+		return t, false
+	}
+	return c.nextExecuteTime, true
+}
+func (c *CronTrigger) NextTriggerTimeAfter(t time.Time) (time.Time, bool) {
+	next := c.schedule.Next(t)
+	return next, !next.IsZero()
 }
 
 // --------------- 一次性触发器 -------------------
@@ -119,3 +201,6 @@ func (o *OnceTrigger) MakeNextTriggerTime(t time.Time) (time.Time, bool) {
 	}
 	return t, false
 }
+func (o *OnceTrigger) NextTriggerTimeAfter(t time.Time) (time.Time, bool) {
+	return o.MakeNextTriggerTime(t)
+}
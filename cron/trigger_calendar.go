@@ -0,0 +1,188 @@
+package cron
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 日期格式
+const CalendarDateLayout = "2006-01-02"
+
+// 查找未被排除的触发时间时最多检查的次数, 超出后会中止查找, 参考 IScanLimitedTrigger
+const maxExcludeScanCount = 10000
+
+// 日历, 用于排除节假日等
+type ICalendar interface {
+	// 时间 t 是否被排除
+	IsExcluded(t time.Time) bool
+	// 描述
+	String() string
+}
+
+// 可以跳过整段排除时间的日历, 排除触发器会直接从排除时间段之后继续查找, 而不是逐个检查被排除的触发时间
+type IRangeCalendar interface {
+	// 如果时间 t 被排除, 返回之后第一个未被排除的时间, 否则返回 t
+	NextIncludedTime(t time.Time) time.Time
+}
+
+// -------------- 日期日历 --------------------
+
+// 日期日历, 排除指定的日期
+type DateCalendar struct {
+	dates map[string]struct{}
+	loc   *time.Location
+}
+
+// 创建日期日历, dates 的格式为 YYYY-MM-dd, loc 为判断日期使用的时区, 为nil时使用本地时区
+func NewDateCalendar(dates []string, loc *time.Location) (ICalendar, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	c := &DateCalendar{dates: make(map[string]struct{}, len(dates)), loc: loc}
+	for _, date := range dates {
+		date = strings.TrimSpace(date)
+		if _, err := time.ParseInLocation(CalendarDateLayout, date, loc); err != nil {
+			return nil, fmt.Errorf("invalid date %q, the layout must be YYYY-MM-dd", date)
+		}
+		c.dates[date] = struct{}{}
+	}
+	return c, nil
+}
+
+// 从文件加载日期日历, 文件中每行一个日期, 格式为 YYYY-MM-dd, 忽略空行和#开头的注释
+func NewDateCalendarFromFile(file string, loc *time.Location) (ICalendar, error) {
+	dates, err := readDateFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return NewDateCalendar(dates, loc)
+}
+
+// 读取日期文件
+func readDateFile(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var dates []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		dates = append(dates, line)
+	}
+	return dates, scanner.Err()
+}
+
+func (c *DateCalendar) IsExcluded(t time.Time) bool {
+	_, ok := c.dates[t.In(c.loc).Format(CalendarDateLayout)]
+	return ok
+}
+
+func (c *DateCalendar) NextIncludedTime(t time.Time) time.Time {
+	if !c.IsExcluded(t) {
+		return t
+	}
+	// 跳到下一天的开始, 连续排除的日期最多跳过 len(c.dates) 次
+	y, m, d := t.In(c.loc).Date()
+	for i := 0; i < len(c.dates); i++ {
+		d++
+		t = time.Date(y, m, d, 0, 0, 0, 0, c.loc)
+		if !c.IsExcluded(t) {
+			break
+		}
+	}
+	return t
+}
+
+func (c *DateCalendar) String() string {
+	dates := make([]string, 0, len(c.dates))
+	for date := range c.dates {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+	return "dates(" + strings.Join(dates, ",") + ")"
+}
+
+// -------------- 日历排除触发器 --------------------
+
+// 日历排除触发器, 跳过被日历排除的触发时间
+type ExcludeTrigger struct {
+	trigger   ITrigger
+	calendar  ICalendar
+	exhausted bool // 上次生成触发时间时是否超出检查次数
+	mx        sync.Mutex
+}
+
+// 创建日历排除触发器, trigger 不能是需要执行结果反馈的触发器
+func NewExcludeTrigger(trigger ITrigger, calendar ICalendar) (ITrigger, error) {
+	if _, ok := trigger.(IFeedbackTrigger); ok {
+		return nil, fmt.Errorf("%s trigger can not be excluded by calendar", trigger.TriggerType())
+	}
+	return &ExcludeTrigger{trigger: trigger, calendar: calendar}, nil
+}
+
+func (e *ExcludeTrigger) TriggerType() TriggerType {
+	return ExcludeTriggerType
+}
+func (e *ExcludeTrigger) Expression() string {
+	return e.trigger.Expression() + " exclude " + e.calendar.String()
+}
+
+func (e *ExcludeTrigger) ResetClock() {
+	e.trigger.ResetClock()
+}
+func (e *ExcludeTrigger) MakeNextTriggerTime(t time.Time) (time.Time, bool) {
+	n, ok, exhausted := e.skipExcluded(t, e.trigger.MakeNextTriggerTime)
+	if !ok && !exhausted {
+		exhausted = isScanExhausted(e.trigger)
+	}
+	e.mx.Lock()
+	e.exhausted = exhausted
+	e.mx.Unlock()
+	return n, ok
+}
+func (e *ExcludeTrigger) NextTriggerTimeAfter(t time.Time) (time.Time, bool) {
+	mt, ok := e.trigger.(IMisfireTrigger)
+	if !ok {
+		return t, false
+	}
+	n, ok, _ := e.skipExcluded(t, mt.NextTriggerTimeAfter)
+	return n, ok
+}
+func (e *ExcludeTrigger) ScanExhausted() bool {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+	return e.exhausted
+}
+
+// 跳过被排除的触发时间, 超出检查次数时 exhausted 为 true, 返回最后检查的时间
+func (e *ExcludeTrigger) skipExcluded(t time.Time, next func(t time.Time) (time.Time, bool)) (_ time.Time, ok bool, exhausted bool) {
+	rc, isRange := e.calendar.(IRangeCalendar)
+	for i := 0; i < maxExcludeScanCount; i++ {
+		n, ok := next(t)
+		if !ok {
+			return t, false, false
+		}
+		if !e.calendar.IsExcluded(n) {
+			return n, true, false
+		}
+		t = n
+		// 跳到排除时间段的最后
+		if isRange {
+			if included := rc.NextIncludedTime(n); included.After(n) {
+				t = included.Add(-time.Nanosecond)
+			}
+		}
+	}
+	return t, false, true
+}
@@ -0,0 +1,150 @@
+package cron
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 查找交集触发时间时最多检查的次数, 超出后会中止查找, 参考 IScanLimitedTrigger
+const maxIntersectionScanCount = 10000
+
+// 组合触发器
+type CompositeTrigger struct {
+	triggerType TriggerType
+	triggers    []ITrigger
+	exhausted   bool // 上次生成触发时间时是否超出检查次数
+	mx          sync.Mutex
+}
+
+// 创建并集组合触发器, 任何一个触发器触发时都会触发, 同一时间只触发一次
+func NewUnionTrigger(triggers ...ITrigger) (ITrigger, error) {
+	return newCompositeTrigger(UnionTriggerType, triggers)
+}
+
+// 创建交集组合触发器, 所有触发器在同一时间都触发时才会触发
+//
+// 所有触发器都必须实现 IMisfireTrigger
+func NewIntersectionTrigger(triggers ...ITrigger) (ITrigger, error) {
+	return newCompositeTrigger(IntersectionTriggerType, triggers)
+}
+
+func newCompositeTrigger(triggerType TriggerType, triggers []ITrigger) (ITrigger, error) {
+	if len(triggers) == 0 {
+		return nil, fmt.Errorf("%s trigger requires at least one trigger", triggerType)
+	}
+	for _, t := range triggers {
+		if _, ok := t.(IFeedbackTrigger); ok {
+			return nil, fmt.Errorf("%s trigger can not be combined", t.TriggerType())
+		}
+		if _, ok := t.(IMisfireTrigger); !ok && triggerType == IntersectionTriggerType {
+			return nil, fmt.Errorf("%s trigger can not be intersected", t.TriggerType())
+		}
+	}
+	return &CompositeTrigger{triggerType: triggerType, triggers: triggers}, nil
+}
+
+func (c *CompositeTrigger) TriggerType() TriggerType {
+	return c.triggerType
+}
+func (c *CompositeTrigger) Expression() string {
+	expressions := make([]string, len(c.triggers))
+	for i, t := range c.triggers {
+		expressions[i] = t.Expression()
+	}
+	return c.triggerType.String() + "(" + strings.Join(expressions, "; ") + ")"
+}
+
+func (c *CompositeTrigger) ResetClock() {
+	for _, t := range c.triggers {
+		t.ResetClock()
+	}
+}
+func (c *CompositeTrigger) MakeNextTriggerTime(t time.Time) (time.Time, bool) {
+	if c.triggerType == IntersectionTriggerType {
+		n, ok, exhausted := c.nextIntersection(t)
+		c.mx.Lock()
+		c.exhausted = exhausted
+		c.mx.Unlock()
+		return n, ok
+	}
+
+	// 有触发器中止查找时, 取所有中止查找的触发器中最早的检查时间
+	var next, checkpoint time.Time
+	for _, trigger := range c.triggers {
+		n, ok := trigger.MakeNextTriggerTime(t)
+		if ok && (next.IsZero() || n.Unix() < next.Unix()) {
+			next = n
+		}
+		if !ok && isScanExhausted(trigger) && (checkpoint.IsZero() || n.Before(checkpoint)) {
+			checkpoint = n
+		}
+	}
+	if !checkpoint.IsZero() && (next.IsZero() || checkpoint.Before(next)) {
+		next = checkpoint
+	}
+	exhausted := !checkpoint.IsZero() && next.Equal(checkpoint)
+	c.mx.Lock()
+	c.exhausted = exhausted
+	c.mx.Unlock()
+	if next.IsZero() {
+		return t, false
+	}
+	return next, !exhausted
+}
+
+// 返回 t 之后的触发时间, 并集组合触发器中有触发器没有实现 IMisfireTrigger 时忽略这个触发器
+func (c *CompositeTrigger) NextTriggerTimeAfter(t time.Time) (time.Time, bool) {
+	if c.triggerType == UnionTriggerType {
+		var next time.Time
+		for _, trigger := range c.triggers {
+			mt, ok := trigger.(IMisfireTrigger)
+			if !ok {
+				continue
+			}
+			n, ok := mt.NextTriggerTimeAfter(t)
+			if ok && (next.IsZero() || n.Unix() < next.Unix()) {
+				next = n
+			}
+		}
+		if next.IsZero() {
+			return t, false
+		}
+		return next, true
+	}
+
+	n, ok, _ := c.nextIntersection(t)
+	return n, ok
+}
+func (c *CompositeTrigger) ScanExhausted() bool {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.exhausted
+}
+
+// 查找 t 之后所有触发器都触发的时间, 超出检查次数时 exhausted 为 true, 返回最后检查的时间
+func (c *CompositeTrigger) nextIntersection(t time.Time) (_ time.Time, ok bool, exhausted bool) {
+	// 每次从所有触发器中最晚的触发时间开始重新查找, 直到所有触发器的触发时间相同
+	for i := 0; i < maxIntersectionScanCount; i++ {
+		var latest time.Time
+		same := true
+		for j, trigger := range c.triggers {
+			n, ok := trigger.(IMisfireTrigger).NextTriggerTimeAfter(t)
+			if !ok {
+				return t, false, false
+			}
+			if j > 0 && n.Unix() != latest.Unix() {
+				same = false
+			}
+			if n.After(latest) {
+				latest = n
+			}
+		}
+		if same {
+			return latest, true, false
+		}
+		t = latest.Add(-time.Second)
+	}
+	return t, false, true
+}
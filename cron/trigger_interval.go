@@ -0,0 +1,135 @@
+package cron
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 需要执行结果反馈的触发器, 每次调度器触发的执行结束后会调用 ExecuteFinished, 然后重新计算下次触发时间
+type IFeedbackTrigger interface {
+	// 执行结束
+	ExecuteFinished(end time.Time)
+}
+
+// -------------- 固定延迟触发器 --------------------
+
+// 固定延迟触发器, 上一次执行结束后延迟 delay 再触发, 执行期间不会触发
+type FixedDelayTrigger struct {
+	delay           time.Duration
+	nextExecuteTime time.Time
+	waiting         bool // 是否在等待执行结束
+	mx              sync.Mutex
+}
+
+// 创建一个固定延迟触发器, 启动后延迟 delay 第一次触发, delay 最小为1秒
+func NewFixedDelayTrigger(delay time.Duration) (ITrigger, error) {
+	if delay < time.Second {
+		return nil, fmt.Errorf("delay must not be less than 1s")
+	}
+	return &FixedDelayTrigger{
+		delay:           delay,
+		nextExecuteTime: time.Now().Add(delay),
+	}, nil
+}
+
+func (f *FixedDelayTrigger) TriggerType() TriggerType {
+	return FixedDelayTriggerType
+}
+func (f *FixedDelayTrigger) Expression() string {
+	return "@delay " + f.delay.String()
+}
+
+func (f *FixedDelayTrigger) ResetClock() {
+	f.mx.Lock()
+	f.waiting = false
+	f.nextExecuteTime = time.Now().Add(f.delay)
+	f.mx.Unlock()
+}
+
+// 到达触发时间后进入等待状态, 直到执行结束前都没有下一次
+func (f *FixedDelayTrigger) MakeNextTriggerTime(t time.Time) (time.Time, bool) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	if f.waiting {
+		return t, false
+	}
+	if t.Unix() >= f.nextExecuteTime.Unix() { // 已触发
+		f.waiting = true
+		return t, false
+	}
+	return f.nextExecuteTime, true
+}
+
+func (f *FixedDelayTrigger) ExecuteFinished(end time.Time) {
+	f.mx.Lock()
+	f.waiting = false
+	f.nextExecuteTime = end.Add(f.delay)
+	f.mx.Unlock()
+}
+
+// -------------- 固定频率触发器 --------------------
+
+// 固定频率触发器, 从 start 开始每隔 interval 触发一次, 直到 end
+//
+// 触发时间和执行耗时无关, 不同副本计算的触发时间相同
+type FixedRateTrigger struct {
+	interval time.Duration
+	start    time.Time
+	end      time.Time
+}
+
+// 创建一个固定频率触发器, interval 最小为1秒
+//
+// start 为零值时以unix时间0为起点, 即触发时间是 interval 的整数倍. end 为零值时表示不结束
+func NewFixedRateTrigger(interval time.Duration, start, end time.Time) (ITrigger, error) {
+	if interval < time.Second {
+		return nil, fmt.Errorf("interval must not be less than 1s")
+	}
+	if !start.IsZero() && !end.IsZero() && end.Before(start) {
+		return nil, fmt.Errorf("end time must not be before start time")
+	}
+	return &FixedRateTrigger{interval: interval, start: start, end: end}, nil
+}
+
+func (f *FixedRateTrigger) TriggerType() TriggerType {
+	return FixedRateTriggerType
+}
+func (f *FixedRateTrigger) Expression() string {
+	expression := "@rate " + f.interval.String()
+	if !f.start.IsZero() {
+		expression += " from " + f.start.Format(OnceTriggerTimeLayout)
+	}
+	if !f.end.IsZero() {
+		expression += " to " + f.end.Format(OnceTriggerTimeLayout)
+	}
+	return expression
+}
+
+func (f *FixedRateTrigger) ResetClock() {
+}
+func (f *FixedRateTrigger) MakeNextTriggerTime(t time.Time) (time.Time, bool) {
+	next, ok := f.NextTriggerTimeAfter(t)
+	if !ok {
+		return t, false
+	}
+	return next, true
+}
+func (f *FixedRateTrigger) NextTriggerTimeAfter(t time.Time) (time.Time, bool) {
+	start := f.start
+	if start.IsZero() {
+		start = time.Unix(0, 0)
+	}
+
+	var next time.Time
+	if t.Before(start) {
+		next = start
+	} else {
+		n := t.Sub(start)/f.interval + 1
+		next = start.Add(n * f.interval)
+	}
+	if !f.end.IsZero() && next.After(f.end) {
+		return time.Time{}, false
+	}
+	return next, true
+}
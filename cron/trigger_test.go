@@ -0,0 +1,244 @@
+package cron
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFixedDelayTrigger(t *testing.T) {
+	trigger, err := NewFixedDelayTrigger(time.Second * 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	next, ok := trigger.MakeNextTriggerTime(now)
+	if !ok || next.Unix() != now.Add(time.Second*10).Unix() {
+		t.Fatal("第一次触发时间和预期不符", next, ok)
+	}
+
+	// 到达触发时间后等待执行结束
+	if _, ok = trigger.MakeNextTriggerTime(next); ok {
+		t.Fatal("执行期间不应该有下一次触发")
+	}
+	if _, ok = trigger.MakeNextTriggerTime(next.Add(time.Second * 30)); ok {
+		t.Fatal("执行期间不应该有下一次触发")
+	}
+
+	end := next.Add(time.Second * 30)
+	trigger.(IFeedbackTrigger).ExecuteFinished(end)
+	next, ok = trigger.MakeNextTriggerTime(end)
+	if !ok || !next.Equal(end.Add(time.Second*10)) {
+		t.Fatal("执行结束后的触发时间和预期不符", next, ok)
+	}
+
+	if _, err = NewFixedDelayTrigger(time.Millisecond); err == nil {
+		t.Fatal("延迟小于1秒时应该返回错误")
+	}
+}
+
+func TestFixedRateTrigger(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	end := time.Date(2024, 1, 1, 0, 10, 0, 0, time.Local)
+	trigger, err := NewFixedRateTrigger(time.Minute*3, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		t      time.Time
+		expect time.Time
+		ok     bool
+	}{
+		{start.Add(-time.Hour), start, true},
+		{start, start.Add(time.Minute * 3), true},
+		{start.Add(time.Minute * 4), start.Add(time.Minute * 6), true},
+		{start.Add(time.Minute * 9), time.Time{}, false},
+	}
+	for _, test := range tests {
+		next, ok := trigger.MakeNextTriggerTime(test.t)
+		if ok != test.ok || (ok && !next.Equal(test.expect)) {
+			t.Fatal("触发时间和预期不符", test.t, next, ok)
+		}
+	}
+	if trigger.Expression() != "@rate 3m0s from 2024-01-01 00:00:00 to 2024-01-01 00:10:00" {
+		t.Fatal("表达式和预期不符", trigger.Expression())
+	}
+
+	// 没有开始时间时触发时间是间隔的整数倍
+	trigger, _ = NewFixedRateTrigger(time.Minute, time.Time{}, time.Time{})
+	next, _ := trigger.MakeNextTriggerTime(time.Unix(90, 0))
+	if next.Unix() != 120 {
+		t.Fatal("触发时间和预期不符", next)
+	}
+}
+
+func TestExcludeTrigger(t *testing.T) {
+	cronTrigger, _ := NewCronTrigger("0 9 * * *")
+	calendar, err := NewDateCalendar([]string{"2024-10-01", "2024-10-02"}, time.Local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trigger, err := NewExcludeTrigger(cronTrigger, calendar)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next, ok := trigger.(IMisfireTrigger).NextTriggerTimeAfter(time.Date(2024, 9, 30, 10, 0, 0, 0, time.Local))
+	if !ok || !next.Equal(time.Date(2024, 10, 3, 9, 0, 0, 0, time.Local)) {
+		t.Fatal("触发时间和预期不符", next, ok)
+	}
+
+	delay, _ := NewFixedDelayTrigger(time.Second)
+	if _, err = NewExcludeTrigger(delay, calendar); err == nil {
+		t.Fatal("固定延迟触发器不能排除日期")
+	}
+}
+
+func TestExcludeTriggerSubMinute(t *testing.T) {
+	// 连续排除多天时秒级的触发时间远超检查次数
+	calendar, _ := NewDateCalendar([]string{"2024-10-01", "2024-10-02", "2024-10-03"}, time.Local)
+	cronTrigger, _ := NewCronTrigger("*/5 * * * * *")
+	trigger, _ := NewExcludeTrigger(cronTrigger, calendar)
+	next, ok := trigger.(IMisfireTrigger).NextTriggerTimeAfter(time.Date(2024, 9, 30, 23, 59, 58, 0, time.Local))
+	if !ok || !next.Equal(time.Date(2024, 10, 4, 0, 0, 0, 0, time.Local)) {
+		t.Fatal("触发时间和预期不符", next, ok)
+	}
+
+	// 有状态的 MakeNextTriggerTime 需要从当前时间开始计算
+	y, m, d := time.Now().Date()
+	tomorrow := time.Date(y, m, d+1, 0, 0, 0, 0, time.Local)
+	var dates []string
+	for i := 0; i < 2; i++ {
+		dates = append(dates, tomorrow.AddDate(0, 0, i).Format(CalendarDateLayout))
+	}
+	calendar, _ = NewDateCalendar(dates, time.Local)
+	cronTrigger, _ = NewCronTrigger("@every 5s")
+	trigger, _ = NewExcludeTrigger(cronTrigger, calendar)
+	included := tomorrow.AddDate(0, 0, 2)
+	next, ok = trigger.MakeNextTriggerTime(tomorrow)
+	if !ok || next.Before(included) || next.After(included.Add(time.Second*5)) {
+		t.Fatal("排除日期之后的触发时间和预期不符", next, ok)
+	}
+	if trigger.(IScanLimitedTrigger).ScanExhausted() {
+		t.Fatal("找到触发时间时不应该中止查找")
+	}
+}
+
+// 排除所有时间的日历
+type excludeAllCalendar struct{}
+
+func (excludeAllCalendar) IsExcluded(t time.Time) bool { return true }
+func (excludeAllCalendar) String() string              { return "all" }
+
+func TestTriggerScanExhausted(t *testing.T) {
+	cronTrigger, _ := NewCronTrigger("@every 1s")
+	exclude, _ := NewExcludeTrigger(cronTrigger, excludeAllCalendar{})
+	midnight, _ := NewCronTrigger("0 0 * * *")
+	halfPast, _ := NewCronTrigger("30 0 * * *")
+	intersection, _ := NewIntersectionTrigger(midnight, halfPast)
+	union, _ := NewUnionTrigger(exclude, NewOnceTrigger(time.Now().Add(-time.Hour)))
+
+	tests := []struct {
+		name      string
+		trigger   ITrigger
+		exhausted bool
+	}{
+		{"exclude", exclude, true},
+		{"intersection", intersection, true},
+		{"union", union, true},
+		{"no_next", NewOnceTrigger(time.Now().Add(-time.Hour)), false},
+	}
+	for _, test := range tests {
+		now := time.Now()
+		if _, ok := test.trigger.MakeNextTriggerTime(now); ok {
+			t.Fatal("不应该找到触发时间", test.name)
+		}
+		if isScanExhausted(test.trigger) != test.exhausted {
+			t.Fatal("是否中止查找和预期不符", test.name)
+		}
+
+		// 中止查找时任务会在检查时间继续查找
+		task := NewTaskOfConfig(test.name, TaskConfig{Trigger: test.trigger, Enable: true})
+		next, ok := task.MakeNextTriggerTime(now)
+		if ok != test.exhausted || task.isScanCheckpoint() != test.exhausted {
+			t.Fatal("任务的检查时间和预期不符", test.name, ok)
+		}
+		if test.exhausted && !next.After(now) {
+			t.Fatal("检查时间应该在当前时间之后", test.name, next)
+		}
+	}
+}
+
+func TestCompositeTrigger(t *testing.T) {
+	a, _ := NewCronTrigger("0 9 * * *")
+	b, _ := NewCronTrigger("0 18 * * *")
+	union, err := NewUnionTrigger(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2024, 10, 1, 10, 0, 0, 0, time.Local)
+	next, ok := union.(IMisfireTrigger).NextTriggerTimeAfter(from)
+	if !ok || !next.Equal(time.Date(2024, 10, 1, 18, 0, 0, 0, time.Local)) {
+		t.Fatal("并集触发时间和预期不符", next, ok)
+	}
+
+	// 每月13号且为周五
+	c, _ := NewCronTrigger("0 0 13 * *")
+	d, _ := NewCronTrigger("0 0 * * 5")
+	intersection, err := NewIntersectionTrigger(c, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, ok = intersection.MakeNextTriggerTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local))
+	if !ok || !next.Equal(time.Date(2024, 9, 13, 0, 0, 0, 0, time.Local)) {
+		t.Fatal("交集触发时间和预期不符", next, ok)
+	}
+	if intersection.Expression() != "intersection(0 0 13 * *; 0 0 * * 5)" {
+		t.Fatal("表达式和预期不符", intersection.Expression())
+	}
+
+	if _, err = NewUnionTrigger(); err == nil {
+		t.Fatal("没有触发器时应该返回错误")
+	}
+}
+
+func TestTaskFileConfigMakeTrigger(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "holidays.txt")
+	if err := ioutil.WriteFile(file, []byte("# 国庆\n2024-10-01\n\n2024-10-02\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		conf       TaskFileConfig
+		expectType TriggerType
+	}{
+		{TaskFileConfig{Expression: "*/5 * * * * *"}, CronTriggerType},
+		{TaskFileConfig{Expression: "2024-10-01 00:00:00", IsOnceTrigger: true}, OnceTriggerType},
+		{TaskFileConfig{TriggerType: "fixed_delay", Expression: "30s"}, FixedDelayTriggerType},
+		{TaskFileConfig{TriggerType: "fixed_rate", Expression: "1m", StartTime: "2024-10-01 00:00:00"}, FixedRateTriggerType},
+		{TaskFileConfig{TriggerType: "union", Expressions: []string{"0 9 * * *", "0 18 * * *"}}, UnionTriggerType},
+		{TaskFileConfig{Expression: "0 9 * * *", ExcludeDates: []string{"2024-10-03"}, ExcludeDatesFile: file}, ExcludeTriggerType},
+	}
+	for _, test := range tests {
+		trigger, err := test.conf.makeTrigger()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if trigger.TriggerType() != test.expectType {
+			t.Fatal("触发器类型和预期不符", trigger.TriggerType(), trigger.Expression())
+		}
+	}
+
+	for _, conf := range []TaskFileConfig{
+		{TriggerType: "unknown", Expression: "30s"},
+		{TriggerType: "fixed_delay", Expression: "abc"},
+		{Expression: "0 9 * * *", ExcludeDates: []string{"20241001"}},
+		{TriggerType: "intersection"},
+	} {
+		if _, err := conf.makeTrigger(); err == nil {
+			t.Fatal("配置错误时应该返回错误", conf)
+		}
+	}
+}
@@ -38,10 +38,20 @@ func WithService() zapp.Option {
 	return zapp.WithService(nowServiceType)
 }
 
-// 注册cron的Handler
+// 获取cron服务, 服务未启用时返回nil
+func GetService() ICron {
+	s, ok := zapp.App().GetService(nowServiceType)
+	if !ok {
+		return nil
+	}
+	c, _ := s.(ICron)
+	return c
+}
+
+// 注册cron的Handler, cron表达式错误时会退出程序
 func RegistryHandler(name string, expression string, enable bool, handler Handler) {
 	task := NewTaskOfConfig(name, TaskConfig{
-		Trigger:  NewCronTrigger(expression),
+		Trigger:  mustCronTrigger(name, expression),
 		Executor: NewExecutor(0, 0, 1),
 		Handler:  handler,
 		Enable:   enable,
@@ -60,6 +70,11 @@ func RegistryOnceHandler(name string, t time.Time, enable bool, handler Handler)
 	zapp.App().InjectService(nowServiceType, task)
 }
 
+// 注册DAG任务, 整个DAG由一个cron表达式触发, cron表达式错误或DAG配置错误时会退出程序
+func RegistryDAG(name string, expression string, enable bool, nodes ...DAGNode) {
+	zapp.App().InjectService(nowServiceType, NewDAGTask(name, expression, enable, nodes...))
+}
+
 // 注册自定义task
 func RegistryTask(task ITask) {
 	zapp.App().InjectService(nowServiceType, task)